@@ -7,7 +7,10 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -18,17 +21,22 @@ import (
 	"net/http/httptest"
 	"net/http/httputil"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fullstorydev/grpcurl"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/pkg/browser"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 	"golang.org/x/term"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -42,6 +50,7 @@ import (
 	// Register xds so xds and xds-experimental resolver schemes work
 	_ "google.golang.org/grpc/xds"
 
+	"github.com/fullstorydev/grpcui"
 	"github.com/fullstorydev/grpcui/internal"
 	"github.com/fullstorydev/grpcui/standalone"
 )
@@ -100,6 +109,52 @@ var (
 		-rpc-header, -reflect-header, and -default-header options. No other
 		expansion/escaping is performed. This can be used to supply
 		credentials/secrets without having to put them in command-line arguments.`))
+	oauth2TokenURL = flags.String("oauth2-token-url", "", prettify(`
+		The URL of an OAuth2 token endpoint from which to get access tokens,
+		which are sent as "authorization" metadata with every RPC, including
+		reflection requests. Tokens are cached and refreshed before they
+		expire. Uses the client credentials flow, or the refresh token flow if
+		-oauth2-refresh-token-file is given. Requires -oauth2-client-id.`))
+	oauth2ClientID = flags.String("oauth2-client-id", "", prettify(`
+		The client ID to use with -oauth2-token-url.`))
+	oauth2ClientSecretFile = flags.String("oauth2-client-secret-file", "", prettify(`
+		The name of a file that contains the client secret to use with
+		-oauth2-token-url.`))
+	oauth2RefreshTokenFile = flags.String("oauth2-refresh-token-file", "", prettify(`
+		The name of a file that contains a refresh token. When given, tokens
+		are obtained from -oauth2-token-url via the refresh token flow instead
+		of the client credentials flow.`))
+	tokenCommand = flags.String("token-command", "", prettify(`
+		A credential helper command that prints an access token, which is sent
+		as "authorization" metadata with every RPC, including reflection
+		requests. The command is split into arguments at spaces and run
+		without a shell. It may print the token by itself, or a JSON object
+		with "access_token", "token_type", and "expires_in" properties. The
+		command is run again when the token expires, or every five minutes if
+		its expiry is unknown. Cannot be used with -oauth2-token-url.`))
+	jwtKeyFile = flags.String("jwt-key-file", "", prettify(`
+		The name of a file that contains a private key with which to sign a
+		fresh JWT for every RPC, including reflection requests. The JWT is
+		sent as a bearer token in "authorization" metadata. The file may
+		contain a PEM-encoded RSA, ECDSA, or Ed25519 key or a JSON service
+		account key with "private_key", "private_key_id", and "client_email"
+		properties. Cannot be used with -token-command or -oauth2-token-url.`))
+	jwtKeyID = flags.String("jwt-key-id", "", prettify(`
+		The key ID ("kid" header) of JWTs signed with -jwt-key-file. Defaults
+		to the "private_key_id" of a service account key.`))
+	jwtIssuer = flags.String("jwt-issuer", "", prettify(`
+		The issuer ("iss" claim) of JWTs signed with -jwt-key-file. Defaults to
+		the "client_email" of a service account key.`))
+	jwtSubject = flags.String("jwt-subject", "", prettify(`
+		The subject ("sub" claim) of JWTs signed with -jwt-key-file. Defaults
+		to the issuer.`))
+	jwtAudience = flags.String("jwt-audience", "", prettify(`
+		The audience ("aud" claim) of JWTs signed with -jwt-key-file. Any
+		'{service}' in the value is replaced with the fully-qualified name of
+		the service being invoked. Defaults to the URI of the service, like
+		'https://host/acme.Orders'.`))
+	jwtLifetime = flags.Float64("jwt-lifetime", 300, prettify(`
+		The time, in seconds, until JWTs signed with -jwt-key-file expire.`))
 	authority = flags.String("authority", "", prettify(`
 		The authoritative name of the remote server. This value is passed as the
 		value of the ":authority" pseudo-header in the HTTP/2 protocol. When TLS
@@ -149,24 +204,207 @@ var (
 		when the tool detects that stdin is a terminal/tty. Otherwise, this
 		defaults to false.`))
 	examplesFile = flags.String("examples", "", prettify(`
-		Load examples from the given JSON file. The examples are shown in the UI
-		which lets users pick pre-defined RPC and request data, like a recipe.
-		This can be templates for common requests or could even represent a test
-		suite as a sequence of RPCs. This is similar to the "collections" feature
-		in postman. The format of this file is the same as used when saving
-		history from the gRPC UI "History" tab.`))
+		Load examples from the given file or directory. The examples are shown
+		in the UI which lets users pick pre-defined RPC and request data, like a
+		recipe. This can be templates for common requests or could even
+		represent a test suite as a sequence of RPCs. This is similar to the
+		"collections" feature in postman. The format of a JSON file is the same
+		as used when saving history from the gRPC UI "History" tab. Files with
+		a ".yaml" or ".yml" extension are read as YAML. If a directory is given,
+		all JSON and YAML files therein (including in sub-directories) are
+		loaded. A file may also contain an object with "service", "method", and
+		"examples" properties, which is convenient for keeping one file per
+		method.`))
+	strictExamples = flags.Bool("strict-examples", false, prettify(`
+		When true, grpcui will fail to start if any examples loaded via the
+		-examples flag refer to methods that are not exposed or have request
+		data that does not match the method's request type. Otherwise, such
+		problems are reported as warnings.`))
+	runExamples = flags.Bool("run-examples", false, prettify(`
+		When true, grpcui does not serve a web UI. Instead, it invokes the RPC
+		for each example loaded via the -examples flag and checks the outcome
+		against the example's "expect" property, which may describe the
+		expected status code and message, response messages, and response
+		headers and trailers. Examples without expectations must complete
+		successfully. The results, including differences from the expected
+		responses, are printed and grpcui exits with a non-zero status if
+		any example fails. This allows examples to be used as smoke tests.`))
+	examplesWritable = flags.Bool("examples-writable", false, prettify(`
+		When true, users of the UI can save the current request as a named
+		example, which is written back to the file or directory given by the
+		-examples flag and shown to all users. When it is a directory, saved
+		examples are written to "<dir>/<service>/<method>.json". A YAML file
+		cannot be written, so use a directory or a JSON file instead. If the
+		given path does not exist, it is created when the first example is
+		saved: as a JSON file if its name ends in ".json", otherwise as a
+		directory.`))
+	fuzzMethod = flags.String("fuzz", "", prettify(`
+		When set, grpcui does not serve a web UI. Instead, it sends generated
+		requests for the named method (e.g. "my.Service/Method") and reports
+		any that fail with an Unknown, Internal, DataLoss, or Unavailable
+		status, or whose status message mentions a panic. Such statuses
+		usually mean the server crashed or failed to validate the request.
+		Requests use boundary values for numbers, empty and huge strings,
+		invalid UTF-8 in bytes, unknown enum values, deeply nested messages,
+		and empty one-ofs. Some are mutations of the request data in the
+		first example for the method loaded via -examples, if any. Requests
+		are sent with the metadata given via -H and -rpc-header and are
+		subject to -max-time. grpcui exits with a non-zero status if any
+		problems are found.`))
+	fuzzCount = flags.Int("fuzz-count", 100, prettify(`
+		The number of requests to send when using -fuzz.`))
+	fuzzSeed = flags.Int64("fuzz-seed", 0, prettify(`
+		The seed for the first request generated when using -fuzz. Each
+		subsequent request uses the next seed. Each problem is reported with
+		the seed of its request, so a single request can be sent again with
+		"-fuzz-seed <seed> -fuzz-count 1". If not specified, a random seed is
+		used.`))
+	fuzzOut = flags.String("fuzz-out", "", prettify(`
+		The name of a file to be written that will contain the requests for
+		the problems found when using -fuzz, in the same format as examples.
+		The file can be loaded via -examples, to examine the requests in the
+		UI, or used with -run-examples to check whether the problems have been
+		fixed.`))
+	historyFile = flags.String("history", "", prettify(`
+		The name of a JSON file in which to keep the history of invocations
+		made from the web UI. By default, history is kept in the local storage
+		of each browser. When this flag is used, the history is kept on the
+		server instead, so it is available across browsers and can be searched.
+		Unless -user-header is also used, the history is shared by all users of
+		the UI. The file is created if it does not exist.`))
+	shareDir = flags.String("share-dir", "", prettify(`
+		The name of a directory in which to keep requests that users share
+		from the web UI. When set, the UI includes a "Share" button that
+		creates a link that opens the UI with the current request (and,
+		optionally, its response) pre-filled. Unless the user chooses
+		otherwise, metadata that likely holds credentials, such as an
+		"authorization" header, is not shared. The directory is created if it
+		does not exist.`))
+	workspaceDir = flags.String("workspace-dir", "", prettify(`
+		The name of a directory in which to keep users' workspaces. When set,
+		the UI includes a "Workspace" tab where users can save requests in
+		folders, along with notes and variables, run them later, and export
+		and import the whole workspace as JSON. Unless -user-header is also
+		used, all users share one workspace. The directory is created if it
+		does not exist.`))
+	auditLog = flags.String("audit-log", "", prettify(`
+		The name of a file to which a record of every RPC invoked from the web
+		UI is appended, as a line of JSON. Each record includes the time, the
+		address and identity (see -user-header) of the user, the method, the
+		status code, the duration, and the number of messages sent and
+		received. Use "-" to write records to stdout. The file is rotated when
+		it reaches the size given by -audit-log-max-size.`))
+	auditLogMaxSize = flags.Int("audit-log-max-size", 100, prettify(`
+		The size, in megabytes, at which the file given by -audit-log is
+		rotated. The old file is renamed with a ".1" suffix.`))
+	auditLogMaxBackups = flags.Int("audit-log-max-backups", 5, prettify(`
+		The number of old files kept when the file given by -audit-log is
+		rotated.`))
+	auditBodies = flags.Bool("audit-bodies", false, prettify(`
+		When true, records written to the file given by -audit-log include the
+		request and response messages. Request metadata is never included.
+		Fields masked via -redact-field are masked in these messages, too.`))
+	userHeader = flags.String("user-header", "", prettify(`
+		The name of an HTTP header that identifies the user of the web UI, such
+		as one set by an authenticating reverse proxy. When present, the history
+		kept via -history and the workspace kept via -workspace-dir are scoped
+		to the header's value, so each user sees only their own, and records
+		written to -audit-log include it. Only use this when grpcui is behind a
+		proxy that always sets (or removes) the header, since otherwise users
+		can impersonate each other.`))
+	protosetOut = flags.String("protoset-out", "", prettify(`
+		The name of a file to be written that will contain a FileDescriptorSet
+		proto. All descriptors resolved at startup (for example, via server
+		reflection), along with their transitive dependencies, are written to
+		the named file. The file can later be supplied via -protoset, to use the
+		same schema with servers that do not support reflection.`))
 	reflection = optionalBoolFlag{val: true}
 
 	port = flags.Int("port", 0, prettify(`
 		The port on which the web UI is exposed.`))
 	bind = flags.String("bind", "127.0.0.1", prettify(`
-		The address on which the web UI is exposed.`))
+		The address on which the web UI is exposed. If this is not a loopback
+		address, one of the -auth-* flags must also be used, unless
+		-allow-unauthenticated is given.`))
+	bindUnix = flags.String("bind-unix", "", prettify(`
+		The path of a Unix domain socket on which to expose the web UI,
+		instead of a TCP port, such as for a reverse proxy to front it. A
+		socket left behind by an earlier run is replaced, and the socket is
+		removed when grpcui is interrupted or terminated. Cannot be used with
+		-bind or -port.`))
+	bindUnixMode = flags.String("bind-unix-mode", "0600", prettify(`
+		The permissions, in octal, of the socket given by -bind-unix. Only
+		users that may write to the socket can connect to it, so use "0660"
+		to allow a proxy running as another user in the same group.`))
+	httpsCert = flags.String("https-cert", "", prettify(`
+		The name of a file that contains a PEM-encoded certificate with which
+		to serve the web UI over HTTPS. Must be used with -https-key.`))
+	httpsKey = flags.String("https-key", "", prettify(`
+		The name of a file that contains the PEM-encoded private key for
+		-https-cert.`))
+	httpsSelfSigned = flags.Bool("https-self-signed", false, prettify(`
+		When true, the web UI is served over HTTPS with a self-signed
+		certificate that is generated at startup, for localhost, this host's
+		name, and the -bind address. Browsers warn about such certificates, so
+		compare the printed fingerprint with the one they show. Cannot be used
+		with -https-cert.`))
+	httpsClientCA = flags.String("https-client-ca", "", prettify(`
+		The name of a file that contains PEM-encoded CA certificates. Users of
+		the web UI may then authenticate with a client certificate signed by
+		one of them. The user is the certificate's common name or, if it has
+		none, its first email address. Requires -https-cert or
+		-https-self-signed.`))
+	authzPolicy = flags.String("authz-policy", "", prettify(`
+		The name of a JSON or YAML file with a policy that decides which
+		methods each user may invoke. Methods that a user may not invoke are
+		hidden from their web form and rejected if invoked anyway. Users are
+		identified via the -auth-* flags or -user-header. See the README for
+		the format of the file.`))
+	readOnly = flags.Bool("read-only", false, prettify(`
+		When true, only methods whose idempotency_level option is
+		NO_SIDE_EFFECTS, or that are given via -read-only-allow, may be invoked
+		from the web UI.`))
+	protected = flags.Bool("protected", false, prettify(`
+		When true, the target is treated as protected: invoking a method whose
+		idempotency_level option is neither NO_SIDE_EFFECTS nor IDEMPOTENT must
+		be confirmed by the user. This is enforced by the server, so replayed
+		history items and scripted requests need confirmation, too. See
+		-confirm-method and -no-confirm-method to adjust which methods need
+		confirmation.`))
+	allowUnauthenticated = flags.Bool("allow-unauthenticated", false, prettify(`
+		When true, the web UI may be exposed on a non-loopback address via
+		-bind without requiring users to authenticate. Only use this when the
+		network, or a proxy in front of grpcui, restricts who can reach it.`))
+	authHtpasswd = flags.String("auth-htpasswd", "", prettify(`
+		The name of an htpasswd-style file of users that may use the web UI.
+		Users log in via a login page, or send credentials via HTTP basic
+		auth. Passwords must be hashed with "htpasswd -m" or "htpasswd -s".
+		The authenticated user identifies them in -history, -workspace-dir,
+		and -audit-log, instead of -user-header.`))
+	authTokens = flags.String("auth-tokens", "", prettify(`
+		The name of a file of static bearer tokens that may be used to access
+		the web UI. Each line has the form "user:token". Clients send a token
+		in an "Authorization: Bearer <token>" header, or enter it as the
+		password in the login page.`))
+	authProxyHeader = flags.String("auth-proxy-header", "", prettify(`
+		The name of an HTTP header, set by an authenticating reverse proxy,
+		that identifies the user of the web UI. Requests without the header
+		are rejected. The header is only trusted in requests from the
+		addresses given by -auth-proxy-trusted, or via -bind-unix.`))
 	basePath = flags.String("base-path", "/", prettify(`
 		The path on which the web UI is exposed.
 		Defaults to slash ("/"), which is the root of the server.
 		Example: "/debug/grpcui".`))
-	services multiString
-	methods  multiString
+	services         multiString
+	methods          multiString
+	authProxyTrusted multiString
+	readOnlyAllow    multiString
+	confirmMethod    multiString
+	noConfirmMethod  multiString
+	redactField      multiString
+	redactMetadata   multiString
+	oauth2Scopes     multiString
+	jwtClaims        multiString
 
 	extraJS     multiString
 	extraCSS    multiString
@@ -177,14 +415,16 @@ func init() {
 	flags.Var(&addlHeaders, "H", prettify(`
 		Additional headers in 'name: value' format. May specify more than one
 		via multiple flags. These headers will also be included in reflection
-		requests to a server. These headers are not shown in the gRPC UI form.
-		If the user enters conflicting metadata, the user-entered value will be
+		requests to a server. The values of these headers are not shown in the
+		gRPC UI form, which only lists their names, marked as injected. If the
+		user enters conflicting metadata, the user-entered value will be
 		ignored and only the values present on the command-line will be used.`))
 	flags.Var(&rpcHeaders, "rpc-header", prettify(`
 		Additional RPC headers in 'name: value' format. May specify more than
 		one via multiple flags. These headers will *only* be used when invoking
 		the requested RPC method. They are excluded from reflection requests.
-		These headers are not shown in the gRPC UI form. If the user enters
+		The values of these headers are not shown in the gRPC UI form, which
+		only lists their names, marked as injected. If the user enters
 		conflicting metadata, the user-entered value will be ignored and only
 		the values present on the command-line will be used.`))
 	flags.Var(&reflHeaders, "reflect-header", prettify(`
@@ -207,7 +447,11 @@ func init() {
 		Additional headers to add to metadata in the gRPCui web form. Each value
 		should be in 'name: value' format. May specify more than one via multiple
 		flags. These headers are just defaults in the UI and maybe changed or
-		removed by the user that is interacting with the form.`))
+		removed by the user that is interacting with the form. Their values are
+		visible to anyone who can load the UI, so use -rpc-header instead for
+		credentials. Headers named in a -redact-metadata flag are listed by
+		name only, for the user to fill in. Headers with the same name as a -H
+		or -rpc-header header are left out of the form.`))
 	flags.Var(&protoset, "protoset", prettify(`
 		The name of a file containing an encoded FileDescriptorSet. This file's
 		contents will be used to determine the RPC schema instead of querying
@@ -245,6 +489,46 @@ func init() {
 		a -method flag. Method names must be fully-qualified and may either use
 		a dot (".") or a slash ("/") to separate the fully-qualified service
 		name from the method's name.`))
+	flags.Var(&authProxyTrusted, "auth-proxy-trusted", prettify(`
+		An IP address or CIDR network (like "10.0.0.0/8") of the reverse proxy
+		that sets the header given by -auth-proxy-header. May specify more than
+		one via multiple flags. Defaults to loopback addresses only.`))
+	flags.Var(&readOnlyAllow, "read-only-allow", prettify(`
+		A pattern of methods that may be invoked when using -read-only, even
+		though they are not marked as having no side effects. Patterns are
+		matched against names of the form "package.Service/Method", where "*"
+		matches any sequence of characters except slashes; a pattern without a
+		slash matches all methods of the services it matches. May specify more
+		than one via multiple flags.`))
+	flags.Var(&confirmMethod, "confirm-method", prettify(`
+		A pattern of methods that must be confirmed when using -protected,
+		regardless of their idempotency_level option. Patterns have the same
+		form as for -read-only-allow. May specify more than one via multiple
+		flags.`))
+	flags.Var(&noConfirmMethod, "no-confirm-method", prettify(`
+		A pattern of methods that need not be confirmed when using -protected,
+		even though they are not marked as free of side effects. Patterns have
+		the same form as for -read-only-allow. May specify more than one via
+		multiple flags.`))
+	flags.Var(&oauth2Scopes, "oauth2-scope", prettify(`
+		A scope to request with -oauth2-token-url. May specify more than one
+		via multiple flags.`))
+	flags.Var(&jwtClaims, "jwt-claim", prettify(`
+		An additional claim, in the form 'name=value', for JWTs signed with
+		-jwt-key-file. If the value is valid JSON, like '42' or '["a","b"]',
+		it is added as is, otherwise as a string. May specify more than one
+		via multiple flags.`))
+	flags.Var(&redactField, "redact-field", prettify(`
+		A field whose values are masked in responses shown in the web UI, in
+		saved history, in the grpcurl command shown for a request, in the
+		audit log, and in verbose logs. The field is named by its fully-qualified name, like
+		"acme.Login.password", or by its name alone, like "password", to mask
+		it in any message. Fields with the option "debug_redact = true" are
+		always masked. May specify more than one via multiple flags.`))
+	flags.Var(&redactMetadata, "redact-metadata", prettify(`
+		The name of request or response metadata whose values are masked
+		wherever -redact-field masks fields. May specify more than one via
+		multiple flags.`))
 	flags.Var(&debug, "debug-client", prettify(`
 		When true, the client JS code in the gRPCui web form will log extra
 		debug info to the console.`))
@@ -395,6 +679,89 @@ func main() {
 	if *maxMsgSz < 0 {
 		fail(nil, "The -max-msg-sz argument must not be negative.")
 	}
+	if *strictExamples && *examplesFile == "" {
+		fail(nil, "The -strict-examples argument can only be used with -examples.")
+	}
+	if *runExamples && *examplesFile == "" {
+		fail(nil, "The -run-examples argument can only be used with -examples.")
+	}
+	if *runExamples && *examplesWritable {
+		fail(nil, "The -run-examples and -examples-writable arguments are mutually exclusive.")
+	}
+	if *examplesWritable && *examplesFile == "" {
+		fail(nil, "The -examples-writable argument can only be used with -examples.")
+	}
+	if *fuzzMethod != "" && (*runExamples || *examplesWritable) {
+		fail(nil, "The -fuzz argument cannot be used with -run-examples or -examples-writable.")
+	}
+	if *fuzzCount <= 0 {
+		fail(nil, "The -fuzz-count argument must be positive.")
+	}
+	if *userHeader != "" && *historyFile == "" && *workspaceDir == "" && *auditLog == "" && *authzPolicy == "" {
+		fail(nil, "The -user-header argument can only be used with -history, -workspace-dir, -audit-log, or -authz-policy.")
+	}
+	if *auditBodies && *auditLog == "" {
+		fail(nil, "The -audit-bodies argument can only be used with -audit-log.")
+	}
+	if *auditLogMaxSize <= 0 || *auditLogMaxBackups <= 0 {
+		fail(nil, "The -audit-log-max-size and -audit-log-max-backups arguments must be positive.")
+	}
+	if len(readOnlyAllow) > 0 && !*readOnly {
+		fail(nil, "The -read-only-allow argument can only be used with -read-only.")
+	}
+	if (len(confirmMethod) > 0 || len(noConfirmMethod) > 0) && !*protected {
+		fail(nil, "The -confirm-method and -no-confirm-method arguments can only be used with -protected.")
+	}
+	if *tokenCommand != "" && *oauth2TokenURL != "" {
+		fail(nil, "The -token-command and -oauth2-token-url arguments are mutually exclusive.")
+	}
+	if *oauth2TokenURL == "" && (*oauth2ClientID != "" || *oauth2ClientSecretFile != "" || *oauth2RefreshTokenFile != "" || len(oauth2Scopes) > 0) {
+		fail(nil, "The -oauth2-* arguments can only be used with -oauth2-token-url.")
+	}
+	if *oauth2TokenURL != "" && *oauth2ClientID == "" {
+		fail(nil, "The -oauth2-token-url argument requires -oauth2-client-id.")
+	}
+	if *jwtKeyFile != "" && (*tokenCommand != "" || *oauth2TokenURL != "") {
+		fail(nil, "The -jwt-key-file argument cannot be used with -token-command or -oauth2-token-url.")
+	}
+	if *jwtKeyFile == "" && (*jwtKeyID != "" || *jwtIssuer != "" || *jwtSubject != "" || *jwtAudience != "" || len(jwtClaims) > 0) {
+		fail(nil, "The -jwt-* arguments can only be used with -jwt-key-file.")
+	}
+	if *jwtLifetime <= 0 {
+		fail(nil, "The -jwt-lifetime argument must be positive.")
+	}
+	var unixMode os.FileMode
+	if *bindUnix != "" {
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "bind" || f.Name == "port" {
+				fail(nil, "The -bind-unix argument cannot be used with -bind or -port.")
+			}
+		})
+		var err error
+		if unixMode, err = parseFileMode(*bindUnixMode); err != nil {
+			fail(err, "Invalid -bind-unix-mode argument")
+		}
+	}
+	if (*httpsCert == "") != (*httpsKey == "") {
+		fail(nil, "The -https-cert and -https-key arguments must be used together.")
+	}
+	if *httpsSelfSigned && *httpsCert != "" {
+		fail(nil, "The -https-self-signed and -https-cert arguments are mutually exclusive.")
+	}
+	if *httpsClientCA != "" && !*httpsSelfSigned && *httpsCert == "" {
+		fail(nil, "The -https-client-ca argument requires -https-cert or -https-self-signed.")
+	}
+	if len(authProxyTrusted) > 0 && *authProxyHeader == "" {
+		fail(nil, "The -auth-proxy-trusted argument can only be used with -auth-proxy-header.")
+	}
+	authenticated := *authHtpasswd != "" || *authTokens != "" || *authProxyHeader != "" || *httpsClientCA != ""
+	servesUI := !*runExamples && *fuzzMethod == ""
+	if servesUI && !authenticated && !*allowUnauthenticated && !isLoopback(*bind) {
+		fail(nil, "Refusing to expose the web UI on non-loopback address %q without authentication. Use -auth-htpasswd, -auth-tokens, -auth-proxy-header, or -https-client-ca, or use -allow-unauthenticated to override.", *bind)
+	}
+	if *fuzzOut != "" && *fuzzMethod == "" {
+		fail(nil, "The -fuzz-out argument can only be used with -fuzz.")
+	}
 	if *plaintext && *insecure {
 		fail(nil, "The -plaintext and -insecure arguments are mutually exclusive.")
 	}
@@ -467,29 +834,118 @@ func main() {
 		grpclog.SetLoggerV2(grpclog.NewLoggerV2WithVerbosity(os.Stdout, io.Discard, io.Discard, grpcVerbosity))
 	}
 
-	var examplesOpt standalone.HandlerOption
+	var examples []standalone.Example
+	var exampleStore standalone.ExampleStore
 	if *examplesFile != "" {
-		func() {
-			f, err := os.Open(*examplesFile)
+		var err error
+		if *examplesWritable {
+			exampleStore, err = standalone.NewFileExampleStore(*examplesFile)
 			if err != nil {
-				if os.IsNotExist(err) {
-					fail(nil, "File %q does not exist", *examplesFile)
-				} else {
-					fail(err, "Failed to open %q", *examplesFile)
-				}
+				fail(err, "Failed to open examples at %q for writing", *examplesFile)
+			}
+		}
+		if _, err := os.Stat(*examplesFile); os.IsNotExist(err) {
+			if exampleStore == nil {
+				fail(nil, "File %q does not exist", *examplesFile)
 			}
-			defer func() {
-				_ = f.Close()
-			}()
-			data, err := io.ReadAll(f)
+		} else {
+			examples, err = standalone.LoadExamples(*examplesFile)
 			if err != nil {
-				fail(err, "Failed to read contents of %q", *examplesFile)
+				fail(err, "Failed to load examples from %q", *examplesFile)
 			}
-			examplesOpt, err = standalone.WithExampleData(data)
+		}
+	}
+
+	var historyStore standalone.HistoryStore
+	if *historyFile != "" {
+		var err error
+		if historyStore, err = standalone.NewFileHistoryStore(*historyFile, 0); err != nil {
+			fail(err, "Failed to open history file %q", *historyFile)
+		}
+	}
+
+	var shareStore standalone.ShareStore
+	if *shareDir != "" {
+		var err error
+		if shareStore, err = standalone.NewFileShareStore(*shareDir); err != nil {
+			fail(err, "Failed to open share directory %q", *shareDir)
+		}
+	}
+
+	var workspaceStore standalone.WorkspaceStore
+	if *workspaceDir != "" {
+		var err error
+		if workspaceStore, err = standalone.NewFileWorkspaceStore(*workspaceDir); err != nil {
+			fail(err, "Failed to open workspace directory %q", *workspaceDir)
+		}
+	}
+
+	var authenticators []standalone.Authenticator
+	if *authHtpasswd != "" {
+		a, err := standalone.NewHtpasswdAuthenticator(*authHtpasswd)
+		if err != nil {
+			fail(err, "Failed to load users from %q", *authHtpasswd)
+		}
+		authenticators = append(authenticators, a)
+	}
+	if *authTokens != "" {
+		tokens, err := loadTokens(*authTokens)
+		if err != nil {
+			fail(err, "Failed to load tokens from %q", *authTokens)
+		}
+		authenticators = append(authenticators, standalone.NewTokenAuthenticator(tokens))
+	}
+	if *authProxyHeader != "" {
+		var trusted []*net.IPNet
+		for _, addr := range authProxyTrusted {
+			if !strings.Contains(addr, "/") {
+				if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+					addr += "/32"
+				} else {
+					addr += "/128"
+				}
+			}
+			_, n, err := net.ParseCIDR(addr)
 			if err != nil {
-				fail(err, "Failed to process contents of %q", *examplesFile)
+				fail(err, "Invalid -auth-proxy-trusted argument %q", addr)
 			}
-		}()
+			trusted = append(trusted, n)
+		}
+		authenticators = append(authenticators, standalone.NewProxyHeaderAuthenticator(*authProxyHeader, trusted...))
+	}
+	if *httpsClientCA != "" {
+		authenticators = append(authenticators, standalone.NewClientCertAuthenticator())
+	}
+
+	var policy *standalone.AuthzPolicy
+	if *authzPolicy != "" {
+		var err error
+		if policy, err = standalone.LoadAuthzPolicy(*authzPolicy); err != nil {
+			fail(err, "Failed to load authorization policy")
+		}
+	}
+	if *readOnly {
+		if policy == nil {
+			policy = &standalone.AuthzPolicy{}
+		}
+		policy.ReadOnly = true
+		policy.ReadOnlyAllow = append(policy.ReadOnlyAllow, readOnlyAllow...)
+		if err := policy.Validate(); err != nil {
+			fail(err, "Invalid -read-only-allow argument")
+		}
+	}
+
+	var auditSink standalone.AuditSink
+	var auditWriter *standalone.RotatingFileWriter
+	if *auditLog == "-" {
+		auditSink = standalone.NewJSONAuditSink(os.Stdout)
+	} else if *auditLog != "" {
+		w, err := standalone.NewRotatingFileWriter(*auditLog, int64(*auditLogMaxSize)<<20, *auditLogMaxBackups)
+		if err != nil {
+			fail(err, "Failed to open audit log %q", *auditLog)
+		}
+		auditWriter = w
+		auditSink = standalone.NewJSONAuditSink(w)
 	}
 
 	ctx := context.Background()
@@ -531,6 +987,29 @@ func main() {
 		}
 	}
 
+	tokenSource, err := getTokenSource(ctx)
+	if err != nil {
+		fail(err, "Failed to configure token source")
+	}
+	var perRPCCreds credentials.PerRPCCredentials
+	if tokenSource != nil {
+		perRPCCreds = tokenCredentials{ts: tokenSource}
+	} else if *jwtKeyFile != "" {
+		lifetime := time.Duration(*jwtLifetime * float64(time.Second))
+		perRPCCreds, err = newJWTCredentials(*jwtKeyFile, *jwtKeyID, *jwtIssuer, *jwtSubject, *jwtAudience, lifetime, jwtClaims)
+		if err != nil {
+			fail(err, "Failed to configure JWT signing")
+		}
+	}
+	if perRPCCreds != nil {
+		for _, hdr := range append(addlHeaders, rpcHeaders...) {
+			if name, _, _ := strings.Cut(hdr, ":"); strings.EqualFold(strings.TrimSpace(name), "authorization") {
+				fail(nil, "Cannot use an authorization header with -token-command, -oauth2-token-url, or -jwt-key-file.")
+			}
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(perRPCCreds))
+	}
+
 	var creds credentials.TransportCredentials
 	if !*plaintext {
 		tlsConf, err := grpcurl.ClientTLSConfig(*insecure, *cacert, *cert, *key)
@@ -613,6 +1092,11 @@ func main() {
 	exit = func(code int) {
 		// since defers aren't run by os.Exit...
 		reset()
+		if auditWriter != nil {
+			if err := auditWriter.Close(); err != nil {
+				warn("Failed to close audit log: %v", err)
+			}
+		}
 		os.Exit(code)
 	}
 
@@ -624,6 +1108,59 @@ func main() {
 	if err != nil {
 		fail(err, "Failed to enumerate all proto files")
 	}
+	if *protosetOut != "" {
+		if err := writeProtoset(*protosetOut, allFiles); err != nil {
+			fail(err, "Failed to write protoset to %q", *protosetOut)
+		}
+	}
+
+	if *runExamples {
+		// problems found by validation are reported as failures
+		exit(runAllExamples(ctx, cc, methods, examples, append(addlHeaders, rpcHeaders...)))
+	}
+	if *fuzzMethod != "" {
+		seed := time.Now().UnixNano()
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "fuzz-seed" {
+				seed = *fuzzSeed
+			}
+		})
+		opts := grpcui.FuzzOptions{
+			Seed:     seed,
+			Count:    *fuzzCount,
+			Metadata: append(addlHeaders, rpcHeaders...),
+		}
+		if *maxTime > 0 {
+			opts.Timeout = floatSecondsToDuration(*maxTime)
+		}
+		exit(fuzz(ctx, cc, methods, *fuzzMethod, examples, opts, *fuzzOut))
+	}
+
+	var examplesOpt standalone.HandlerOption
+	if len(examples) > 0 {
+		if errs := standalone.ValidateExamples(examples, methods); len(errs) > 0 {
+			for _, err := range errs {
+				if *strictExamples {
+					fmt.Fprintln(os.Stderr, err)
+				} else {
+					warn("%v", err)
+				}
+			}
+			if *strictExamples {
+				fail(fmt.Errorf("%d invalid example(s)", len(errs)), "Failed to validate examples from %q", *examplesFile)
+			}
+		}
+		if exampleStore == nil {
+			examplesOpt, err = standalone.WithExamples(examples...)
+			if err != nil {
+				fail(err, "Failed to process examples from %q", *examplesFile)
+			}
+		}
+	}
+	if exampleStore != nil {
+		// the store serves the examples loaded above, too
+		examplesOpt = standalone.WithExampleStore(exampleStore)
+	}
 
 	// can go ahead and close reflection client now
 	if refClient != nil {
@@ -650,11 +1187,51 @@ func main() {
 	if examplesOpt != nil {
 		handlerOpts = append(handlerOpts, examplesOpt)
 	}
+	if historyStore != nil {
+		handlerOpts = append(handlerOpts, standalone.WithHistoryStore(historyStore))
+	}
+	if shareStore != nil {
+		handlerOpts = append(handlerOpts, standalone.WithShareStore(shareStore))
+	}
+	if workspaceStore != nil {
+		handlerOpts = append(handlerOpts, standalone.WithWorkspaceStore(workspaceStore))
+	}
+	if auditSink != nil {
+		handlerOpts = append(handlerOpts, standalone.WithAuditSink(auditSink, standalone.AuditOptions{
+			IncludeBodies: *auditBodies,
+		}))
+	}
+	if len(authenticators) > 0 {
+		handlerOpts = append(handlerOpts, standalone.WithAuthentication(authenticators...))
+	}
+	if policy != nil {
+		handlerOpts = append(handlerOpts, standalone.WithAuthzPolicy(policy))
+	}
+	if *protected {
+		protectedOpt, err := standalone.WithProtectedTarget(confirmMethod, noConfirmMethod)
+		if err != nil {
+			fail(err, "Invalid -confirm-method or -no-confirm-method argument")
+		}
+		handlerOpts = append(handlerOpts, protectedOpt)
+	}
+	if tokenSource != nil {
+		handlerOpts = append(handlerOpts, standalone.WithTokenSource(tokenSource))
+	} else if perRPCCreds != nil {
+		// JWTs are signed per RPC by the channel, so the web form can only
+		// list them by name
+		handlerOpts = append(handlerOpts, standalone.WithInjectedMetadata("authorization"))
+	}
+	redactor := grpcui.NewRedactor(redactField, redactMetadata)
+	handlerOpts = append(handlerOpts, standalone.WithRedactor(redactor))
+	if *userHeader != "" {
+		handlerOpts = append(handlerOpts, standalone.WithUserHeader(*userHeader))
+	}
 	handlerOpts = append(handlerOpts, standalone.EmitDefaults(*emitDefaults))
 	handlerOpts = append(handlerOpts, configureJSandCSS(extraJS, standalone.AddJSFile)...)
 	handlerOpts = append(handlerOpts, configureJSandCSS(extraCSS, standalone.AddCSSFile)...)
 	handlerOpts = append(handlerOpts, configureAssets(otherAssets)...)
 	handlerOpts = append(handlerOpts, standalone.WithGRPCOptions(gRPCOptions))
+	handlerOpts = append(handlerOpts, standalone.WithSnippetOptions(grpcui.SnippetOptions{Plaintext: *plaintext}))
 
 	handler := standalone.Handler(cc, target, methods, allFiles, handlerOpts...)
 	if *maxTime > 0 {
@@ -674,6 +1251,10 @@ func main() {
 
 	if verbosity > 0 {
 		// wrap the handler with one that performs more logging of what's going on
+		methodsByName := make(map[string]*desc.MethodDescriptor, len(methods))
+		for _, md := range methods {
+			methodsByName[md.GetFullyQualifiedName()] = md
+		}
 		orig := handler
 		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -682,7 +1263,7 @@ func main() {
 				// TODO: the web form never sends binary request body; but maybe a custom
 				//  JS addition could, so maybe this should be a custom printer of the request
 				//  like we have for the response below?
-				if req, err := httputil.DumpRequest(r, verbosity > 2); err != nil {
+				if req, err := dumpRequest(r, verbosity > 2, redactor, methodsByName); err != nil {
 					internal.LogErrorf("could not dump request: %v", err)
 				} else {
 					internal.LogInfof("received request:\n%s", string(req))
@@ -697,7 +1278,7 @@ func main() {
 					// in case handler never called Write or WriteHeader:
 					tee.mirrorHeaders()
 
-					if resp, err := dumpResponse(recorder.Result(), verbosity > 2); err != nil {
+					if resp, err := dumpResponse(recorder.Result(), verbosity > 2, redactor); err != nil {
 						internal.LogErrorf("could not dump response: %v", err)
 					} else {
 						internal.LogInfof("sent response:\n%s", resp)
@@ -720,19 +1301,44 @@ func main() {
 		handler = mux
 	}
 
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", *bind, *port))
+	tlsConf, err := httpsConfig()
 	if err != nil {
-		fail(err, "Failed to listen on port %d", *port)
+		fail(err, "Failed to configure HTTPS")
+	}
+	var listener net.Listener
+	if *bindUnix != "" {
+		listener, err = listenUnix(*bindUnix, unixMode)
+		if err != nil {
+			fail(err, "Failed to listen on Unix domain socket %q", *bindUnix)
+		}
+	} else {
+		listener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", *bind, *port))
+		if err != nil {
+			fail(err, "Failed to listen on port %d", *port)
+		}
+	}
+	closed := closeOnSignal(listener)
+	scheme := "http"
+	if tlsConf != nil {
+		listener = tls.NewListener(listener, tlsConf)
+		scheme = "https"
 	}
 
 	path := *basePath
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
-	url := fmt.Sprintf("http://%s:%d%s", *bind, listener.Addr().(*net.TCPAddr).Port, path)
-	fmt.Printf("gRPC Web UI available at %s\n", url)
+	var url string
+	if *bindUnix != "" {
+		// browsers can't connect to a socket, so there's nothing to open
+		url = fmt.Sprintf("%s://localhost%s", scheme, path)
+		fmt.Printf("gRPC Web UI available at %s via Unix domain socket %s\n", url, *bindUnix)
+	} else {
+		url = fmt.Sprintf("%s://%s:%d%s", scheme, *bind, listener.Addr().(*net.TCPAddr).Port, path)
+		fmt.Printf("gRPC Web UI available at %s\n", url)
+	}
 
-	if *openBrowser {
+	if *openBrowser && *bindUnix == "" {
 		go func() {
 			if err := browser.OpenURL(url); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to open browser: %v\n", err)
@@ -740,10 +1346,34 @@ func main() {
 		}()
 	}
 	if err := http.Serve(listener, handler); err != nil {
+		select {
+		case <-closed:
+			// shut down due to a signal; this closes the audit log, too
+			exit(0)
+		default:
+		}
 		fail(err, "Failed to serve web UI")
 	}
 }
 
+// closeOnSignal closes the given listener when the process is interrupted or
+// terminated, so that grpcui can shut down cleanly: a Unix domain socket is
+// removed, and the audit log is closed. The returned channel is closed just
+// before the listener, so that errors from serving on a closed listener can
+// be told apart from others.
+func closeOnSignal(l net.Listener) <-chan struct{} {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	closed := make(chan struct{})
+	go func() {
+		<-sigs
+		signal.Stop(sigs)
+		close(closed)
+		_ = l.Close()
+	}()
+	return closed
+}
+
 func usage() {
 	fmt.Fprintf(os.Stderr, `Usage:
 	%s [flags] [address]
@@ -757,8 +1387,9 @@ Unix variants, if a -unix=true flag is present, then the address must be the
 path to the domain socket.
 
 Most flags control how the connection to the gRPC server is established. The
-web server will always bind only to localhost, without TLS, so only the port
-can be controlled via command-line flags.
+web server binds only to localhost by default and serves plain HTTP, unless
+the -https-* flags are given. It can also listen on a Unix domain socket, via
+-bind-unix.
 
 Available flags:
 `, os.Args[0])
@@ -783,6 +1414,142 @@ func prettify(docString string) string {
 	return strings.Join(parts[:j], "\n")
 }
 
+// runAllExamples runs the given examples as tests, printing the results, and
+// returns the exit code: zero if all examples passed, one otherwise.
+func runAllExamples(ctx context.Context, cc *grpc.ClientConn, methods []*desc.MethodDescriptor, examples []standalone.Example, extraMetadata []string) int {
+	var passed, failed int
+	for _, ex := range examples {
+		result := standalone.RunExample(ctx, cc, methods, ex, extraMetadata)
+		outcome := "PASS"
+		if result.Passed() {
+			passed++
+		} else {
+			outcome = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s  %s/%s: %s (%v)\n", outcome, ex.Service, ex.Method, ex.Name, result.Duration.Round(time.Millisecond))
+		for _, failure := range result.Failures {
+			fmt.Printf("      %s\n", strings.ReplaceAll(failure, "\n", "\n        "))
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// fuzz sends generated requests for the named method, printing any problems
+// found, and returns the exit code: zero if no problems were found, one
+// otherwise.
+func fuzz(ctx context.Context, cc *grpc.ClientConn, methods []*desc.MethodDescriptor, methodName string, examples []standalone.Example, opts grpcui.FuzzOptions, outFile string) int {
+	methodName = strings.Replace(methodName, "/", ".", 1)
+	var md *desc.MethodDescriptor
+	for _, m := range methods {
+		if m.GetFullyQualifiedName() == methodName {
+			md = m
+			break
+		}
+	}
+	if md == nil {
+		fail(nil, "The method %q given via -fuzz is not exposed by the server.", methodName)
+	}
+	for _, ex := range examples {
+		if ex.Service+"."+ex.Method == methodName {
+			opts.Base = ex.Request.Data
+			break
+		}
+	}
+
+	fmt.Printf("Fuzzing %s with %d request(s), starting with seed %d\n", methodName, opts.Count, opts.Seed)
+	report, err := grpcui.Fuzz(ctx, cc, md, opts)
+	if err != nil {
+		fail(err, "Failed to fuzz %s", methodName)
+	}
+	const maxShown = 1000
+	for _, finding := range report.Findings {
+		req := string(finding.Request)
+		if len(req) > maxShown {
+			req = fmt.Sprintf("%s... (%d bytes)", req[:maxShown], len(req))
+		}
+		fmt.Printf("\n%s: %s (seed %d)\n  %s\n", finding.Code, finding.Message, finding.Seed, req)
+	}
+
+	codeNames := make([]string, 0, len(report.Codes))
+	for name := range report.Codes {
+		codeNames = append(codeNames, name)
+	}
+	sort.Strings(codeNames)
+	counts := make([]string, len(codeNames))
+	for i, name := range codeNames {
+		counts[i] = fmt.Sprintf("%s %d", name, report.Codes[name])
+	}
+	fmt.Printf("\n%d sent, %d skipped (%s)\n%d problem(s) found\n", report.Sent, report.Skipped, strings.Join(counts, ", "), len(report.Findings))
+
+	if outFile != "" && len(report.Findings) > 0 {
+		svc, method := md.GetService().GetFullyQualifiedName(), md.GetName()
+		findings := make([]standalone.Example, len(report.Findings))
+		for i, finding := range report.Findings {
+			findings[i] = standalone.Example{
+				Name:        fmt.Sprintf("fuzz seed %d", finding.Seed),
+				Description: fmt.Sprintf("%s: %s", finding.Code, finding.Message),
+				Service:     svc,
+				Method:      method,
+				// metadata from the command-line is not included since it
+				// may contain credentials
+				Request: standalone.ExampleRequest{Data: finding.Request},
+			}
+		}
+		js, err := json.MarshalIndent(findings, "", "  ")
+		if err == nil {
+			err = os.WriteFile(outFile, append(js, '\n'), 0644)
+		}
+		if err != nil {
+			fail(err, "Failed to write problems to %q", outFile)
+		}
+		fmt.Printf("Requests for problems written to %s\n", outFile)
+	}
+	if len(report.Findings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// isLoopback returns true if the given -bind address only accepts connections
+// from the local machine.
+func isLoopback(addr string) bool {
+	if addr == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
+
+// loadTokens reads a file of bearer tokens given via -auth-tokens, where each
+// line has the form "user:token", and returns a map of tokens to users.
+func loadTokens(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tokens := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, token, ok := strings.Cut(line, ":")
+		if !ok || user == "" || token == "" {
+			return nil, fmt.Errorf("line %d: expecting \"user:token\"", i+1)
+		}
+		tokens[token] = user
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens found")
+	}
+	return tokens, nil
+}
+
 func warn(msg string, args ...interface{}) {
 	msg = fmt.Sprintf("Warning: %s\n", msg)
 	fmt.Fprintf(os.Stderr, msg, args...)
@@ -826,6 +1593,18 @@ func checkAssetNames(soFar map[string]string, names []string, requireFile bool)
 	}
 }
 
+func writeProtoset(fileName string, files []*desc.FileDescriptor) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := grpcui.WriteProtoset(f, files...); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func configureJSandCSS(names []string, fn func(string, func() (io.ReadCloser, error)) standalone.HandlerOption) []standalone.HandlerOption {
 	opts := make([]standalone.HandlerOption, len(names))
 	for i := range names {
@@ -1097,6 +1876,79 @@ func dial(ctx context.Context, network, addr string, creds credentials.Transport
 	return nil, err
 }
 
+// getTokenSource returns the source of access tokens configured via
+// -token-command or -oauth2-token-url, or nil if neither is given.
+func getTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if *tokenCommand != "" {
+		args := strings.Fields(*tokenCommand)
+		if len(args) == 0 {
+			return nil, errors.New("-token-command is blank")
+		}
+		return grpcui.NewCommandTokenSource(args[0], args[1:]...), nil
+	}
+	if *oauth2TokenURL == "" {
+		return nil, nil
+	}
+	readSecret := func(file string) (string, error) {
+		if file == "" {
+			return "", nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	secret, err := readSecret(*oauth2ClientSecretFile)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := readSecret(*oauth2RefreshTokenFile)
+	if err != nil {
+		return nil, err
+	}
+	if *oauth2RefreshTokenFile != "" {
+		conf := oauth2.Config{
+			ClientID:     *oauth2ClientID,
+			ClientSecret: secret,
+			Endpoint:     oauth2.Endpoint{TokenURL: *oauth2TokenURL},
+			Scopes:       oauth2Scopes,
+		}
+		return conf.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}), nil
+	}
+	conf := clientcredentials.Config{
+		ClientID:     *oauth2ClientID,
+		ClientSecret: secret,
+		TokenURL:     *oauth2TokenURL,
+		Scopes:       oauth2Scopes,
+	}
+	return conf.TokenSource(ctx), nil
+}
+
+// tokenCredentials sends tokens from a token source as "authorization"
+// metadata with every RPC, including reflection requests. RPCs that already
+// have authorization metadata, like those invoked from the web form, which
+// gets it from the same token source, are left alone.
+type tokenCredentials struct {
+	ts oauth2.TokenSource
+}
+
+func (c tokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		return nil, nil
+	}
+	tok, err := c.ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": tok.Type() + " " + tok.AccessToken}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool {
+	// like the -H flag, tokens may be sent with -plaintext
+	return false
+}
+
 type errTrackingCreds struct {
 	credentials.TransportCredentials
 
@@ -1144,14 +1996,69 @@ func (c *errTrackingDialer) err() error {
 	return c.lastErr
 }
 
-func dumpResponse(r *http.Response, includeBody bool) (string, error) {
+// isSensitiveHeader returns true if values of the given HTTP header are masked
+// in the log. Credentials used to access the web UI itself are always masked,
+// in addition to metadata that the given redactor masks.
+func isSensitiveHeader(name string, redactor *grpcui.Redactor) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Cookie", "Set-Cookie":
+		return true
+	}
+	if *authProxyHeader != "" && strings.EqualFold(name, *authProxyHeader) {
+		return true
+	}
+	return redactor.IsSensitiveMetadata(name)
+}
+
+// maskHeaders returns a copy of the given headers with the values of sensitive
+// ones masked.
+func maskHeaders(h http.Header, redactor *grpcui.Redactor) http.Header {
+	masked := h.Clone()
+	for name := range masked {
+		if isSensitiveHeader(name, redactor) {
+			masked[name] = []string{"REDACTED"}
+		}
+	}
+	return masked
+}
+
+// dumpRequest is like httputil.DumpRequest, except that it masks sensitive
+// headers and sensitive fields and metadata in the bodies of requests to
+// invoke methods or to generate client code for them. The bodies of requests
+// to log in, which contain passwords, are never included.
+func dumpRequest(r *http.Request, includeBody bool, redactor *grpcui.Redactor, methods map[string]*desc.MethodDescriptor) ([]byte, error) {
+	clone := r.Clone(r.Context())
+	clone.Header = maskHeaders(r.Header, redactor)
+	if r.URL.Path == "/login" {
+		includeBody = false
+	}
+	if includeBody && r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if strings.HasPrefix(r.URL.Path, "/invoke/") || strings.HasPrefix(r.URL.Path, "/snippets/") {
+			if md := methods[path.Base(r.URL.Path)]; md != nil {
+				body = redactor.RedactRequest(md, body)
+			}
+		}
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		clone.ContentLength = int64(len(body))
+	}
+	return httputil.DumpRequest(clone, includeBody)
+}
+
+// dumpResponse formats the given response for the log, masking sensitive
+// headers, like cookies that are set upon logging in.
+func dumpResponse(r *http.Response, includeBody bool, redactor *grpcui.Redactor) (string, error) {
 	// NB: not using httputil.DumpResponse because it writes binary data in the body which is
 	//  not useful in the log (and can cause unexpected behavior when writing to a terminal,
 	//  which may interpret some byte sequences as control codes).
 	var buf bytes.Buffer
 	buf.WriteString(r.Status)
 	buf.WriteRune('\n')
-	if err := r.Header.Write(&buf); err != nil {
+	if err := maskHeaders(r.Header, redactor).Write(&buf); err != nil {
 		return "", err
 	}
 	if includeBody {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// selfSignedCertLifetime is how long a certificate generated for
+// -https-self-signed is valid. A new one is generated every time grpcui
+// starts.
+const selfSignedCertLifetime = 90 * 24 * time.Hour
+
+// httpsConfig returns the TLS config with which to serve the web UI, as
+// configured via the -https-* flags, or nil if the web UI is served over
+// plain HTTP.
+func httpsConfig() (*tls.Config, error) {
+	var cert tls.Certificate
+	switch {
+	case *httpsSelfSigned:
+		var err error
+		cert, err = selfSignedCert(selfSignedHosts(*bind), time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate self-signed certificate: %w", err)
+		}
+		fingerprint := sha256.Sum256(cert.Certificate[0])
+		fmt.Printf("Using self-signed certificate with SHA-256 fingerprint %s\n", formatFingerprint(fingerprint[:]))
+	case *httpsCert != "":
+		var err error
+		cert, err = tls.LoadX509KeyPair(*httpsCert, *httpsKey)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if *httpsClientCA != "" {
+		pem, err := os.ReadFile(*httpsClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %q", *httpsClientCA)
+		}
+		conf.ClientCAs = pool
+		// users without a certificate may still log in some other way, if
+		// other -auth-* flags are given
+		conf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return conf, nil
+}
+
+// selfSignedHosts returns the names and addresses for which a self-signed
+// certificate is generated, for a web UI bound to the given address.
+func selfSignedHosts(bindAddr string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+	// an unspecified address, like 0.0.0.0, can't be in a certificate
+	if ip := net.ParseIP(bindAddr); bindAddr != "" && (ip == nil || !ip.IsUnspecified()) {
+		for _, h := range hosts {
+			if h == bindAddr {
+				return hosts
+			}
+		}
+		hosts = append(hosts, bindAddr)
+	}
+	return hosts
+}
+
+// selfSignedCert generates a self-signed certificate, valid as of the given
+// time, for the given host names and IP addresses.
+func selfSignedCert(hosts []string, now time.Time) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return tls.Certificate{}, errors.New("no hosts given")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"grpcui"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// formatFingerprint formats a certificate fingerprint the way browsers show
+// it, as colon-separated hex bytes.
+func formatFingerprint(fingerprint []byte) string {
+	parts := make([]string, len(fingerprint))
+	for i, b := range fingerprint {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestSelfSignedCert(t *testing.T) {
+	now := time.Now()
+	cert, err := selfSignedCert([]string{"localhost", "127.0.0.1", "ui.example.com"}, now)
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "ui.example.com"} {
+		if err := cert.Leaf.VerifyHostname(host); err != nil {
+			t.Errorf("expecting certificate to be valid for %s: %v", host, err)
+		}
+	}
+	if err := cert.Leaf.VerifyHostname("other.example.com"); err == nil {
+		t.Errorf("expecting certificate to be invalid for other hosts")
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert.Leaf)
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: pool, CurrentTime: now}); err != nil {
+		t.Errorf("expecting certificate to verify against itself: %v", err)
+	}
+}
+
+func TestSelfSignedHosts(t *testing.T) {
+	for _, tc := range []struct {
+		bind     string
+		included bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"ui.example.com", true},
+		{"0.0.0.0", false},
+		{"::", false},
+	} {
+		hosts := selfSignedHosts(tc.bind)
+		count := 0
+		for _, h := range hosts {
+			if h == tc.bind {
+				count++
+			}
+		}
+		if (count == 1) != tc.included || count > 1 {
+			t.Errorf("-bind %s: unexpected hosts %v", tc.bind, hosts)
+		}
+	}
+}
+
+func TestFormatFingerprint(t *testing.T) {
+	if got := formatFingerprint([]byte{0x0a, 0xbc, 0xff}); got != "0A:BC:FF" {
+		t.Errorf("unexpected fingerprint: %s", got)
+	}
+}
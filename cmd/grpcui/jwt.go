@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtReservedClaims are set from dedicated flags and so can't be given via
+// -jwt-claim.
+var jwtReservedClaims = map[string]string{
+	"iss": "-jwt-issuer",
+	"sub": "-jwt-subject",
+	"aud": "-jwt-audience",
+	"iat": "-jwt-lifetime",
+	"exp": "-jwt-lifetime",
+}
+
+// jwtCredentials signs a fresh, short-lived JWT for every RPC and sends it as
+// a bearer token in "authorization" metadata, much like service accounts
+// that authenticate with self-signed JWTs.
+type jwtCredentials struct {
+	signer   crypto.Signer
+	alg      string
+	keyID    string
+	issuer   string
+	subject  string
+	audience string
+	lifetime time.Duration
+	claims   map[string]interface{}
+}
+
+// newJWTCredentials creates credentials that sign JWTs with the private key
+// in the given file. The file is either a PEM-encoded RSA, ECDSA, or Ed25519
+// key or a JSON service account key with "private_key", "private_key_id",
+// and "client_email" properties. For the latter, the key ID and the issuer
+// default to the values in the file.
+//
+// The audience may refer to the fully-qualified name of the service being
+// invoked as "{service}". If it is empty, the URI of the service, like
+// "https://host/acme.Orders", is used, as gRPC suggests for JWT audiences.
+// Each claim is in the form "name=value" and, if the value is valid JSON, it
+// is added as is, otherwise as a string.
+func newJWTCredentials(keyFile, keyID, issuer, subject, audience string, lifetime time.Duration, claims []string) (*jwtCredentials, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var keyPEM []byte
+	if js := strings.TrimSpace(string(data)); strings.HasPrefix(js, "{") {
+		var sa struct {
+			PrivateKey   string `json:"private_key"`
+			PrivateKeyID string `json:"private_key_id"`
+			ClientEmail  string `json:"client_email"`
+		}
+		if err := json.Unmarshal(data, &sa); err != nil {
+			return nil, fmt.Errorf("failed to parse service account key: %v", err)
+		}
+		if sa.PrivateKey == "" {
+			return nil, errors.New("service account key has no private_key")
+		}
+		keyPEM = []byte(sa.PrivateKey)
+		if keyID == "" {
+			keyID = sa.PrivateKeyID
+		}
+		if issuer == "" {
+			issuer = sa.ClientEmail
+		}
+	} else {
+		keyPEM = data
+	}
+	signer, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := jwtAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		subject = issuer
+	}
+
+	c := &jwtCredentials{
+		signer:   signer,
+		alg:      alg,
+		keyID:    keyID,
+		issuer:   issuer,
+		subject:  subject,
+		audience: audience,
+		lifetime: lifetime,
+		claims:   make(map[string]interface{}, len(claims)),
+	}
+	for _, claim := range claims {
+		name, val, ok := strings.Cut(claim, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("claim %q should be in the form name=value", claim)
+		}
+		if flagName, ok := jwtReservedClaims[name]; ok {
+			return nil, fmt.Errorf("claim %q can't be set directly; use %s instead", name, flagName)
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(val), &v); err != nil {
+			v = val
+		}
+		c.claims[name] = v
+	}
+	return c, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM-encoded private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("failed to parse %s: not a PKCS #8, PKCS #1, or EC private key", block.Type)
+}
+
+func jwtAlgorithm(signer crypto.Signer) (string, error) {
+	switch key := signer.Public().(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return "ES256", nil
+		case 384:
+			return "ES384", nil
+		case 521:
+			return "ES512", nil
+		}
+		return "", fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+func (c *jwtCredentials) GetRequestMetadata(_ context.Context, uri ...string) (map[string]string, error) {
+	var serviceURI string
+	if len(uri) > 0 {
+		serviceURI = uri[0]
+	}
+	tok, err := c.sign(c.audienceFor(serviceURI), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return map[string]string{"authorization": "Bearer " + tok}, nil
+}
+
+func (*jwtCredentials) RequireTransportSecurity() bool {
+	// like the -H flag, tokens may be sent with -plaintext
+	return false
+}
+
+// audienceFor returns the audience of a token for an RPC to the service with
+// the given URI.
+func (c *jwtCredentials) audienceFor(serviceURI string) string {
+	if c.audience == "" {
+		return serviceURI
+	}
+	var service string
+	if u, err := url.Parse(serviceURI); err == nil {
+		service = strings.TrimPrefix(u.Path, "/")
+	}
+	return strings.ReplaceAll(c.audience, "{service}", service)
+}
+
+// sign returns a JWT for the given audience that is issued at the given time.
+func (c *jwtCredentials) sign(audience string, now time.Time) (string, error) {
+	header := struct {
+		Alg   string `json:"alg"`
+		Type  string `json:"typ"`
+		KeyID string `json:"kid,omitempty"`
+	}{Alg: c.alg, Type: "JWT", KeyID: c.keyID}
+	claims := make(map[string]interface{}, len(c.claims)+5)
+	for k, v := range c.claims {
+		claims[k] = v
+	}
+	if c.issuer != "" {
+		claims["iss"] = c.issuer
+	}
+	if c.subject != "" {
+		claims["sub"] = c.subject
+	}
+	if audience != "" {
+		claims["aud"] = audience
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(c.lifetime).Unix()
+
+	headerJS, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJS, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(headerJS) + "." + base64.RawURLEncoding.EncodeToString(claimsJS)
+	sig, err := c.signature([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (c *jwtCredentials) signature(data []byte) ([]byte, error) {
+	switch c.alg {
+	case "EdDSA":
+		return c.signer.Sign(rand.Reader, data, crypto.Hash(0))
+	case "RS256":
+		digest := sha256.Sum256(data)
+		return c.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+
+	// ECDSA signatures in JWTs are the fixed-size concatenation of r and s,
+	// not the ASN.1 encoding that crypto.Signer returns
+	key, ok := c.signer.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signer %T for %s", c.signer, c.alg)
+	}
+	var digest []byte
+	switch c.alg {
+	case "ES256":
+		d := sha256.Sum256(data)
+		digest = d[:]
+	case "ES384":
+		d := sha512.Sum384(data)
+		digest = d[:]
+	default:
+		d := sha512.Sum512(data)
+		digest = d[:]
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		return nil, err
+	}
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return sig, nil
+}
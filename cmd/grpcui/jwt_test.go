@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKeyFile(t *testing.T, contents []byte) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(name, contents, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return name
+}
+
+// decodeJWT verifies the signature of the given JWT and returns its header
+// and claims.
+func decodeJWT(t *testing.T, tok string, pub crypto.PublicKey) (map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT: %s", tok)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("malformed signature: %v", err)
+	}
+	digest := sha256.Sum256(signed)
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		r, s := new(big.Int).SetBytes(sig[:len(sig)/2]), new(big.Int).SetBytes(sig[len(sig)/2:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			t.Errorf("invalid ECDSA signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			t.Errorf("invalid RSA signature: %v", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, signed, sig) {
+			t.Errorf("invalid Ed25519 signature")
+		}
+	}
+	var header, claims map[string]interface{}
+	for i, v := range []*map[string]interface{}{&header, &claims} {
+		js, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("malformed JWT part: %v", err)
+		}
+		if err := json.Unmarshal(js, v); err != nil {
+			t.Fatalf("malformed JWT part: %v", err)
+		}
+	}
+	return header, claims
+}
+
+func TestJWTCredentials(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyFile := writeKeyFile(t, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	creds, err := newJWTCredentials(keyFile, "k1", "ui@example.com", "", "https://{service}.example.com/", time.Minute, []string{"tier=gold", `roles=["a","b"]`})
+	if err != nil {
+		t.Fatalf("failed to create credentials: %v", err)
+	}
+	md, err := creds.GetRequestMetadata(t.Context(), "https://host:8443/acme.Orders")
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	tok, ok := strings.CutPrefix(md["authorization"], "Bearer ")
+	if !ok {
+		t.Fatalf("expecting bearer token; got %q", md["authorization"])
+	}
+	header, claims := decodeJWT(t, tok, &ecKey.PublicKey)
+	if header["alg"] != "ES256" || header["kid"] != "k1" {
+		t.Errorf("unexpected header: %v", header)
+	}
+	if claims["iss"] != "ui@example.com" || claims["sub"] != "ui@example.com" || claims["aud"] != "https://acme.Orders.example.com/" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+	if claims["tier"] != "gold" || len(claims["roles"].([]interface{})) != 2 {
+		t.Errorf("unexpected custom claims: %v", claims)
+	}
+	if exp, iat := claims["exp"].(float64), claims["iat"].(float64); exp-iat != 60 {
+		t.Errorf("expecting token to expire in a minute; got iat %v and exp %v", iat, exp)
+	}
+
+	// a fresh token is signed for every RPC
+	md2, err := creds.GetRequestMetadata(t.Context(), "https://host:8443/acme.Orders")
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if md2["authorization"] == md["authorization"] {
+		t.Errorf("expecting a new token for each RPC")
+	}
+
+	if _, err := newJWTCredentials(keyFile, "", "", "", "", time.Minute, []string{"aud=x"}); err == nil || !strings.Contains(err.Error(), "-jwt-audience") {
+		t.Errorf("expecting error for reserved claim; got %v", err)
+	}
+	if _, err := newJWTCredentials(keyFile, "", "", "", "", time.Minute, []string{"tier"}); err == nil {
+		t.Errorf("expecting error for malformed claim")
+	}
+}
+
+func TestJWTCredentials_ServiceAccountKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	sa, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"private_key_id": "abc123",
+		"client_email":   "ui@acme.iam.example.com",
+	})
+	creds, err := newJWTCredentials(writeKeyFile(t, sa), "", "", "", "", time.Minute, nil)
+	if err != nil {
+		t.Fatalf("failed to create credentials: %v", err)
+	}
+	md, err := creds.GetRequestMetadata(t.Context(), "https://host/acme.Orders")
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	header, claims := decodeJWT(t, strings.TrimPrefix(md["authorization"], "Bearer "), &rsaKey.PublicKey)
+	if header["alg"] != "RS256" || header["kid"] != "abc123" {
+		t.Errorf("unexpected header: %v", header)
+	}
+	if claims["iss"] != "ui@acme.iam.example.com" || claims["sub"] != "ui@acme.iam.example.com" || claims["aud"] != "https://host/acme.Orders" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+}
+
+func TestJWTCredentials_Ed25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	creds, err := newJWTCredentials(writeKeyFile(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), "", "", "", "{service}", time.Minute, nil)
+	if err != nil {
+		t.Fatalf("failed to create credentials: %v", err)
+	}
+	md, err := creds.GetRequestMetadata(t.Context(), "https://host/acme.Orders")
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	header, claims := decodeJWT(t, strings.TrimPrefix(md["authorization"], "Bearer "), pub)
+	if header["alg"] != "EdDSA" || claims["aud"] != "acme.Orders" {
+		t.Errorf("unexpected JWT: %v %v", header, claims)
+	}
+	if _, ok := claims["iss"]; ok {
+		t.Errorf("expecting no issuer; got %v", claims["iss"])
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+)
+
+func TestWriteProtoset(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"orders.proto": `
+				syntax = "proto3";
+				package acme;
+				import "google/protobuf/empty.proto";
+				service Orders {
+					rpc Ping(google.protobuf.Empty) returns (google.protobuf.Empty);
+				}`,
+		}),
+		LookupImport: desc.LoadFileDescriptor,
+	}
+	fds, err := p.ParseFiles("orders.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "out.protoset")
+	if err := writeProtoset(fileName, fds); err != nil {
+		t.Fatalf("failed to write protoset: %v", err)
+	}
+	source, err := grpcurl.DescriptorSourceFromProtoSets(fileName)
+	if err != nil {
+		t.Fatalf("failed to read protoset: %v", err)
+	}
+	services, err := source.ListServices()
+	if err != nil || len(services) != 1 || services[0] != "acme.Orders" {
+		t.Errorf("unexpected services: %v, %v", services, err)
+	}
+	// dependencies are included, too
+	if _, err := source.FindSymbol("google.protobuf.Empty"); err != nil {
+		t.Errorf("expecting dependency in protoset: %v", err)
+	}
+
+	if err := writeProtoset(filepath.Join(t.TempDir(), "missing", "out.protoset"), fds); err == nil {
+		t.Errorf("expecting error when file can't be created")
+	}
+	if _, err := os.Stat("/dev/full"); err == nil {
+		if err := writeProtoset("/dev/full", fds); err == nil {
+			t.Errorf("expecting error when file can't be written")
+		}
+	}
+}
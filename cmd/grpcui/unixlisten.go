@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// parseFileMode parses permissions given in octal, like "0660".
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not a valid octal file mode", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// listenUnix listens on a Unix domain socket at the given path, with the
+// given permissions. A socket left behind by an earlier run that was killed
+// is removed first, but one that another process is still listening on is
+// not. The socket is removed when the returned listener is closed.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s already exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	// the socket is created with permissions from the umask, so it is
+	// created in a directory only we can access and only moved into place
+	// once it has the given permissions; otherwise anyone could connect
+	// before it is chmod'ed
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".grpcui")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	tmpPath := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	ul := l.(*net.UnixListener)
+	// the listener would otherwise remove the temporary path on close
+	ul.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: ul, path: path}, nil
+}
+
+// unixListener removes the socket at path when it is closed.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
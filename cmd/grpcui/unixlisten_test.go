@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	if mode, err := parseFileMode("0660"); err != nil || mode != 0660 {
+		t.Errorf("unexpected result: %v, %v", mode, err)
+	}
+	for _, s := range []string{"", "rw", "0999", "01777"} {
+		if _, err := parseFileMode(s); err == nil {
+			t.Errorf("expecting error for %q", s)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	// socket paths are limited in length, so don't use t.TempDir, whose
+	// paths include the test name
+	dir, err := os.MkdirTemp("", "grpcui")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	path := filepath.Join(dir, "ui.sock")
+
+	l, err := listenUnix(path, 0660)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0660 {
+		t.Errorf("expecting socket with mode 0660; got %v, %v", fi, err)
+	}
+	// the socket is created elsewhere and moved into place, which leaves
+	// nothing else behind
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("expecting only the socket in %s; got %v, %v", dir, entries, err)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to connect to socket: %v", err)
+	}
+	_ = conn.Close()
+	if _, err := listenUnix(path, 0660); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("expecting error for socket in use; got %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("failed to close listener: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expecting socket to be removed on close; got %v", err)
+	}
+
+	// a stale socket, as left behind by a process that was killed
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+	l, err = listenUnix(path, 0600)
+	if err != nil {
+		t.Fatalf("expecting stale socket to be replaced; got %v", err)
+	}
+	_ = l.Close()
+
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := listenUnix(path, 0600); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("expecting error for existing file; got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package grpcui
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// confirmTokenTTL is how long a confirmation token remains valid after the
+// invoke handler issues it.
+const confirmTokenTTL = 2 * time.Minute
+
+// MayHaveSideEffects returns true unless the given method is marked with the
+// option "idempotency_level = NO_SIDE_EFFECTS" or "idempotency_level =
+// IDEMPOTENT". It is suitable for use as InvokeOptions.ConfirmMethod.
+func MayHaveSideEffects(md *desc.MethodDescriptor) bool {
+	switch md.GetMethodOptions().GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_NO_SIDE_EFFECTS, descriptorpb.MethodOptions_IDEMPOTENT:
+		return false
+	default:
+		return true
+	}
+}
+
+// errConfirmationRequired is returned by invokeRPC when the method requires
+// confirmation and the request did not include a valid confirmation token.
+type errConfirmationRequired struct {
+	method string
+	token  string
+}
+
+func (e errConfirmationRequired) Error() string {
+	return fmt.Sprintf("%s may have side effects and must be confirmed", e.method)
+}
+
+// confirmationResponse is the body of a "428 Precondition Required" response
+// to an invocation request that must be confirmed. To confirm, the client
+// sends the same request again, with the given token as its confirm_token.
+type confirmationResponse struct {
+	ConfirmToken string `json:"confirm_token"`
+	Message      string `json:"message"`
+}
+
+// confirmer issues and checks confirmation tokens. A token is only valid for
+// the exact request it was issued for, expires after confirmTokenTTL, and can
+// only be used once, so neither a stray click nor a replayed request can
+// invoke a method without a fresh confirmation.
+type confirmer struct {
+	key []byte
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func newConfirmer() *confirmer {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate confirmation key: %v", err))
+	}
+	return &confirmer{key: key, used: map[string]time.Time{}}
+}
+
+// check returns nil if the given input carries a valid confirmation token for
+// the given method. Otherwise, it returns an errConfirmationRequired with a
+// new token for the input.
+func (c *confirmer) check(method string, input *rpcInput) error {
+	now := time.Now()
+	if c.valid(method, input, now) {
+		return nil
+	}
+	expiry := strconv.FormatInt(now.Add(confirmTokenTTL).Unix(), 10)
+	return errConfirmationRequired{
+		method: method,
+		token:  expiry + "." + c.sign(method, input, expiry),
+	}
+}
+
+func (c *confirmer) valid(method string, input *rpcInput, now time.Time) bool {
+	expiry, sig, ok := strings.Cut(input.ConfirmToken, ".")
+	if !ok || sig == "" {
+		return false
+	}
+	exp, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return false
+	}
+	if !hmac.Equal([]byte(sig), []byte(c.sign(method, input, expiry))) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, exp := range c.used {
+		if now.After(exp) {
+			delete(c.used, t)
+		}
+	}
+	if _, ok := c.used[input.ConfirmToken]; ok {
+		return false
+	}
+	c.used[input.ConfirmToken] = time.Unix(exp, 0)
+	return true
+}
+
+// sign computes the signature of a token for the given request and expiry.
+// It covers everything in the request that determines what gets invoked.
+func (c *confirmer) sign(method string, input *rpcInput, expiry string) string {
+	js, err := json.Marshal(struct {
+		Method   string            `json:"method"`
+		Expiry   string            `json:"expiry"`
+		Metadata []rpcMetadata     `json:"metadata"`
+		Data     []json.RawMessage `json:"data"`
+	}{method, expiry, input.Metadata, input.Data})
+	if err != nil {
+		// can't happen since the input was unmarshaled from JSON; an empty
+		// signature is never valid
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(js)
+	return hex.EncodeToString(mac.Sum(nil))
+}
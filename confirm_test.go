@@ -0,0 +1,121 @@
+package grpcui
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestMayHaveSideEffects(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"orders.proto": `
+				syntax = "proto3";
+				package acme;
+				message Order {}
+				service Orders {
+					rpc GetOrder(Order) returns (Order) {
+						option idempotency_level = NO_SIDE_EFFECTS;
+					}
+					rpc DeleteOrder(Order) returns (Order) {
+						option idempotency_level = IDEMPOTENT;
+					}
+					rpc CreateOrder(Order) returns (Order);
+				}`,
+		}),
+	}
+	fds, err := p.ParseFiles("orders.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	for _, md := range fds[0].GetServices()[0].GetMethods() {
+		if got, expected := MayHaveSideEffects(md), md.GetName() == "CreateOrder"; got != expected {
+			t.Errorf("MayHaveSideEffects(%s) = %v", md.GetName(), got)
+		}
+	}
+}
+
+func TestRPCInvokeHandler_Confirmation(t *testing.T) {
+	invoked := 0
+	svr := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+			return err
+		}
+		invoked++
+		return stream.SendMsg(&emptypb.Empty{})
+	}))
+	l := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = svr.Serve(l)
+	}()
+	defer svr.Stop()
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer cc.Close()
+
+	methods := loadTestMethods(t)
+	h := RPCInvokeHandlerWithOptions(cc, methods, InvokeOptions{ConfirmMethod: MayHaveSideEffects})
+	invoke := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/test.KitchenSink.Ping", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	withToken := func(body, token string) string {
+		return strings.TrimSuffix(body, "}") + `,"confirm_token":"` + token + `"}`
+	}
+
+	body := `{"metadata":[{"name":"x-test","value":"a"}],"data":[{}]}`
+	rec := invoke(body)
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expecting status 428; got %d: %s", rec.Code, rec.Body.String())
+	}
+	var conf confirmationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &conf); err != nil || conf.ConfirmToken == "" || conf.Message == "" {
+		t.Fatalf("unexpected confirmation response: %s", rec.Body.String())
+	}
+	if invoked != 0 {
+		t.Fatalf("method invoked without confirmation")
+	}
+
+	// the token is bound to the request it was issued for
+	other := `{"metadata":[{"name":"x-test","value":"b"}],"data":[{}]}`
+	if rec := invoke(withToken(other, conf.ConfirmToken)); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("expecting token to be rejected for a different request; got %d", rec.Code)
+	}
+	if rec := invoke(withToken(body, "1."+strings.SplitN(conf.ConfirmToken, ".", 2)[1])); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("expecting expired token to be rejected; got %d", rec.Code)
+	}
+	if rec := invoke(withToken(body, conf.ConfirmToken)); rec.Code != http.StatusOK {
+		t.Fatalf("expecting confirmed request to succeed; got %d: %s", rec.Code, rec.Body.String())
+	}
+	if invoked != 1 {
+		t.Errorf("expecting method to be invoked once; got %d", invoked)
+	}
+	// and it can only be used once
+	if rec := invoke(withToken(body, conf.ConfirmToken)); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("expecting replayed token to be rejected; got %d", rec.Code)
+	}
+
+	h = RPCInvokeHandlerWithOptions(cc, methods, InvokeOptions{})
+	if rec := invoke(body); rec.Code != http.StatusOK {
+		t.Errorf("expecting no confirmation without ConfirmMethod; got %d", rec.Code)
+	}
+}
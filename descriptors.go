@@ -0,0 +1,151 @@
+package grpcui
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RPCDescriptorsHandler returns an HTTP handler that can be used to download
+// the descriptors for the given methods. This allows users of the web UI to
+// get the schema for the exposed services, even when that schema was obtained
+// via server reflection, so that they can generate client stubs from it.
+//
+// The handler accepts GET requests. The "format" query parameter controls the
+// format of the response:
+//   - "protoset" (the default) returns an encoded FileDescriptorSet that
+//     includes the files that define the exposed services and all of their
+//     transitive dependencies.
+//   - "proto" returns a zip archive of proto source files, reconstructed from
+//     the same set of descriptors.
+//
+// The optional "service" query parameter restricts the results to only the
+// files needed for a single service. It must be the fully-qualified name of
+// one of the services for the given methods. If absent, the response covers
+// all services exposed.
+func RPCDescriptorsHandler(methods []*desc.MethodDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		svcName := r.URL.Query().Get("service")
+		var files []*desc.FileDescriptor
+		seen := map[string]struct{}{}
+		for _, md := range methods {
+			sd := md.GetService()
+			if svcName != "" && sd.GetFullyQualifiedName() != svcName {
+				continue
+			}
+			fd := sd.GetFile()
+			if _, ok := seen[fd.GetName()]; ok {
+				continue
+			}
+			seen[fd.GetName()] = struct{}{}
+			files = append(files, fd)
+		}
+		if len(files) == 0 {
+			http.Error(w, "Unknown service", http.StatusUnprocessableEntity)
+			return
+		}
+
+		baseName := "grpcui"
+		if svcName != "" {
+			baseName = svcName
+		}
+
+		var buf bytes.Buffer
+		var contentType, fileName string
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "protoset":
+			if err := WriteProtoset(&buf, files...); err != nil {
+				http.Error(w, "Failed to create protoset: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			contentType = "application/octet-stream"
+			fileName = baseName + ".protoset"
+		case "proto":
+			if err := WriteProtoSources(&buf, files...); err != nil {
+				http.Error(w, "Failed to create proto sources: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			contentType = "application/zip"
+			fileName = baseName + "-protos.zip"
+		default:
+			http.Error(w, fmt.Sprintf("Unsupported format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		_, _ = io.Copy(w, &buf)
+	})
+}
+
+// WriteProtoset writes the given files, along with all of their transitive
+// dependencies, to the given writer as an encoded FileDescriptorSet. Files in
+// the set are ordered so that every file appears after its dependencies.
+func WriteProtoset(out io.Writer, files ...*desc.FileDescriptor) error {
+	fds := transitiveFiles(files)
+	set := descriptorpb.FileDescriptorSet{
+		File: make([]*descriptorpb.FileDescriptorProto, len(fds)),
+	}
+	for i, fd := range fds {
+		set.File[i] = fd.AsFileDescriptorProto()
+	}
+	b, err := proto.Marshal(&set)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(b)
+	return err
+}
+
+// WriteProtoSources writes the given files, along with all of their transitive
+// dependencies, to the given writer as a zip archive of proto source files.
+// The sources are reconstructed from the descriptors, so they will include
+// comments only if the descriptors include source code info.
+func WriteProtoSources(out io.Writer, files ...*desc.FileDescriptor) error {
+	var pr protoprint.Printer
+	zw := zip.NewWriter(out)
+	for _, fd := range transitiveFiles(files) {
+		f, err := zw.Create(strings.TrimPrefix(fd.GetName(), "/"))
+		if err != nil {
+			return err
+		}
+		if err := pr.PrintProtoFile(fd, f); err != nil {
+			return fmt.Errorf("failed to print %s: %w", fd.GetName(), err)
+		}
+	}
+	return zw.Close()
+}
+
+func transitiveFiles(files []*desc.FileDescriptor) []*desc.FileDescriptor {
+	var results []*desc.FileDescriptor
+	seen := map[string]struct{}{}
+	var addFile func(fd *desc.FileDescriptor)
+	addFile = func(fd *desc.FileDescriptor) {
+		if _, ok := seen[fd.GetName()]; ok {
+			return
+		}
+		seen[fd.GetName()] = struct{}{}
+		for _, dep := range fd.GetDependencies() {
+			addFile(dep)
+		}
+		results = append(results, fd)
+	}
+	for _, fd := range files {
+		addFile(fd)
+	}
+	return results
+}
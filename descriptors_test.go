@@ -0,0 +1,111 @@
+package grpcui
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// loadTestMethods returns all methods in the test server's proto, which has a
+// rich set of field and message types for exercising descriptor-driven code.
+func loadTestMethods(t *testing.T) []*desc.MethodDescriptor {
+	t.Helper()
+	p := protoparse.Parser{
+		ImportPaths:           []string{"testing/cmd/testsvr"},
+		IncludeSourceCodeInfo: true,
+	}
+	fds, err := p.ParseFiles("test.proto")
+	if err != nil {
+		t.Fatalf("failed to parse test proto: %v", err)
+	}
+	return AllMethodsForServices(fds[0].GetServices())
+}
+
+func TestRPCDescriptorsHandler_Protoset(t *testing.T) {
+	h := RPCDescriptorsHandler(loadTestMethods(t))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/descriptors?service=test.KitchenSink", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatalf("failed to unmarshal protoset: %v", err)
+	}
+	// dependencies must come before the files that import them
+	seen := map[string]bool{}
+	for _, fd := range set.File {
+		for _, dep := range fd.Dependency {
+			if !seen[dep] {
+				t.Errorf("file %s appears before its dependency %s", fd.GetName(), dep)
+			}
+		}
+		seen[fd.GetName()] = true
+	}
+	if !seen["test.proto"] || !seen["google/protobuf/timestamp.proto"] {
+		t.Errorf("protoset is missing expected files: %v", seen)
+	}
+}
+
+func TestRPCDescriptorsHandler_ProtoSources(t *testing.T) {
+	h := RPCDescriptorsHandler(loadTestMethods(t))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/descriptors?format=proto", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to read zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "test.proto" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open %s: %v", f.Name, err)
+			}
+			src, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			if !bytes.Contains(src, []byte("service KitchenSink {")) {
+				t.Errorf("test.proto source is missing service definition:\n%s", src)
+			}
+		}
+	}
+	sort.Strings(names)
+	if i := sort.SearchStrings(names, "test.proto"); i == len(names) || names[i] != "test.proto" {
+		t.Errorf("zip is missing test.proto: %v", names)
+	}
+}
+
+func TestRPCDescriptorsHandler_Errors(t *testing.T) {
+	h := RPCDescriptorsHandler(loadTestMethods(t))
+
+	testCases := []struct {
+		method, uri string
+		code        int
+	}{
+		{"GET", "/descriptors?service=foo.Bar", http.StatusUnprocessableEntity},
+		{"GET", "/descriptors?format=yaml", http.StatusBadRequest},
+		{"POST", "/descriptors", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.uri, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.uri, tc.code, rec.Code)
+		}
+	}
+}
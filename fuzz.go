@@ -0,0 +1,517 @@
+package grpcui
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/golang/protobuf/proto"  //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// FuzzOptions contains optional arguments when fuzzing a method.
+type FuzzOptions struct {
+	// The seed for the first request. Each subsequent request uses the next
+	// seed, so a single request can be reproduced by fuzzing with its seed
+	// and a count of one.
+	Seed int64
+	// The number of requests to send. If zero, 100 requests are sent.
+	Count int
+	// Metadata to send with each request. Each value should be in the form
+	// "name: value".
+	Metadata []string
+	// The timeout for each request. If zero, a timeout of ten seconds is used.
+	Timeout time.Duration
+	// The length of "huge" strings and bytes values. If zero, 64 KiB is used.
+	// The server's maximum message size may need to be increased for larger
+	// values.
+	MaxLength int
+	// Request data that is mutated to produce some of the requests. Mutated
+	// requests are more likely to get past a server's initial validation of
+	// a request. If nil, sample data from SampleData is used.
+	Base interface{}
+}
+
+// FuzzFinding describes a fuzzed request that the server failed to handle
+// properly.
+type FuzzFinding struct {
+	// The seed that generated the request.
+	Seed int64 `json:"seed"`
+	// The request data. For client-streaming methods, this is an array of
+	// request messages.
+	Request json.RawMessage `json:"request"`
+	// The name of the status code, like "Internal".
+	Code string `json:"code"`
+	// The status message.
+	Message string `json:"message"`
+}
+
+// FuzzReport summarizes the results of fuzzing a method.
+type FuzzReport struct {
+	// The fully-qualified name of the method.
+	Method string `json:"method"`
+	// The number of requests sent to the server.
+	Sent int `json:"sent"`
+	// The number of generated requests that were not sent because they could
+	// not be encoded for the method's request type. This can happen when a
+	// value is out of range for the field's type, for example.
+	Skipped int `json:"skipped"`
+	// The number of requests that completed with each status code.
+	Codes map[string]int `json:"codes"`
+	// The requests that the server failed to handle properly.
+	Findings []FuzzFinding `json:"findings"`
+}
+
+// fuzzFailureCodes are the status codes that indicate a server failed to
+// properly handle a request, instead of rejecting it. Servers usually convert
+// panics into Unknown or Internal errors, and a server that crashes will make
+// the request fail with Unavailable.
+var fuzzFailureCodes = map[codes.Code]bool{
+	codes.Unknown:     true,
+	codes.Internal:    true,
+	codes.DataLoss:    true,
+	codes.Unavailable: true,
+}
+
+// Fuzz sends generated requests for the given method using the given channel
+// and reports those that the server failed to handle properly. These are
+// requests that completed with an Unknown, Internal, DataLoss, or Unavailable
+// status, or whose status message mentions a panic.
+//
+// Requests are generated from the method's request type, using boundary
+// values for numbers, empty and huge strings, invalid UTF-8 in bytes, unknown
+// enum values, deeply nested messages, empty one-ofs, and empty and large
+// repeated and map fields. Some requests are generated from scratch and
+// others by mutating the given base data. Generation is deterministic, so the
+// seed in a finding can be used to reproduce its request.
+//
+// An error is returned only if the context is cancelled.
+func Fuzz(ctx context.Context, ch grpc.ClientConnInterface, md *desc.MethodDescriptor, opts FuzzOptions) (*FuzzReport, error) {
+	count := opts.Count
+	if count <= 0 {
+		count = 100
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+	if err != nil {
+		return nil, err
+	}
+	unmarshaler := jsonpb.Unmarshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSource(descSource)}
+
+	report := &FuzzReport{
+		Method: md.GetFullyQualifiedName(),
+		Codes:  map[string]int{},
+	}
+	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		seed := opts.Seed + int64(i)
+		js := FuzzRequest(md, seed, opts)
+		msgs := []json.RawMessage{js}
+		if md.IsClientStreaming() {
+			msgs = nil
+			_ = json.Unmarshal(js, &msgs)
+		}
+
+		// make sure the request can be sent before counting it
+		valid := true
+		for _, msg := range msgs {
+			if err := unmarshaler.Unmarshal(bytes.NewReader(msg), dynamic.NewMessage(md.GetInputType())); err != nil {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			report.Skipped++
+			continue
+		}
+
+		stat := fuzzInvoke(ctx, ch, descSource, unmarshaler, md, opts.Metadata, timeout, msgs)
+		report.Sent++
+		name := stat.Code().String()
+		report.Codes[name]++
+		if fuzzFailureCodes[stat.Code()] || strings.Contains(strings.ToLower(stat.Message()), "panic") {
+			report.Findings = append(report.Findings, FuzzFinding{
+				Seed:    seed,
+				Request: js,
+				Code:    name,
+				Message: stat.Message(),
+			})
+		}
+	}
+	return report, nil
+}
+
+func fuzzInvoke(ctx context.Context, ch grpc.ClientConnInterface, descSource grpcurl.DescriptorSource, unmarshaler jsonpb.Unmarshaler, md *desc.MethodDescriptor, hdrs []string, timeout time.Duration, reqs []json.RawMessage) *status.Status {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	requestFunc := func(m proto.Message) error {
+		if len(reqs) == 0 {
+			return io.EOF
+		}
+		req := reqs[0]
+		reqs = reqs[1:]
+		return unmarshaler.Unmarshal(bytes.NewReader(req), m)
+	}
+	var handler fuzzHandler
+	if err := grpcurl.InvokeRPC(ctx, descSource, ch, md.GetFullyQualifiedName(), hdrs, &handler, requestFunc); err != nil {
+		return status.Convert(err)
+	}
+	if handler.status == nil {
+		// grpcurl reports a nil status for successful unary RPCs
+		return status.New(codes.OK, "")
+	}
+	return handler.status
+}
+
+// fuzzHandler records the status of a fuzzed request, ignoring responses.
+type fuzzHandler struct {
+	status *status.Status
+}
+
+func (*fuzzHandler) OnResolveMethod(*desc.MethodDescriptor) {}
+
+func (*fuzzHandler) OnSendHeaders(metadata.MD) {}
+
+func (*fuzzHandler) OnReceiveHeaders(metadata.MD) {}
+
+func (*fuzzHandler) OnReceiveResponse(proto.Message) {}
+
+func (h *fuzzHandler) OnReceiveTrailers(stat *status.Status, _ metadata.MD) {
+	h.status = stat
+}
+
+// FuzzRequest returns the request data that Fuzz generates for the given
+// method and seed. For client-streaming methods, the data is an array of
+// request messages.
+func FuzzRequest(md *desc.MethodDescriptor, seed int64, opts FuzzOptions) json.RawMessage {
+	maxLen := opts.MaxLength
+	if maxLen <= 0 {
+		maxLen = 64 * 1024
+	}
+	f := fuzzer{rnd: rand.New(rand.NewSource(seed)), maxLen: maxLen}
+	base := opts.Base
+	if base == nil {
+		base = SampleData(md.GetInputType(), SampleDataOptions{Seed: seed})
+	}
+
+	var msgs []interface{}
+	n := 1
+	if md.IsClientStreaming() {
+		n = f.rnd.Intn(4)
+		if baseMsgs, ok := base.([]interface{}); ok && len(baseMsgs) > 0 {
+			base = baseMsgs[0]
+		}
+	}
+	for i := 0; i < n; i++ {
+		msgs = append(msgs, f.request(md.GetInputType(), base))
+	}
+
+	var data interface{} = msgs
+	if !md.IsClientStreaming() {
+		data = msgs[0]
+	}
+	js, err := json.Marshal(data)
+	if err != nil {
+		// should not happen, since we only generate values that can be
+		// encoded as JSON
+		return json.RawMessage("{}")
+	}
+	return js
+}
+
+type fuzzer struct {
+	rnd      *rand.Rand
+	maxLen   int
+	maxDepth int
+}
+
+// request returns either a new random message or a mutation of the given base
+// data.
+func (f *fuzzer) request(md *desc.MessageDescriptor, base interface{}) interface{} {
+	// most requests are a few levels deep, but some are very deep
+	f.maxDepth = []int{2, 4, 4, 64}[f.rnd.Intn(4)]
+	if baseMsg, ok := base.(map[string]interface{}); ok && !isWellKnownType(md) && f.rnd.Intn(2) == 0 {
+		return f.mutate(md, copyJSONValue(baseMsg).(map[string]interface{}), 0)
+	}
+	return f.message(md, 0)
+}
+
+// mutate replaces some of the fields in the given message with fuzzed values
+// and removes others.
+func (f *fuzzer) mutate(md *desc.MessageDescriptor, msg map[string]interface{}, depth int) interface{} {
+	fields := md.GetFields()
+	if len(fields) == 0 {
+		return msg
+	}
+	for n := 1 + f.rnd.Intn(3); n > 0; n-- {
+		fd := fields[f.rnd.Intn(len(fields))]
+		name := fd.GetJSONName()
+		switch {
+		case f.rnd.Intn(4) == 0 && !fd.IsRequired():
+			delete(msg, name)
+		case fd.GetMessageType() != nil && !fd.IsRepeated() && !isWellKnownType(fd.GetMessageType()):
+			if nested, ok := msg[name].(map[string]interface{}); ok && depth < 8 {
+				msg[name] = f.mutate(fd.GetMessageType(), nested, depth+1)
+				break
+			}
+			fallthrough
+		default:
+			if ood := fd.GetOneOf(); ood != nil && !ood.IsSynthetic() {
+				// only one field in a one-of may be set
+				for _, choice := range ood.GetChoices() {
+					delete(msg, choice.GetJSONName())
+				}
+			}
+			msg[name] = f.field(fd, depth)
+		}
+	}
+	return msg
+}
+
+func copyJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = copyJSONValue(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, val := range v {
+			s[i] = copyJSONValue(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+func isWellKnownType(md *desc.MessageDescriptor) bool {
+	return md.GetFile().GetPackage() == "google.protobuf"
+}
+
+func (f *fuzzer) message(md *desc.MessageDescriptor, depth int) interface{} {
+	if v, ok := f.wellKnown(md); ok {
+		return v
+	}
+	result := map[string]interface{}{}
+	// past a few levels, only one message field is populated in each
+	// message, so that deep nesting doesn't generate exponentially large
+	// messages
+	chain := depth >= 4
+	chained := false
+	oneOfsSeen := map[*desc.OneOfDescriptor]bool{}
+	for _, fd := range md.GetFields() {
+		if ood := fd.GetOneOf(); ood != nil && !ood.IsSynthetic() {
+			if oneOfsSeen[ood] {
+				continue
+			}
+			oneOfsSeen[ood] = true
+			choices := ood.GetChoices()
+			// sometimes leave the one-of empty
+			i := f.rnd.Intn(len(choices) + 1)
+			if i == len(choices) {
+				continue
+			}
+			fd = choices[i]
+		} else if !fd.IsRequired() && f.rnd.Intn(3) == 0 {
+			continue
+		}
+		if fd.GetMessageType() != nil && !isWellKnownType(fd.GetMessageType()) {
+			if depth >= f.maxDepth || (chain && chained) {
+				if !fd.IsRequired() || depth >= f.maxDepth+maxRequiredDepth {
+					continue
+				}
+			}
+			chained = true
+		}
+		result[fd.GetJSONName()] = f.field(fd, depth)
+	}
+	return result
+}
+
+func (f *fuzzer) field(fd *desc.FieldDescriptor, depth int) interface{} {
+	switch {
+	case fd.IsMap():
+		m := map[string]interface{}{}
+		for i := []int{0, 1, 3}[f.rnd.Intn(3)]; i > 0; i-- {
+			key := fmt.Sprint(f.single(fd.GetMapKeyType(), depth))
+			m[key] = f.single(fd.GetMapValueType(), depth)
+		}
+		return m
+	case fd.IsRepeated():
+		sizes := []int{0, 1, 3, 100}
+		if fd.GetMessageType() != nil || fd.GetType() == descriptor.FieldDescriptorProto_TYPE_STRING ||
+			fd.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES {
+			// avoid lots of large values
+			sizes = sizes[:3]
+		}
+		elems := []interface{}{}
+		for i := sizes[f.rnd.Intn(len(sizes))]; i > 0; i-- {
+			elems = append(elems, f.single(fd, depth))
+		}
+		return elems
+	default:
+		return f.single(fd, depth)
+	}
+}
+
+func (f *fuzzer) single(fd *desc.FieldDescriptor, depth int) interface{} {
+	if msg := fd.GetMessageType(); msg != nil {
+		return f.message(msg, depth+1)
+	}
+	if enum := fd.GetEnumType(); enum != nil {
+		vals := enum.GetValues()
+		// proto3 enums are open, so the server must handle unknown values
+		if fd.GetFile().IsProto3() && f.rnd.Intn(4) == 0 {
+			return []int32{-1, 999999, math.MaxInt32}[f.rnd.Intn(3)]
+		}
+		return vals[f.rnd.Intn(len(vals))].GetName()
+	}
+	return f.scalar(fd)
+}
+
+func (f *fuzzer) scalar(fd *desc.FieldDescriptor) interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return f.string()
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return base64.StdEncoding.EncodeToString(f.bytes())
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return f.rnd.Intn(2) == 1
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return f.pick("NaN", "Infinity", "-Infinity", 0, -1, math.MaxFloat32, -math.MaxFloat32, math.SmallestNonzeroFloat32)
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return f.pick("NaN", "Infinity", "-Infinity", 0, -1, math.MaxFloat64, -math.MaxFloat64, math.SmallestNonzeroFloat64)
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return f.pick(0, 1, -1, math.MaxInt32, math.MinInt32, f.rnd.Int31()-f.rnd.Int31())
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return f.pick(0, 1, math.MaxUint32, math.MaxInt32+1, f.rnd.Uint32())
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		// 64-bit int values are represented as strings in JSON
+		return strconv.FormatInt(f.pick(int64(0), int64(1), int64(-1), int64(math.MaxInt64), int64(math.MinInt64),
+			int64(math.MaxInt32)+1, f.rnd.Int63()-f.rnd.Int63()).(int64), 10)
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return strconv.FormatUint(f.pick(uint64(0), uint64(1), uint64(math.MaxUint64), uint64(math.MaxInt64)+1,
+			f.rnd.Uint64()).(uint64), 10)
+	default:
+		return 0
+	}
+}
+
+func (f *fuzzer) pick(vals ...interface{}) interface{} {
+	return vals[f.rnd.Intn(len(vals))]
+}
+
+// fuzzStrings are strings that servers often fail to handle: control
+// characters, format specifiers, characters with special meaning in common
+// syntaxes, and unusual Unicode.
+var fuzzStrings = []string{
+	"",
+	" ",
+	"\x00",
+	"\r\n\t",
+	"%s%s%s%n",
+	"{{}}${}",
+	`'"<>&;\`,
+	"../../../../",
+	"\u202e\u200b\ufeff", // bidi override, zero-width space, byte order mark
+	"\U0001f600\U0001f44d\U0001f3fd\U0001f1fa\U0001f1f8",
+	"\U0010ffff",
+	"null",
+}
+
+func (f *fuzzer) string() string {
+	switch f.rnd.Intn(8) {
+	case 0:
+		return strings.Repeat("A", f.maxLen)
+	case 1:
+		// random, but valid, UTF-8
+		runes := make([]rune, 1+f.rnd.Intn(64))
+		for i := range runes {
+			runes[i] = rune(f.rnd.Intn(0xd800))
+		}
+		return string(runes)
+	default:
+		return fuzzStrings[f.rnd.Intn(len(fuzzStrings))]
+	}
+}
+
+// fuzzBytes are invalid UTF-8 sequences, which servers that expect bytes
+// fields to contain text may fail to handle.
+var fuzzBytes = [][]byte{
+	{},
+	{0xff, 0xfe},
+	{0xc0, 0x80},             // overlong encoding of NUL
+	{0xed, 0xa0, 0x80},       // surrogate half
+	{0xf4, 0x90, 0x80, 0x80}, // beyond the maximum code point
+	{0xe2, 0x82},             // truncated sequence
+}
+
+func (f *fuzzer) bytes() []byte {
+	switch f.rnd.Intn(6) {
+	case 0:
+		b := make([]byte, f.maxLen)
+		f.rnd.Read(b)
+		return b
+	default:
+		return fuzzBytes[f.rnd.Intn(len(fuzzBytes))]
+	}
+}
+
+// wellKnown returns a fuzzed value for the given message if it is a
+// well-known type with a special JSON format.
+func (f *fuzzer) wellKnown(md *desc.MessageDescriptor) (interface{}, bool) {
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		return f.pick("0001-01-01T00:00:00Z", "9999-12-31T23:59:59.999999999Z", "1970-01-01T00:00:00Z"), true
+	case "google.protobuf.Duration":
+		// the JSON format allows larger durations, but jsonpb can only
+		// parse those that fit in a time.Duration
+		return f.pick("0s", "9223372036s", "-9223372036s", "0.000000001s"), true
+	case "google.protobuf.FieldMask":
+		return f.pick("", "a.b.c", "*"), true
+	case "google.protobuf.Struct":
+		return map[string]interface{}{"": nil, f.string(): map[string]interface{}{}}, true
+	case "google.protobuf.Value":
+		return f.pick(nil, f.string(), "NaN", []interface{}{}, map[string]interface{}{}), true
+	case "google.protobuf.ListValue":
+		return []interface{}{nil, f.string()}, true
+	case "google.protobuf.Any":
+		return map[string]interface{}{
+			"@type": "type.googleapis.com/google.protobuf.StringValue",
+			"value": f.string(),
+		}, true
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		return f.scalar(md.FindFieldByName("value")), true
+	}
+	return nil, false
+}
@@ -0,0 +1,108 @@
+package grpcui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestFuzzRequest(t *testing.T) {
+	for _, md := range loadTestMethods(t) {
+		descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+		if err != nil {
+			t.Fatalf("failed to create descriptor source: %v", err)
+		}
+		unmarshaler := jsonpb.Unmarshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSource(descSource)}
+		for seed := int64(0); seed < 20; seed++ {
+			opts := FuzzOptions{MaxLength: 100}
+			js := FuzzRequest(md, seed, opts)
+			if again := FuzzRequest(md, seed, opts); !bytes.Equal(js, again) {
+				t.Fatalf("%s (seed %d): request is not reproducible:\n%s\n%s", md.GetFullyQualifiedName(), seed, js, again)
+			}
+			msgs := []json.RawMessage{js}
+			if md.IsClientStreaming() {
+				msgs = nil
+				if err := json.Unmarshal(js, &msgs); err != nil {
+					t.Fatalf("%s (seed %d): request for client stream is not an array: %s", md.GetFullyQualifiedName(), seed, js)
+				}
+			}
+			// generated values should all be in range, so every request can
+			// be sent
+			for _, msg := range msgs {
+				if err := unmarshaler.Unmarshal(bytes.NewReader(msg), dynamic.NewMessage(md.GetInputType())); err != nil {
+					t.Errorf("%s (seed %d): request is not valid: %v\n%s", md.GetFullyQualifiedName(), seed, err, msg)
+				}
+			}
+		}
+	}
+}
+
+func TestFuzz(t *testing.T) {
+	exchange := loadTestMethods(t)[1]
+	// the server "crashes" when it gets a large request
+	svr := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		req := dynamic.NewMessage(exchange.GetInputType())
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		if b, _ := req.Marshal(); len(b) > 1000 {
+			return status.Error(codes.Unknown, "panic: runtime error: index out of range")
+		}
+		return status.Error(codes.InvalidArgument, "bad request")
+	}))
+	l := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = svr.Serve(l)
+	}()
+	defer svr.Stop()
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer cc.Close()
+
+	opts := FuzzOptions{Seed: 100, Count: 50, MaxLength: 2000, Metadata: []string{"x-test: fuzz"}}
+	report, err := Fuzz(context.Background(), cc, exchange, opts)
+	if err != nil {
+		t.Fatalf("failed to fuzz: %v", err)
+	}
+	if report.Method != "test.KitchenSink.Exchange" {
+		t.Errorf("unexpected method in report: %s", report.Method)
+	}
+	if report.Sent+report.Skipped != opts.Count {
+		t.Errorf("expecting %d requests; got %d sent and %d skipped", opts.Count, report.Sent, report.Skipped)
+	}
+	if report.Codes["Unknown"]+report.Codes["InvalidArgument"] != report.Sent {
+		t.Errorf("unexpected status codes: %v", report.Codes)
+	}
+	if len(report.Findings) == 0 || len(report.Findings) != report.Codes["Unknown"] {
+		t.Fatalf("expecting a finding for each Unknown status; got %d findings and codes %v", len(report.Findings), report.Codes)
+	}
+	for _, finding := range report.Findings {
+		if finding.Code != "Unknown" || !strings.Contains(finding.Message, "panic") {
+			t.Errorf("unexpected finding: %s: %s", finding.Code, finding.Message)
+		}
+		if finding.Seed < opts.Seed || finding.Seed >= opts.Seed+int64(opts.Count) {
+			t.Errorf("finding has seed %d outside of the range used", finding.Seed)
+		}
+		if js := FuzzRequest(exchange, finding.Seed, opts); !bytes.Equal(js, finding.Request) {
+			t.Errorf("finding with seed %d cannot be reproduced", finding.Seed)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package grpcui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// RPCImportGRPCurlHandler returns an HTTP handler that parses a grpcurl
+// command line into a request that can be loaded into the web form. This is
+// the reverse of the grpcurl command that the web form shows for the current
+// request.
+//
+// The handler accepts POST requests with a JSON body that has a single
+// property named "command", whose value is the command line. The command line
+// may span multiple lines (using backslash line continuations) and may include
+// request data provided via stdin (using "-d @") in the form of a heredoc or
+// piped from an echo command. The response is a JSON object that describes the
+// service and method, the metadata (from -H and -rpc-header flags), the
+// timeout (from the -max-time flag), and the request data (from the -d flag).
+//
+// If the command does not invoke one of the given methods, the response status
+// is 422 (Unprocessable Entity). Other problems with the command line result
+// in a 400 (Bad Request) status.
+func RPCImportGRPCurlHandler(methods []*desc.MethodDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "Request must be JSON", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var input struct {
+			Command string `json:"command"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := parseGRPCurlCommand(input.Command, methods)
+		if err != nil {
+			var unknown errUnknownMethod
+			if errors.As(err, &unknown) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			http.Error(w, "Invalid grpcurl command: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+	})
+}
+
+type errUnknownMethod struct {
+	method string
+}
+
+func (e errUnknownMethod) Error() string {
+	return fmt.Sprintf("method %q is not exposed by this server", e.method)
+}
+
+// importedRequest has the same shape as items in the web form's history,
+// so the JS code can load it the same way.
+type importedRequest struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	Request struct {
+		TimeoutSeconds float64       `json:"timeout_seconds,omitempty"`
+		Metadata       []rpcMetadata `json:"metadata"`
+		// This is a single message, unless the method is client-streaming,
+		// in which case it is an array of messages.
+		Data json.RawMessage `json:"data"`
+	} `json:"request"`
+}
+
+// grpcurlBoolFlags are the grpcurl flags that do not accept a separate value
+// argument. All other flags do.
+var grpcurlBoolFlags = map[string]bool{
+	"help":                 true,
+	"version":              true,
+	"plaintext":            true,
+	"insecure":             true,
+	"alts":                 true,
+	"expand-headers":       true,
+	"allow-unknown-fields": true,
+	"format-error":         true,
+	"emit-defaults":        true,
+	"msg-template":         true,
+	"v":                    true,
+	"vv":                   true,
+	"unix":                 true,
+	"use-reflection":       true,
+}
+
+var grpcurlValueFlags = map[string]bool{
+	"cacert":                      true,
+	"cert":                        true,
+	"key":                         true,
+	"alts-handshaker-service":     true,
+	"alts-target-service-account": true,
+	"authority":                   true,
+	"user-agent":                  true,
+	"d":                           true,
+	"format":                      true,
+	"connect-timeout":             true,
+	"keepalive-time":              true,
+	"max-time":                    true,
+	"max-msg-sz":                  true,
+	"protoset-out":                true,
+	"proto-out-dir":               true,
+	"servername":                  true,
+	"H":                           true,
+	"rpc-header":                  true,
+	"reflect-header":              true,
+	"protoset":                    true,
+	"proto":                       true,
+	"import-path":                 true,
+}
+
+func parseGRPCurlCommand(cmdLine string, methods []*desc.MethodDescriptor) (*importedRequest, error) {
+	args, stdin, err := grpcurlArgs(cmdLine)
+	if err != nil {
+		return nil, err
+	}
+
+	var result importedRequest
+	var data *string
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+		name := strings.TrimPrefix(arg[1:], "-")
+		var val string
+		hasVal := false
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name, val, hasVal = name[:eq], name[eq+1:], true
+		}
+		switch {
+		case grpcurlBoolFlags[name]:
+			continue
+		case grpcurlValueFlags[name]:
+			if !hasVal {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag -%s requires a value", name)
+				}
+				i++
+				val = args[i]
+			}
+		default:
+			return nil, fmt.Errorf("unknown flag -%s", name)
+		}
+
+		switch name {
+		case "d":
+			data = &val
+		case "H", "rpc-header":
+			parts := strings.SplitN(val, ":", 2)
+			md := rpcMetadata{Name: strings.TrimSpace(parts[0])}
+			if len(parts) > 1 {
+				md.Value = strings.TrimLeft(parts[1], " \t")
+			}
+			result.Request.Metadata = append(result.Request.Metadata, md)
+		case "max-time":
+			secs, err := strconv.ParseFloat(val, 64)
+			if err != nil || secs < 0 {
+				return nil, fmt.Errorf("invalid value for -max-time: %q", val)
+			}
+			result.Request.TimeoutSeconds = secs
+		case "format":
+			if val != "json" {
+				return nil, fmt.Errorf("only JSON request data is supported, not -format %s", val)
+			}
+		}
+	}
+
+	switch {
+	case len(positional) == 0:
+		return nil, errors.New("command does not indicate a method to invoke")
+	case positional[0] == "list" || positional[0] == "describe" ||
+		(len(positional) > 1 && (positional[1] == "list" || positional[1] == "describe")):
+		return nil, errors.New("command does not invoke an RPC")
+	case len(positional) > 2:
+		return nil, fmt.Errorf("too many arguments: %s", strings.Join(positional, " "))
+	}
+
+	// like grpcurl, accept either "service/method" or "service.method"
+	symbol := strings.TrimPrefix(positional[len(positional)-1], "/")
+	sep := strings.LastIndexByte(symbol, '/')
+	if sep < 0 {
+		sep = strings.LastIndexByte(symbol, '.')
+	}
+	if sep < 0 {
+		return nil, fmt.Errorf("%q is not a method name", symbol)
+	}
+	fullMethod := symbol[:sep] + "." + symbol[sep+1:]
+	var md *desc.MethodDescriptor
+	for _, m := range methods {
+		if m.GetFullyQualifiedName() == fullMethod {
+			md = m
+			break
+		}
+	}
+	if md == nil {
+		return nil, errUnknownMethod{method: fullMethod}
+	}
+	result.Service = md.GetService().GetFullyQualifiedName()
+	result.Method = md.GetName()
+
+	var msgs []json.RawMessage
+	if data != nil {
+		src := *data
+		if src == "@" {
+			if stdin == nil {
+				return nil, errors.New("request data is read from stdin (-d @), but the command has no input")
+			}
+			src = *stdin
+		}
+		dec := json.NewDecoder(strings.NewReader(src))
+		for {
+			var msg json.RawMessage
+			if err := dec.Decode(&msg); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("request data is not valid JSON: %w", err)
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if md.IsClientStreaming() {
+		if msgs == nil {
+			msgs = []json.RawMessage{}
+		}
+		result.Request.Data, _ = json.Marshal(msgs)
+	} else {
+		switch len(msgs) {
+		case 0:
+			// grpcurl sends an empty message when no data is given
+			result.Request.Data = json.RawMessage("{}")
+		case 1:
+			result.Request.Data = msgs[0]
+		default:
+			return nil, fmt.Errorf("method %s does not accept a stream of %d request messages", fullMethod, len(msgs))
+		}
+	}
+	return &result, nil
+}
+
+// grpcurlArgs finds the grpcurl invocation in the given shell command line and
+// returns its arguments. If the command's stdin is provided inline, via a
+// heredoc or echo pipe, the contents of stdin are also returned.
+func grpcurlArgs(cmdLine string) ([]string, *string, error) {
+	cmds, err := splitShellCommands(cmdLine)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, cmd := range cmds {
+		if len(cmd.words) > 0 && cmd.words[0] == "$" {
+			// strip shell prompt
+			cmd.words = cmd.words[1:]
+		}
+		if len(cmd.words) == 0 || path.Base(cmd.words[0]) != "grpcurl" {
+			continue
+		}
+		stdin := cmd.heredoc
+		if stdin == nil && i > 0 {
+			prev := cmds[i-1]
+			switch {
+			case prev.heredoc != nil:
+				// e.g. "cat <<EOM | grpcurl ..."
+				stdin = prev.heredoc
+			case len(prev.words) > 0 && prev.words[0] == "echo":
+				s := strings.Join(prev.words[1:], " ")
+				stdin = &s
+			}
+		}
+		return cmd.words[1:], stdin, nil
+	}
+	return nil, nil, errors.New("no grpcurl command found")
+}
+
+type shellCommand struct {
+	words   []string
+	heredoc *string
+}
+
+// splitShellCommands tokenizes the given command line using shell quoting
+// rules and splits it into a pipeline of commands. This supports quoted
+// strings, backslash escapes and line continuations, pipes, and heredocs.
+// Any other shell syntax is treated as literal text.
+func splitShellCommands(s string) ([]*shellCommand, error) {
+	cmd := &shellCommand{}
+	cmds := []*shellCommand{cmd}
+	var word bytes.Buffer
+	inWord := false
+	var heredocDelim string
+	var heredocCmd *shellCommand
+
+	endWord := func() {
+		if inWord {
+			cmd.words = append(cmd.words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] != '\n' {
+					word.WriteByte(s[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single-quoted string")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\\\"$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated double-quoted string")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		case c == '|':
+			endWord()
+			cmd = &shellCommand{}
+			cmds = append(cmds, cmd)
+		case c == '<' && strings.HasPrefix(s[i:], "<<"):
+			endWord()
+			i += 2
+			if i < len(s) && s[i] == '-' {
+				i++
+			}
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			start := i
+			for i < len(s) && strings.IndexByte(" \t\r\n|", s[i]) < 0 {
+				i++
+			}
+			heredocDelim = strings.Trim(s[start:i], `'"`)
+			if heredocDelim == "" {
+				return nil, errors.New("heredoc is missing delimiter")
+			}
+			heredocCmd = cmd
+			i--
+		case c == '\n':
+			endWord()
+			if heredocDelim == "" {
+				// the command line ends here
+				return cmds, nil
+			}
+			lines := strings.Split(s[i+1:], "\n")
+			for n, line := range lines {
+				if strings.TrimSpace(line) == heredocDelim {
+					body := strings.Join(lines[:n], "\n")
+					heredocCmd.heredoc = &body
+					return cmds, nil
+				}
+			}
+			return nil, fmt.Errorf("heredoc is missing terminating %q", heredocDelim)
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endWord()
+	if heredocDelim != "" {
+		return nil, fmt.Errorf("heredoc is missing terminating %q", heredocDelim)
+	}
+	return cmds, nil
+}
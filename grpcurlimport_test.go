@@ -0,0 +1,143 @@
+package grpcui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGRPCurlCommand(t *testing.T) {
+	methods := loadTestMethods(t)
+
+	testCases := []struct {
+		name     string
+		cmd      string
+		method   string
+		metadata []rpcMetadata
+		timeout  float64
+		data     string
+	}{
+		{
+			name:   "simple",
+			cmd:    `grpcurl -plaintext localhost:8080 test.KitchenSink/Exchange`,
+			method: "Exchange",
+			data:   `{}`,
+		},
+		{
+			name: "flags and quoting",
+			cmd: `$ /usr/local/bin/grpcurl -plaintext -H 'Authorization: Bearer abc' --rpc-header="x-id:  123" \
+				-max-time=2.5 -d '{"s": "it'\''s"}' localhost:8080 test.KitchenSink.Exchange`,
+			method: "Exchange",
+			metadata: []rpcMetadata{
+				{Name: "Authorization", Value: "Bearer abc"},
+				{Name: "x-id", Value: "123"},
+			},
+			timeout: 2.5,
+			data:    `{"s": "it's"}`,
+		},
+		{
+			name: "heredoc stream",
+			cmd: `grpcurl -d @ localhost:8080 test.KitchenSink/UploadMany <<EOM
+{"s": "one"}
+{"s": "two"}
+EOM
+echo done`,
+			method: "UploadMany",
+			data:   `[{"s":"one"},{"s":"two"}]`,
+		},
+		{
+			name:   "piped from echo",
+			cmd:    `echo '{"s": "piped"}' | grpcurl -d @ localhost:8080 test.KitchenSink/Exchange`,
+			method: "Exchange",
+			data:   `{"s": "piped"}`,
+		},
+		{
+			name:   "empty stream",
+			cmd:    `grpcurl localhost:8080 test.KitchenSink/UploadMany`,
+			method: "UploadMany",
+			data:   `[]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := parseGRPCurlCommand(tc.cmd, methods)
+			if err != nil {
+				t.Fatalf("failed to parse command: %v", err)
+			}
+			if req.Service != "test.KitchenSink" || req.Method != tc.method {
+				t.Errorf("wrong method: %s %s", req.Service, req.Method)
+			}
+			if !reflect.DeepEqual(req.Request.Metadata, tc.metadata) {
+				t.Errorf("wrong metadata: %v", req.Request.Metadata)
+			}
+			if req.Request.TimeoutSeconds != tc.timeout {
+				t.Errorf("wrong timeout: %v", req.Request.TimeoutSeconds)
+			}
+			if string(req.Request.Data) != tc.data {
+				t.Errorf("wrong data: %s", req.Request.Data)
+			}
+		})
+	}
+}
+
+func TestParseGRPCurlCommand_Errors(t *testing.T) {
+	methods := loadTestMethods(t)
+
+	testCases := []struct {
+		cmd, err string
+	}{
+		{`curl http://localhost:8080`, "no grpcurl command found"},
+		{`grpcurl -plaintext localhost:8080 list`, "does not invoke an RPC"},
+		{`grpcurl -bogus localhost:8080 test.KitchenSink/Exchange`, "unknown flag -bogus"},
+		{`grpcurl -d '{"s": ' localhost:8080 test.KitchenSink/Exchange`, "not valid JSON"},
+		{`grpcurl -d '{}{}' localhost:8080 test.KitchenSink/Exchange`, "does not accept a stream"},
+		{`grpcurl -d @ localhost:8080 test.KitchenSink/Exchange`, "has no input"},
+		{`grpcurl -d "{}' localhost:8080 test.KitchenSink/Exchange`, "unterminated double-quoted string"},
+		{`grpcurl localhost:8080 test.KitchenSink/Bogus`, `method "test.KitchenSink.Bogus" is not exposed`},
+	}
+	for _, tc := range testCases {
+		_, err := parseGRPCurlCommand(tc.cmd, methods)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.cmd, tc.err, err)
+		}
+	}
+}
+
+func TestRPCImportGRPCurlHandler(t *testing.T) {
+	h := RPCImportGRPCurlHandler(loadTestMethods(t))
+
+	testCases := []struct {
+		cmd  string
+		code int
+	}{
+		{`grpcurl localhost:8080 test.KitchenSink/Exchange`, http.StatusOK},
+		{`grpcurl localhost:8080 test.KitchenSink/Bogus`, http.StatusUnprocessableEntity},
+		{`grpcurl -max-time forever localhost:8080 test.KitchenSink/Exchange`, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		body, _ := json.Marshal(map[string]string{"command": tc.cmd})
+		req := httptest.NewRequest("POST", "/import-grpcurl", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.cmd, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	for contentType, code := range map[string]int{
+		"application/json; charset=utf-8": http.StatusOK,
+		"text/plain":                      http.StatusUnsupportedMediaType,
+	} {
+		req := httptest.NewRequest("POST", "/import-grpcurl", strings.NewReader(`{"command": "grpcurl localhost:8080 test.KitchenSink/Exchange"}`))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Errorf("content type %q: expected status %d, got %d", contentType, code, rec.Code)
+		}
+	}
+}
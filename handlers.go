@@ -18,6 +18,7 @@ import (
 	"github.com/golang/protobuf/proto"  //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
+	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -66,12 +67,37 @@ type InvokeOptions struct {
 	// of a bool "verbose" flag, so that additional logs may be added in the
 	// future and the caller control how detailed those logs will be.
 	Verbosity int
+	// If non-nil, invoking a method for which this function returns true must
+	// be confirmed. The handler rejects requests to invoke such a method with
+	// "428 Precondition Required" and a JSON body with a single-use token
+	// that is valid for two minutes. The client confirms by sending the same
+	// request again with that token as its "confirm_token". MayHaveSideEffects
+	// is a good choice for targets where mistakes are costly.
+	ConfirmMethod func(*desc.MethodDescriptor) bool
+	// If non-nil, sensitive fields and metadata in responses are masked
+	// before they are returned to the client.
+	Redactor *Redactor
+	// If non-nil, a token from this source is sent as "authorization"
+	// metadata with every RPC, overriding any authorization metadata in the
+	// invocation request (but not ExtraMetadata or PreserveHeaders). The
+	// source should cache tokens, like those returned by
+	// oauth2.ReuseTokenSource and NewCommandTokenSource.
+	TokenSource oauth2.TokenSource
+	// The names of metadata that the channel adds to every RPC on its own,
+	// such as via per-RPC credentials. Values for these names in the
+	// invocation request are not sent, so they don't end up alongside the
+	// ones the channel adds.
+	InjectedMetadata []string
 }
 
 // RPCInvokeHandlerWithOptions is the same as RPCInvokeHandler except that it
 // accepts an additional argument, options. This can be used to add extra
 // request metadata to all RPCs invoked.
 func RPCInvokeHandlerWithOptions(ch grpc.ClientConnInterface, descs []*desc.MethodDescriptor, options InvokeOptions) http.Handler {
+	var conf *confirmer
+	if options.ConfirmMethod != nil {
+		conf = newConfirmer()
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			w.Header().Set("Allow", "POST")
@@ -96,8 +122,23 @@ func RPCInvokeHandlerWithOptions(ch grpc.ClientConnInterface, descs []*desc.Meth
 					http.Error(w, "Failed to create descriptor source: "+err.Error(), http.StatusInternalServerError)
 					return
 				}
-				results, err := invokeRPC(r.Context(), method, ch, descSource, r.Header, r.Body, &options)
+				var methodConf *confirmer
+				if conf != nil && options.ConfirmMethod(md) {
+					methodConf = conf
+				}
+				results, err := invokeRPC(r.Context(), method, ch, descSource, r.Header, r.Body, &options, methodConf)
 				if err != nil {
+					if e, ok := err.(errConfirmationRequired); ok {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusPreconditionRequired)
+						enc := json.NewEncoder(w)
+						enc.SetIndent("", "  ")
+						enc.Encode(confirmationResponse{
+							ConfirmToken: e.token,
+							Message:      fmt.Sprintf("%s may have side effects. Invoke it anyway?", method),
+						})
+						return
+					}
 					if _, ok := err.(errReadFail); ok {
 						http.Error(w, "Failed to read request", 499)
 						return
@@ -192,6 +233,7 @@ type fieldDef struct {
 	IsArray     bool        `json:"isArray"`
 	IsMap       bool        `json:"isMap"`
 	IsRequired  bool        `json:"isRequired"`
+	IsRedacted  bool        `json:"isRedacted"`
 	DefaultVal  interface{} `json:"defaultVal"`
 	Description string      `json:"description"`
 }
@@ -308,6 +350,7 @@ func (s *schema) processField(fd *desc.FieldDescriptor) fieldDef {
 		IsArray:    fd.IsRepeated() && !fd.IsMap(),
 		IsMap:      fd.IsMap(),
 		IsRequired: fd.IsRequired(),
+		IsRedacted: fd.GetFieldOptions().GetDebugRedact(),
 		DefaultVal: fd.GetDefaultValue(),
 	}
 
@@ -414,7 +457,7 @@ func (e errReadFail) Error() string {
 	return e.err.Error()
 }
 
-func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterface, descSource grpcurl.DescriptorSource, reqHdrs http.Header, body io.Reader, options *InvokeOptions) (*rpcResult, error) {
+func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterface, descSource grpcurl.DescriptorSource, reqHdrs http.Header, body io.Reader, options *InvokeOptions, conf *confirmer) (*rpcResult, error) {
 	js, err := io.ReadAll(body)
 	if err != nil {
 		return nil, errReadFail{err: err}
@@ -424,6 +467,11 @@ func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterfa
 	if err := json.Unmarshal(js, &input); err != nil {
 		return nil, errBadInput{err: err}
 	}
+	if conf != nil {
+		if err := conf.check(methodName, &input); err != nil {
+			return nil, err
+		}
+	}
 
 	reqStats := rpcRequestStats{
 		Total: len(input.Data),
@@ -445,6 +493,16 @@ func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterfa
 	for _, hdr := range input.Metadata {
 		webFormHdrs.Append(hdr.Name, hdr.Value)
 	}
+	for _, name := range options.InjectedMetadata {
+		delete(webFormHdrs, strings.ToLower(name))
+	}
+	if options.TokenSource != nil {
+		tok, err := options.TokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		webFormHdrs.Set("authorization", tok.Type()+" "+tok.AccessToken)
+	}
 	invokeHdrs := options.computeHeaders(reqHdrs, webFormHdrs)
 
 	if input.TimeoutSeconds > 0 {
@@ -461,6 +519,7 @@ func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterfa
 	result := rpcResult{
 		descSource:   descSource,
 		emitDefaults: options.EmitDefaults,
+		redactor:     options.Redactor,
 		Requests:     &reqStats,
 	}
 	if err := grpcurl.InvokeRPC(ctx, descSource, ch, methodName, invokeHdrs, &result, requestFunc); err != nil {
@@ -522,6 +581,7 @@ type rpcInput struct {
 	TimeoutSeconds float32           `json:"timeout_seconds"`
 	Metadata       []rpcMetadata     `json:"metadata"`
 	Data           []json.RawMessage `json:"data"`
+	ConfirmToken   string            `json:"confirm_token,omitempty"`
 }
 
 type rpcResponseElement struct {
@@ -544,6 +604,8 @@ type rpcError struct {
 type rpcResult struct {
 	descSource   grpcurl.DescriptorSource
 	emitDefaults bool
+	redactor     *Redactor
+	method       *desc.MethodDescriptor
 	Headers      []rpcMetadata        `json:"headers"`
 	Error        *rpcError            `json:"error"`
 	Responses    []rpcResponseElement `json:"responses"`
@@ -551,24 +613,26 @@ type rpcResult struct {
 	Trailers     []rpcMetadata        `json:"trailers"`
 }
 
-func (*rpcResult) OnResolveMethod(*desc.MethodDescriptor) {}
+func (r *rpcResult) OnResolveMethod(md *desc.MethodDescriptor) {
+	r.method = md
+}
 
 func (*rpcResult) OnSendHeaders(metadata.MD) {}
 
 func (r *rpcResult) OnReceiveHeaders(md metadata.MD) {
-	r.Headers = responseMetadata(md)
+	r.Headers = responseMetadata(md, r.redactor)
 }
 
 func (r *rpcResult) OnReceiveResponse(m proto.Message) {
-	r.Responses = append(r.Responses, responseToJSON(r.descSource, m, r.emitDefaults))
+	r.Responses = append(r.Responses, responseToJSON(r.descSource, m, r.emitDefaults, r.redactor, r.method.GetOutputType()))
 }
 
 func (r *rpcResult) OnReceiveTrailers(stat *status.Status, md metadata.MD) {
-	r.Trailers = responseMetadata(md)
-	r.Error = toRpcError(r.descSource, stat, r.emitDefaults)
+	r.Trailers = responseMetadata(md, r.redactor)
+	r.Error = toRpcError(r.descSource, stat, r.emitDefaults, r.redactor)
 }
 
-func responseMetadata(md metadata.MD) []rpcMetadata {
+func responseMetadata(md metadata.MD, redactor *Redactor) []rpcMetadata {
 	keys := make([]string, 0, len(md))
 	for k := range md {
 		keys = append(keys, k)
@@ -584,10 +648,13 @@ func responseMetadata(md metadata.MD) []rpcMetadata {
 			ret = append(ret, rpcMetadata{Name: k, Value: v})
 		}
 	}
+	if redactor != nil {
+		ret = redactor.redactMetadata(ret)
+	}
 	return ret
 }
 
-func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status, emitDefaults bool) *rpcError {
+func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status, emitDefaults bool, redactor *Redactor) *rpcError {
 	if stat.Code() == codes.OK {
 		return nil
 	}
@@ -595,7 +662,8 @@ func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status, emitDe
 	details := stat.Proto().Details
 	msgs := make([]rpcResponseElement, len(details))
 	for i, d := range details {
-		msgs[i] = responseToJSON(descSource, d, emitDefaults)
+		// details are packed into Anys, so their types come from the JSON
+		msgs[i] = responseToJSON(descSource, d, emitDefaults, redactor, nil)
 	}
 	return &rpcError{
 		Code:    uint32(stat.Code()),
@@ -605,12 +673,19 @@ func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status, emitDe
 	}
 }
 
-func responseToJSON(descSource grpcurl.DescriptorSource, msg proto.Message, emitDefaults bool) rpcResponseElement {
+// responseToJSON converts the given message, of the given type, to JSON. If
+// redactor is non-nil, sensitive fields are masked. If md is nil, the message
+// must be a google.protobuf.Any.
+func responseToJSON(descSource grpcurl.DescriptorSource, msg proto.Message, emitDefaults bool, redactor *Redactor, md *desc.MessageDescriptor) rpcResponseElement {
 	anyResolver := grpcurl.AnyResolverFromDescriptorSourceWithFallback(descSource)
 	jsm := jsonpb.Marshaler{EmitDefaults: emitDefaults, OrigName: true, Indent: "  ", AnyResolver: anyResolver}
 	var b bytes.Buffer
 	if err := jsm.Marshal(&b, msg); err == nil {
-		return rpcResponseElement{Data: json.RawMessage(b.Bytes())}
+		js := b.Bytes()
+		if redactor != nil {
+			js = redactor.redactJSON(md, js, sourceResolver(descSource))
+		}
+		return rpcResponseElement{Data: json.RawMessage(js)}
 	} else {
 		b, err := json.Marshal(err.Error())
 		if err != nil {
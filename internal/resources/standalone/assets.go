@@ -7,6 +7,8 @@ import (
 
 const IndexTemplateName = "index-template.html"
 
+const LoginTemplateName = "login-template.html"
+
 //go:embed *.html *.css *.png *.js
 var res embed.FS
 
@@ -14,6 +16,10 @@ func IndexTemplate() []byte {
 	return MustAsset(IndexTemplateName)
 }
 
+func LoginTemplate() []byte {
+	return MustAsset(LoginTemplateName)
+}
+
 func AssetNames() []string {
 	list, err := res.ReadDir(".")
 	if err != nil {
@@ -1,7 +1,13 @@
-window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadataURI, debug, headers) {
+window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadataURI, debug, headers, features) {
+
+    // features describes optional server handlers; keys are absent when
+    // the corresponding handler is not available
+    features = features || {};
 
     var descriptionsShown = false;
     var requestForm = $("#grpc-request-form");
+    // the response most recently shown in the response tab, for sharing
+    var lastResponse = null;
 
     function formServiceSelected(callback, preferredMethod) {
         var svcName = $("#grpc-service").val();
@@ -13,6 +19,7 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
         }
         $("#grpc-service-description").text(svcDesc);
         $("#grpc-service-description-end").text(svcDescEnd);
+        updateDescriptorDownloads(svcName);
 
         var methodList = $("#grpc-method");
         methodList.empty();
@@ -31,6 +38,18 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
         formMethodSelected(callback);
     }
 
+    function updateDescriptorDownloads(svcName) {
+        if (!features.descriptorsURI) {
+            return;
+        }
+        const uri = features.descriptorsURI;
+        const svcParam = "&service=" + encodeURIComponent(svcName);
+        $("#grpc-download-protoset").attr("href", uri + "?format=protoset" + svcParam);
+        $("#grpc-download-protos").attr("href", uri + "?format=proto" + svcParam);
+        $("#grpc-download-all-protoset").attr("href", uri + "?format=protoset");
+        $("#grpc-descriptor-downloads").show();
+    }
+
     function formMethodSelected(callback) {
         var service = $("#grpc-service").val();
         var method = $("#grpc-method").val();
@@ -66,6 +85,7 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             t.tabs("option", "active", 0);
         }
         t.tabs("disable", 2);
+        lastResponse = null;
         $(".grpc-invoke").prop("disabled", !enabled);
     }
 
@@ -2117,8 +2137,62 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
         }
     }
 
+    // Masks sensitive values in the given request data, for the grpcurl
+    // command. A field is sensitive if it has the debug_redact option or if
+    // the server was configured to redact it, by its fully-qualified name or
+    // by its name alone.
+    function redactRequestData(value, typeName) {
+        const schema = requestForm.data("schema");
+        const fields = schema ? schema.messageTypes[typeName] : undefined;
+        if (!fields || value === null || typeof value !== "object") {
+            return value;
+        }
+        if (value instanceof Array) {
+            return value.map(v => redactRequestData(v, typeName));
+        }
+        const redactFields = features.redact.fields || [];
+        const result = {...value};
+        const visit = function(fld) {
+            if (fld.oneOfFields && fld.oneOfFields.length > 0) {
+                fld.oneOfFields.forEach(visit);
+                return;
+            }
+            const key = result.hasOwnProperty(fld.name) ? fld.name : fld.protoName;
+            if (!result.hasOwnProperty(key)) {
+                return;
+            }
+            if (fld.isRedacted || redactFields.includes(fld.protoName) ||
+                    redactFields.includes(typeName + "." + fld.protoName)) {
+                result[key] = "REDACTED";
+            } else if (fld.isMap) {
+                const valueFld = (schema.messageTypes[fld.type] || []).find(f => f.protoName === "value");
+                if (valueFld && valueFld.isMessage) {
+                    const entries = {};
+                    for (const k in result[key]) {
+                        entries[k] = redactRequestData(result[key][k], valueFld.type);
+                    }
+                    result[key] = entries;
+                }
+            } else if (fld.isMessage) {
+                result[key] = redactRequestData(result[key], fld.type);
+            }
+        };
+        fields.forEach(visit);
+        return result;
+    }
+
     let gRPCurlTextArea = $("#grpc-curl-text");
     function updateCurlCommand(requestDataJson) {
+        if (features.redact) {
+            const schema = requestForm.data("schema");
+            try {
+                const data = redactRequestData(JSON.parse(requestDataJson), schema.requestType);
+                requestDataJson = JSON.stringify(data, null, 2);
+            } catch (e) {
+                // leave invalid JSON, which the user is still editing, as is
+            }
+        }
+
         const grpcOpts = window.gRPCurlOptions ? ' ' + window.gRPCurlOptions : '';
 
         let metadataStr = "";
@@ -2134,24 +2208,105 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             }
             const name = $(cells[0]).val();
             const val = $(cells[1]).val();
-            if (name !== "") {
-                metadataStr += ` -H "${name}: ${val}"`;
+            if (name !== "" && !(features.injectedMetadata || []).includes(name.toLowerCase())) {
+                const redacted = features.redact && (features.redact.metadata || []).includes(name.toLowerCase());
+                metadataStr += ` -H "${name}: ${redacted ? "REDACTED" : val}"`;
             }
         }
 
+        for (const name of features.injectedMetadata || []) {
+            metadataStr += ` -H "${name}: ${injectedMetadataVar(name)}"`;
+        }
+
         gRPCurlTextArea.text(`grpcurl${grpcOpts}${metadataStr} -d '${requestDataJson}' ${window.target} ${service}.${method}`);
     }
 
+    // Client code snippets are generated by the server, so we wait until the
+    // user pauses editing before asking for a new one.
+    let snippetTimer = null;
+    let snippetLang = $("#grpc-snippet-lang");
+    let snippetText = $("#grpc-snippet-text");
+    if (features.snippetURI) {
+        $("#grpc-snippet").show();
+        snippetLang.change(function() {
+            updateSnippet();
+        });
+    }
+    // Returns the timeout in the request form, in seconds, or undefined if
+    // no timeout is set.
+    function formTimeout() {
+        const timeoutStr = $("#grpc-request-timeout input").val();
+        const timeout = Number(timeoutStr);
+        return (timeoutStr === "" || Number.isNaN(timeout)) ? undefined : timeout;
+    }
+
+    // Returns the metadata in the request form, as an array of name-value
+    // pairs. Rows without a name are skipped.
+    function formMetadata() {
+        const metadata = [];
+        const rows = $("#grpc-request-metadata-form tr");
+        for (let i = 0; i < rows.length; i++) {
+            const cells = $("input", rows[i]);
+            if (cells.length === 0) {
+                continue;
+            }
+            const name = $(cells[0]).val();
+            if (name !== "") {
+                metadata.push({name: name, value: $(cells[1]).val()});
+            }
+        }
+        return metadata;
+    }
+
+    function updateSnippet() {
+        if (!features.snippetURI) {
+            return;
+        }
+        if (snippetTimer !== null) {
+            clearTimeout(snippetTimer);
+        }
+        snippetTimer = setTimeout(function() {
+            snippetTimer = null;
+            const service = $("#grpc-service").val();
+            const method = $("#grpc-method").val();
+            let data;
+            try {
+                data = JSON.parse(jsonRawTextArea.val());
+            } catch (e) {
+                // user is still typing; keep showing the last snippet
+                return;
+            }
+            if (!(data instanceof Array)) {
+                data = [data];
+            }
+            const timeout = formTimeout();
+            const metadata = formMetadata();
+            $.ajax({
+                type: "POST",
+                url: features.snippetURI + "/" + service + "." + method + "?lang=" + encodeURIComponent(snippetLang.val()),
+                contentType: "application/json",
+                data: JSON.stringify({timeout_seconds: timeout, metadata: metadata, data: data}),
+                dataType: "text",
+            }).done(function(code) {
+                snippetText.text(code);
+            }).fail(function(failureData) {
+                snippetText.text("Failed to generate client code: " + failureData.responseText);
+            });
+        }, 300);
+    }
+
     var jsonRawTextArea = $("#grpc-request-raw-text");
     function updateJSONRequest(req) {
         let requestDataJson = JSON.stringify(req, null, 2);
         jsonRawTextArea.val(requestDataJson);
         updateCurlCommand(requestDataJson);
+        updateSnippet();
     }
 
     function validateJSON() {
         let requestDataJson = jsonRawTextArea.val();
         updateCurlCommand(requestDataJson);
+        updateSnippet();
         var reqObj = JSON.parse(requestDataJson);
         rebuildRequestForm(reqObj, false);
     }
@@ -2167,6 +2322,24 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
     var MIN_INT32 = -2147483648;
     var MAX_UINT32 = 4294967295;
 
+    // Adds a row to the request metadata table for metadata that the server
+    // injects into every RPC. It shows the name only, since the value is never
+    // sent to the browser, and has no inputs, so it is not included in the
+    // metadata of requests.
+    function addInjectedMetadataRow(name) {
+        const tr = $('<tr class="injectedMetadataRow">');
+        $("#grpc-request-metadata-form tr:last-of-type").before(tr);
+        tr.append('<td></td>',
+            $('<td class="name">').text(name),
+            $('<td class="injected">').text("(injected)"));
+    }
+
+    // Returns the shell variable that the grpcurl command uses for the value
+    // of the given injected metadata.
+    function injectedMetadataVar(name) {
+        return "${" + name.toUpperCase().replace(/[^A-Z0-9_]/g, "_") + "}";
+    }
+
     // Adds a row to the request metadata table.
     function addMetadataRow(name = '', value = '') {
         tr = $('<tr class="metadataRow">');
@@ -2253,33 +2426,47 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             startTime: new Date().toISOString(),
         }
 
-        const startTime = window.performance.now();
-
-        $.ajax(
-            {
-                type: "POST",
-                url: invokeURI + "/" + service + "." + method,
-                contentType: "application/json",
-                data: JSON.stringify({timeout_seconds: timeout, metadata: metadata, data: data}),
-            })
-            .done(function(responseData) {
-                var durationMs = window.performance.now() - startTime;
-                renderResponse(historyItem, durationMs, responseData);
-            })
-            .fail(function(failureData, status) {
-                addHistory({
-                    ...historyItem,
-                    durationMS: window.performance.now() - startTime,
-                    failureStatus: status,
+        let startTime;
+        const send = function(confirmToken) {
+            startTime = window.performance.now();
+            $.ajax(
+                {
+                    type: "POST",
+                    url: invokeURI + "/" + service + "." + method,
+                    contentType: "application/json",
+                    data: JSON.stringify({timeout_seconds: timeout, metadata: metadata, data: data, confirm_token: confirmToken}),
+                })
+                .done(function(responseData) {
+                    var durationMs = window.performance.now() - startTime;
+                    $(".grpc-invoke").prop("disabled", false);
+                    renderResponse(historyItem, durationMs, responseData);
+                })
+                .fail(function(failureData, status) {
+                    // methods with side effects on a protected target must be
+                    // confirmed, by sending the request again with the token
+                    // the server issued for it
+                    const confirmation = failureData.status === 428 ? failureData.responseJSON : undefined;
+                    if (confirmation && confirmation.confirm_token) {
+                        if (confirm(confirmation.message)) {
+                            send(confirmation.confirm_token);
+                        } else {
+                            $(".grpc-invoke").prop("disabled", false);
+                        }
+                        return;
+                    }
+                    addHistory({
+                        ...historyItem,
+                        durationMS: window.performance.now() - startTime,
+                        failureStatus: status,
+                    });
+                    alert("Unexpected error: " + status);
+                    if (debug) {
+                        console.trace(failureData.responseText);
+                    }
+                    $(".grpc-invoke").prop("disabled", false);
                 });
-                alert("Unexpected error: " + status);
-                if (debug) {
-                    console.trace(failureData.responseText);
-                }
-            })
-            .always(function() {
-                $(".grpc-invoke").prop("disabled", false);
-            });
+        };
+        send(undefined);
     }
 
     function renderResponse(historyItem, durationMs, responseData) {
@@ -2335,11 +2522,15 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             $("#grpc-response-error").hide();
         }
 
-        addHistory({
-            ...historyItem,
-            durationMS: durationMs,
-            responseData: historyResponseData(responseData),
-        });
+        // a null history item means we are showing a shared response
+        if (historyItem) {
+            addHistory({
+                ...historyItem,
+                durationMS: durationMs,
+                responseData: historyResponseData(responseData),
+            });
+        }
+        lastResponse = responseData;
 
         // TODO(jh): "copy as grpcurl" button? This would provide a
         // command-line for grpcurl that does the same thing as clicking
@@ -2770,9 +2961,18 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
 
     const showExamplesUI = () => {
         let examplesList = $("#grpc-request-examples");
-        examples.forEach(example => {
+        examplesList.empty();
+        let group = null;
+        examples.forEach((example, index) => {
+            // examples for the same method are adjacent, so we add a heading
+            // before the first example of each method
+            const method = example.service + "." + example.method;
+            if (method !== group) {
+                group = method;
+                examplesList.append($('<li class="grpc-request-example-group">').text(method));
+            }
             let exampleItem = $('<li class="grpc-request-example">');
-            exampleItem.addClass("grpc-request-example");
+            exampleItem.attr("data-index", index);
             exampleItem.text(example.name);
             if (example.description) {
                 exampleItem.prop('title', example.description);
@@ -2781,9 +2981,10 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             examplesList.append(exampleItem);
         })
         examplesList.selectable({
+            filter: "li.grpc-request-example",
             stop: function() {
                 $(".ui-selected", this).each(function() {
-                    const index = $("li", examplesList).index(this);
+                    const index = Number($(this).attr("data-index"));
                     loadRequest(examples[index])
                 });
             }
@@ -2792,6 +2993,21 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
     }
 
     const loadHistory = () => {
+        if (features.historyURI) {
+            // history is kept on the server
+            const search = $("#grpc-history-search").val();
+            $.ajax({
+                type: "GET",
+                url: features.historyURI,
+                data: {q: search, limit: maxHistory},
+            }).done(function(items) {
+                history = items;
+                updateHistoryUI();
+            }).fail(function(failureData) {
+                console.trace(failureData.responseText);
+            });
+            return;
+        }
         const json = localStorage.getItem(historyStorageKey);
         if (json) {
             history = (JSON.parse(json));
@@ -2826,6 +3042,18 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
 
     const clearHistory = () => {
         if (confirm('Are you sure you wish to delete all history? This action is permanent and cannot be undone.')) {
+            if (features.historyURI) {
+                $.ajax({
+                    type: "DELETE",
+                    url: features.historyURI,
+                }).done(function() {
+                    history = [];
+                    updateHistoryUI();
+                }).fail(function(failureData) {
+                    alert("Failed to clear history: " + failureData.responseText);
+                });
+                return;
+            }
             history = [];
             onHistoryChange();
         }
@@ -2844,7 +3072,9 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
         document.body.removeChild(element);
     }
 
-    const saveHistory = () => {
+    // Returns the history in the same format as examples, for saving or
+    // exporting.
+    const historyAsExamples = () => {
         let savedHistory = [];
         for (let i = 0; i < history.length; i++) {
             let item = $.extend({}, history[i]); // make a copy before mutating
@@ -2853,6 +3083,10 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             delete item.startTime;
             delete item.durationMS;
             delete item.responseData;
+            delete item.failureStatus;
+            // properties of history kept on the server
+            delete item.id;
+            delete item.user;
             let req = item.request;
             if (req.hasOwnProperty('timeout')) {
                 req = $.extend({}, req); // make a copy before mutating
@@ -2865,11 +3099,71 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
             }
             savedHistory.push(item);
         }
+        return savedHistory;
+    }
+
+    const saveHistory = () => {
+        download('history.json', JSON.stringify(historyAsExamples(), null, 2));
+    }
 
-        download('history.json', JSON.stringify(savedHistory, null, 2));
+    const exportPostman = () => {
+        $.ajax({
+            type: "POST",
+            url: features.postmanURI + "/export",
+            contentType: "application/json",
+            data: JSON.stringify(historyAsExamples()),
+            dataType: "text",
+        }).done(function(collection) {
+            download('grpcui.postman_collection.json', collection);
+        }).fail(function(failureData) {
+            alert("Failed to export history: " + failureData.responseText);
+        });
+    }
+
+    const importPostman = (file) => {
+        file.text().then(function(text) {
+            $.ajax({
+                type: "POST",
+                url: features.postmanURI + "/import",
+                contentType: "application/json",
+                data: text,
+            }).done(function(result) {
+                if (result.examples.length > 0) {
+                    examples = (examples || []).concat(result.examples);
+                    // keep examples for the same method together
+                    examples.sort((a, b) => {
+                        const x = a.service + "." + a.method, y = b.service + "." + b.method;
+                        return x < y ? -1 : x > y ? 1 : 0;
+                    });
+                    showExamplesUI();
+                }
+                let msg = `Imported ${result.examples.length} example(s).`;
+                if (result.problems.length > 0) {
+                    msg += "\n\nThe following requests could not be imported:\n" +
+                        result.problems.map(p => `${p.item} (${p.methodPath}): ${p.reason}`).join("\n");
+                }
+                alert(msg);
+            }).fail(function(failureData) {
+                alert("Failed to import collection: " + failureData.responseText);
+            });
+        });
     }
 
     const addHistory = (item) => {
+        if (features.historyURI) {
+            $.ajax({
+                type: "POST",
+                url: features.historyURI,
+                contentType: "application/json",
+                data: JSON.stringify(item),
+            }).done(function() {
+                // reload, so the new item is only shown if it matches the search
+                loadHistory();
+            }).fail(function(failureData) {
+                console.trace(failureData.responseText);
+            });
+            return;
+        }
         history = history.slice(0, maxHistory - 1);
         history.unshift(item);
         onHistoryChange();
@@ -2959,12 +3253,17 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
         });
     }
 
-    const loadRequest = (item) => {
+    // Loads the given request into the form. If given, the callback is
+    // called once the form is populated.
+    const loadRequest = (item, callback) => {
         const t = $("#grpc-request-response");
         t.tabs("option", "active", 0);
         let timeout = "";
         if (item.request.timeout_seconds) {
             timeout = item.request.timeout_seconds + "";
+        } else if (item.request.timeout_secs) {
+            // examples use 'timeout_secs'
+            timeout = item.request.timeout_secs + "";
         } else if (item.request.timeout) {
             // older versions stored string in 'timeout' attribute; so support
             // that in case someone loads an item from history from older
@@ -2986,9 +3285,449 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
                     addMetadataRow(metadata.name, metadata.value);
                 }
             }
+            callback?.();
         }, item.method);
     }
 
+    // Parses a pasted grpcurl command line on the server, which checks it
+    // against the exposed methods, and loads the result into the form.
+    if (features.importGRPCurlURI) {
+        $("#grpc-curl-import").show();
+        $("#grpc-curl-import-button").click(function() {
+            const command = $("#grpc-curl-import-text").val();
+            if (command.trim() === "") {
+                return;
+            }
+            $.ajax({
+                type: "POST",
+                url: features.importGRPCurlURI,
+                contentType: "application/json",
+                data: JSON.stringify({command: command}),
+            }).done(function(item) {
+                clearExampleSelection();
+                loadRequest(item);
+                $("#grpc-curl-import-text").val("");
+            }).fail(function(failureData) {
+                alert(failureData.responseText);
+            });
+        });
+    }
+
+    // Replaces the request data in the form with plausible sample data that
+    // the server generates from the request type.
+    if (features.sampleDataURI) {
+        $("#grpc-request-sample").show().click(function() {
+            const service = $("#grpc-service").val();
+            const method = $("#grpc-method").val();
+            $.ajax({
+                type: "GET",
+                url: features.sampleDataURI + "/" + service + "." + method,
+            }).done(function(data) {
+                clearExampleSelection();
+                updateJSONRequest(data);
+                validateJSON();
+            }).fail(function(failureData) {
+                alert("Failed to generate sample data: " + failureData.responseText);
+            });
+        });
+    }
+
+    // Saves the request in the form as a named example on the server, so it
+    // is available to everyone using this UI.
+    if (features.saveExampleURI) {
+        const saveExampleForm = $("#grpc-save-example");
+        $("#grpc-save-example-button").show().click(function(e) {
+            if (onlyIfValid(e)) {
+                saveExampleForm.show();
+                $("#grpc-save-example-name").focus();
+            }
+        });
+        $("#grpc-save-example-cancel").click(function() {
+            saveExampleForm.hide();
+        });
+        $("#grpc-save-example-save").click(function(e) {
+            if (!onlyIfValid(e)) {
+                return;
+            }
+            const name = $("#grpc-save-example-name").val().trim();
+            if (name === "") {
+                alert("Please enter a name for the example.");
+                return;
+            }
+            const example = {
+                name: name,
+                description: $("#grpc-save-example-description").val(),
+                service: $("#grpc-service").val(),
+                method: $("#grpc-method").val(),
+                request: {
+                    timeout_seconds: formTimeout(),
+                    metadata: formMetadata(),
+                    data: requestForm.data("request"),
+                },
+            };
+            $.ajax({
+                type: "POST",
+                url: features.saveExampleURI,
+                contentType: "application/json",
+                data: JSON.stringify(example),
+            }).done(function() {
+                saveExampleForm.hide();
+                $("#grpc-save-example-name").val("");
+                $("#grpc-save-example-description").val("");
+                loadExamples();
+            }).fail(function(failureData) {
+                alert("Failed to save example: " + failureData.responseText);
+            });
+        });
+    }
+
+    // Shares the request in the form, and optionally its response, by storing
+    // it on the server and showing a link that opens the UI with the request
+    // pre-filled.
+    if (features.shareURI) {
+        const shareForm = $("#grpc-share");
+        $("#grpc-share-button").show().click(function(e) {
+            if (onlyIfValid(e)) {
+                $("#grpc-share-response").prop("checked", false).prop("disabled", !lastResponse);
+                $("#grpc-share-secrets").prop("checked", false);
+                $("#grpc-share-link").hide();
+                shareForm.show();
+            }
+        });
+        $("#grpc-share-cancel").click(function() {
+            shareForm.hide();
+        });
+        $("#grpc-share-create").click(function(e) {
+            if (!onlyIfValid(e)) {
+                return;
+            }
+            const shared = {
+                service: $("#grpc-service").val(),
+                method: $("#grpc-method").val(),
+                request: {
+                    timeout_seconds: formTimeout(),
+                    metadata: formMetadata(),
+                    data: requestForm.data("request"),
+                },
+                includeSecrets: $("#grpc-share-secrets").prop("checked"),
+            };
+            if (lastResponse && $("#grpc-share-response").prop("checked")) {
+                shared.response = lastResponse;
+            }
+            $.ajax({
+                type: "POST",
+                url: features.shareURI,
+                contentType: "application/json",
+                data: JSON.stringify(shared),
+            }).done(function(result) {
+                const url = new URL(window.location.href);
+                url.search = "";
+                url.hash = "";
+                url.searchParams.set("share", result.id);
+                $("#grpc-share-link").show();
+                $("#grpc-share-url").val(url.toString()).select();
+            }).fail(function(failureData) {
+                alert("Failed to share request: " + failureData.responseText);
+            });
+        });
+        $("#grpc-share-copy").click(function() {
+            navigator.clipboard?.writeText($("#grpc-share-url").val());
+        });
+
+        // if the page was opened via a link to a shared request, load it
+        const sharedID = new URLSearchParams(window.location.search).get("share");
+        if (sharedID) {
+            $.ajax({
+                type: "GET",
+                url: features.shareURI + "/" + encodeURIComponent(sharedID),
+            }).done(function(shared) {
+                clearExampleSelection();
+                loadRequest(shared, () => {
+                    if (shared.response) {
+                        renderResponse(null, 0, shared.response);
+                    }
+                });
+            }).fail(function(failureData) {
+                alert("Failed to load shared request: " + failureData.responseText);
+            });
+        }
+    }
+
+    // Lets users keep requests in named folders in a workspace on the server,
+    // and browse, run, edit, export, and import them from their own tab.
+    if (features.workspaceURI) {
+        let workspace = {version: 1, revision: 0, folders: []};
+        // the item being edited, and the folder that holds it; a new item
+        // that is not yet in a folder has no folder
+        let editing = null, editingFolder = null;
+        const editor = $("#grpc-workspace-editor");
+
+        const loadWorkspace = () => {
+            $.ajax({
+                type: "GET",
+                url: features.workspaceURI,
+            }).done(function(ws) {
+                workspace = ws;
+                updateWorkspaceUI();
+            }).fail(function(failureData) {
+                console.trace(failureData.responseText);
+            });
+        };
+
+        // Saves the workspace after it has been changed locally. If someone
+        // else changed it in the meantime, the change is discarded and the
+        // latest workspace is loaded instead.
+        const saveWorkspace = (onSuccess) => {
+            $.ajax({
+                type: "PUT",
+                url: features.workspaceURI,
+                contentType: "application/json",
+                data: JSON.stringify(workspace),
+            }).done(function(ws) {
+                workspace = ws;
+                updateWorkspaceUI();
+                onSuccess?.();
+            }).fail(function(failureData) {
+                alert("Failed to save workspace: " + failureData.responseText);
+                loadWorkspace();
+            });
+        };
+
+        // Returns all folders in the workspace, depth first, along with their
+        // paths, for choosing where to put an item.
+        const allFolders = () => {
+            let result = [];
+            const visit = (folders, path) => {
+                for (const folder of folders || []) {
+                    const folderPath = path + folder.name;
+                    result.push({folder: folder, path: folderPath});
+                    visit(folder.folders, folderPath + " / ");
+                }
+            };
+            visit(workspace.folders, "");
+            return result;
+        };
+
+        // Replaces references to variables, like "{{name}}", in the given
+        // item's metadata values and in strings in its data, and returns the
+        // resulting request.
+        const applyVariables = (item) => {
+            const vars = item.variables || {};
+            const replace = (s) => s.replace(/\{\{\s*([\w.-]+)\s*\}\}/g, (ref, name) => {
+                return vars.hasOwnProperty(name) ? vars[name] : ref;
+            });
+            const replaceAll = (v) => {
+                if (typeof v === "string") {
+                    return replace(v);
+                } else if (Array.isArray(v)) {
+                    return v.map(replaceAll);
+                } else if (v !== null && typeof v === "object") {
+                    let result = {};
+                    for (const k in v) {
+                        result[k] = replaceAll(v[k]);
+                    }
+                    return result;
+                }
+                return v;
+            };
+            let req = $.extend({}, item.request);
+            req.data = replaceAll(req.data);
+            req.metadata = (req.metadata || []).map(md => ({name: md.name, value: replace(md.value)}));
+            return {service: item.service, method: item.method, request: req};
+        };
+
+        const openEditor = (item, folder) => {
+            editing = item;
+            editingFolder = folder;
+            $("#grpc-workspace-item-name").val(item.name || "");
+            $("#grpc-workspace-item-notes").val(item.notes || "");
+            $("#grpc-workspace-item-variables").val(
+                Object.entries(item.variables || {}).map(([k, v]) => k + "=" + v).join("\n"));
+            const select = $("#grpc-workspace-item-folder").empty();
+            const folders = allFolders();
+            if (folders.length === 0) {
+                select.append($("<option>").val("").text("Requests (new folder)"));
+            }
+            for (const f of folders) {
+                select.append($("<option>").val(f.folder.id).text(f.path));
+            }
+            if (folder) {
+                select.val(folder.id);
+            }
+            editor.show();
+            $("#grpc-workspace-item-name").focus();
+        };
+
+        const findFolder = (id) => allFolders().find(f => f.folder.id === id)?.folder;
+
+        $("#grpc-workspace-item-cancel").click(function() {
+            editor.hide();
+            editing = editingFolder = null;
+        });
+        $("#grpc-workspace-item-save").click(function() {
+            const name = $("#grpc-workspace-item-name").val().trim();
+            if (name === "") {
+                alert("Please enter a name for the request.");
+                return;
+            }
+            let variables = {};
+            for (const line of $("#grpc-workspace-item-variables").val().split("\n")) {
+                if (line.trim() === "") {
+                    continue;
+                }
+                const i = line.indexOf("=");
+                if (i <= 0) {
+                    alert("Variables must be entered as name=value, one per line.");
+                    return;
+                }
+                variables[line.substring(0, i).trim()] = line.substring(i + 1);
+            }
+            let folder = findFolder($("#grpc-workspace-item-folder").val());
+            if (!folder) {
+                folder = {name: "Requests", items: []};
+                workspace.folders.push(folder);
+            }
+            editing.name = name;
+            editing.notes = $("#grpc-workspace-item-notes").val();
+            editing.variables = variables;
+            if (folder !== editingFolder) {
+                if (editingFolder) {
+                    editingFolder.items.splice(editingFolder.items.indexOf(editing), 1);
+                }
+                folder.items = folder.items || [];
+                folder.items.push(editing);
+            }
+            saveWorkspace(() => {
+                editor.hide();
+                editing = editingFolder = null;
+            });
+        });
+
+        const renderFolder = (folder, parent) => {
+            let el = $('<div class="grpc-workspace-folder">');
+            let header = $('<div class="grpc-workspace-folder-header">');
+            header.append($('<span class="grpc-workspace-folder-name">').text(folder.name));
+            header.append($('<button>').text("Add Folder").click(() => {
+                const name = prompt("Name of the new folder:");
+                if (name && name.trim() !== "") {
+                    folder.folders = folder.folders || [];
+                    folder.folders.push({name: name.trim()});
+                    saveWorkspace();
+                }
+            }));
+            header.append($('<button>').text("Rename").click(() => {
+                const name = prompt("New name of the folder:", folder.name);
+                if (name && name.trim() !== "") {
+                    folder.name = name.trim();
+                    saveWorkspace();
+                }
+            }));
+            header.append($('<button>').text("Delete").click(() => {
+                if (confirm(`Delete folder "${folder.name}" and everything in it?`)) {
+                    parent.splice(parent.indexOf(folder), 1);
+                    saveWorkspace();
+                }
+            }));
+            el.append(header);
+            let contents = $('<div class="grpc-workspace-folder-contents">');
+            for (const item of folder.items || []) {
+                let itemEl = $('<div class="grpc-workspace-item">');
+                itemEl.append($('<span class="grpc-workspace-item-name">').text(item.name));
+                itemEl.append($('<span class="grpc-workspace-item-method">').text(item.service + "." + item.method));
+                itemEl.append($('<button>').text("Load").click(() => {
+                    clearExampleSelection();
+                    loadRequest(applyVariables(item));
+                }));
+                itemEl.append($('<button>').text("Run").click(() => {
+                    clearExampleSelection();
+                    loadRequest(applyVariables(item), () => invoke());
+                }));
+                itemEl.append($('<button>').text("Edit").click(() => openEditor(item, folder)));
+                itemEl.append($('<button>').text("Delete").click(() => {
+                    if (confirm(`Delete request "${item.name}"?`)) {
+                        folder.items.splice(folder.items.indexOf(item), 1);
+                        saveWorkspace();
+                    }
+                }));
+                if (item.notes) {
+                    itemEl.append($('<pre class="grpc-workspace-item-notes">').text(item.notes));
+                }
+                contents.append(itemEl);
+            }
+            for (const sub of folder.folders || []) {
+                contents.append(renderFolder(sub, folder.folders));
+            }
+            el.append(contents);
+            return el;
+        };
+
+        const updateWorkspaceUI = () => {
+            const tree = $("#grpc-workspace-tree").empty();
+            if (workspace.folders.length === 0) {
+                tree.append($('<p class="grpc-workspace-empty">').text(
+                    'No saved requests. Use "Save to Workspace" on the Request Form tab to add one.'));
+            }
+            for (const folder of workspace.folders) {
+                tree.append(renderFolder(folder, workspace.folders));
+            }
+        };
+
+        $("#grpc-request-response a[href='#grpc-workspace-tab']").parent().show();
+        $("#grpc-workspace-save-button").show().click(function(e) {
+            if (!onlyIfValid(e)) {
+                return;
+            }
+            const item = {
+                service: $("#grpc-service").val(),
+                method: $("#grpc-method").val(),
+                request: {
+                    timeout_seconds: formTimeout(),
+                    metadata: formMetadata(),
+                    data: requestForm.data("request"),
+                },
+            };
+            const tabs = $("#grpc-request-response");
+            tabs.tabs("option", "active", tabs.find("> ul > li").index($("a[href='#grpc-workspace-tab']").parent()));
+            openEditor(item, null);
+        });
+        $("#grpc-workspace-new-folder").click(function() {
+            const name = prompt("Name of the new folder:");
+            if (name && name.trim() !== "") {
+                workspace.folders.push({name: name.trim()});
+                saveWorkspace();
+            }
+        });
+        $("#grpc-workspace-export").click(function() {
+            download('workspace.json', JSON.stringify(workspace, null, 2));
+        });
+        $("#grpc-workspace-import").click(() => $("#grpc-workspace-file").click());
+        $("#grpc-workspace-file").change(function() {
+            if (this.files.length > 0) {
+                this.files[0].text().then(function(text) {
+                    let imported;
+                    try {
+                        imported = JSON.parse(text);
+                    } catch (e) {
+                        alert("Failed to parse workspace: " + e);
+                        return;
+                    }
+                    if (imported.version !== workspace.version) {
+                        alert(`Unsupported workspace version ${imported.version}; expecting ${workspace.version}.`);
+                        return;
+                    }
+                    // imported folders are added next to the existing ones;
+                    // the server assigns new IDs to any that clash
+                    workspace.folders = workspace.folders.concat(imported.folders || []);
+                    saveWorkspace(() => alert(`Imported ${(imported.folders || []).length} folder(s).`));
+                });
+            }
+            // reset, so choosing the same file again still triggers a change
+            $(this).val('');
+        });
+
+        loadWorkspace();
+    }
+
     const clearExampleSelection = () => {
         $('#grpc-request-examples .ui-selected').removeClass('ui-selected')
     }
@@ -2999,6 +3738,17 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
     }
 
     const deleteHistoryItem = (index) => {
+        if (features.historyURI) {
+            $.ajax({
+                type: "DELETE",
+                url: features.historyURI + "/" + encodeURIComponent(history[index].id),
+            }).done(function() {
+                loadHistory();
+            }).fail(function(failureData) {
+                alert("Failed to delete history item: " + failureData.responseText);
+            });
+            return;
+        }
         history.splice(index, 1);
         onHistoryChange();
     }
@@ -3062,7 +3812,27 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
     });
 
     $('#grpc-history-clear').click(() => clearHistory());
+    if (features.historyURI) {
+        let searchTimer;
+        $('#grpc-history-search').show().on('input', () => {
+            // wait for the user to stop typing before searching
+            clearTimeout(searchTimer);
+            searchTimer = setTimeout(loadHistory, 300);
+        });
+    }
     $('#grpc-history-save').click(() => saveHistory());
+    if (features.postmanURI) {
+        $('#grpc-history-postman').show();
+        $('#grpc-history-postman-export').click(() => exportPostman());
+        $('#grpc-history-postman-import').click(() => $('#grpc-history-postman-file').click());
+        $('#grpc-history-postman-file').change(function() {
+            if (this.files.length > 0) {
+                importPostman(this.files[0]);
+            }
+            // reset, so choosing the same file again still triggers a change
+            $(this).val('');
+        });
+    }
 
     loadExamples();
     loadHistory();
@@ -3083,6 +3853,9 @@ window.initGRPCForm = function(services, svcDescs, mtdDescs, invokeURI, metadata
     // initialize methods drop-down based on selected service and preferred method
     formServiceSelected(undefined, initialSelection.method);
 
+    for (const name of features.injectedMetadata || []) {
+        addInjectedMetadataRow(name);
+    }
     if (isUnset(headers) || headers.length === 0) {
         // add a single blank entry to request metadata table
         addMetadataRow();
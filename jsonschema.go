@@ -0,0 +1,594 @@
+package grpcui
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// RPCJSONSchemaHandler returns an HTTP handler that can be used to get JSON
+// Schema documents that describe the JSON format of request and response
+// messages. The schemas follow the proto3 JSON mapping rules, so they can be
+// used to validate JSON request data and fixtures: 64-bit integers may be
+// strings, well-known types use their special JSON formats (for example, a
+// google.protobuf.Timestamp is an RFC 3339 string), and at most one field of
+// each oneof may be present.
+//
+// The handler accepts GET requests and uses query parameters to indicate the
+// type whose schema should be returned:
+//   - "method" names an RPC method (the same as with RPCMetadataHandler). By
+//     default, the schema describes the method's request type. If the "part"
+//     query parameter is "response", it describes the response type instead.
+//     If the method is "*", the response includes definitions for *all*
+//     message types in the given files, but no top-level type.
+//   - "type" names a message type, which must be defined in the given files.
+//
+// All message and enum types used are included as definitions in the "$defs"
+// property of the returned schema.
+func RPCJSONSchemaHandler(methods []*desc.MethodDescriptor, files []*desc.FileDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		gen := newJSONSchemaGenerator("#/$defs/")
+		var root map[string]interface{}
+		query := r.URL.Query()
+		if typeName := query.Get("type"); typeName != "" {
+			md := findMessage(files, typeName)
+			if md == nil {
+				http.Error(w, "Unknown message type", http.StatusUnprocessableEntity)
+				return
+			}
+			root = gen.messageSchema(md)
+		} else if method := query.Get("method"); method == "*" {
+			root = map[string]interface{}{}
+			for _, fd := range files {
+				gen.visitAllMessages(fd.GetMessageTypes())
+			}
+		} else {
+			for _, md := range methods {
+				if md.GetFullyQualifiedName() == method {
+					if query.Get("part") == "response" {
+						root = gen.messageSchema(md.GetOutputType())
+					} else {
+						root = gen.messageSchema(md.GetInputType())
+					}
+					break
+				}
+			}
+			if root == nil {
+				http.Error(w, "Unknown RPC Method", http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		root["$schema"] = jsonSchemaDialect
+		if len(gen.defs) > 0 {
+			root["$defs"] = gen.defs
+		}
+
+		w.Header().Set("Content-Type", "application/schema+json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(root)
+	})
+}
+
+// RPCOpenAPIHandler returns an HTTP handler that serves an OpenAPI 3.1
+// document for the given methods. Only methods that have HTTP bindings, via
+// the google.api.http method option, are included. Request and response
+// schemas use the same format as RPCJSONSchemaHandler and are included in the
+// document's components.
+//
+// The handler accepts GET requests and has no query parameters.
+func RPCOpenAPIHandler(methods []*desc.MethodDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(openAPIDocument(methods))
+	})
+}
+
+func findMessage(files []*desc.FileDescriptor, name string) *desc.MessageDescriptor {
+	for _, fd := range transitiveFiles(files) {
+		if md := fd.FindMessage(name); md != nil {
+			return md
+		}
+	}
+	return nil
+}
+
+type jsonSchemaGenerator struct {
+	refPrefix string
+	defs      map[string]interface{}
+}
+
+func newJSONSchemaGenerator(refPrefix string) *jsonSchemaGenerator {
+	return &jsonSchemaGenerator{
+		refPrefix: refPrefix,
+		defs:      map[string]interface{}{},
+	}
+}
+
+func (g *jsonSchemaGenerator) visitAllMessages(msgs []*desc.MessageDescriptor) {
+	for _, md := range msgs {
+		if wellKnownSchema(md) == nil {
+			g.messageRef(md)
+		}
+		g.visitAllMessages(md.GetNestedMessageTypes())
+	}
+}
+
+// messageSchema returns a schema that refers to the given message type. The
+// returned map is a new value that the caller may mutate.
+func (g *jsonSchemaGenerator) messageSchema(md *desc.MessageDescriptor) map[string]interface{} {
+	if s := wellKnownSchema(md); s != nil {
+		return s
+	}
+	return g.messageRef(md)
+}
+
+func (g *jsonSchemaGenerator) messageRef(md *desc.MessageDescriptor) map[string]interface{} {
+	name := md.GetFullyQualifiedName()
+	if _, ok := g.defs[name]; !ok {
+		// add placeholder first, in case of recursive types
+		g.defs[name] = nil
+		g.defs[name] = g.messageDef(md)
+	}
+	return map[string]interface{}{"$ref": g.refPrefix + name}
+}
+
+func (g *jsonSchemaGenerator) messageDef(md *desc.MessageDescriptor) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	var constraints []interface{}
+	for _, fd := range md.GetFields() {
+		s := g.fieldSchema(fd)
+		props[fd.GetJSONName()] = s
+		// grpcui emits, and jsonpb accepts, the original proto names, too
+		if fd.GetName() != fd.GetJSONName() {
+			props[fd.GetName()] = s
+		}
+		if fd.IsRequired() {
+			if fd.GetName() == fd.GetJSONName() {
+				required = append(required, fd.GetJSONName())
+			} else {
+				constraints = append(constraints, fieldPresent(fd))
+			}
+		}
+	}
+	for _, ood := range md.GetOneOfs() {
+		if ood.IsSynthetic() {
+			continue
+		}
+		// at most one of the fields may be present
+		var present []interface{}
+		for _, fd := range ood.GetChoices() {
+			present = append(present, fieldPresent(fd))
+		}
+		choices := append(present, map[string]interface{}{
+			"not": map[string]interface{}{"anyOf": present},
+		})
+		constraints = append(constraints, map[string]interface{}{"oneOf": choices})
+	}
+
+	def := map[string]interface{}{
+		"type":                 "object",
+		"title":                md.GetFullyQualifiedName(),
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		def["required"] = required
+	}
+	if len(constraints) > 0 {
+		def["allOf"] = constraints
+	}
+	addDescription(def, md)
+	return def
+}
+
+// fieldPresent returns a schema that requires the given field to be present,
+// under either its JSON name or its original proto name.
+func fieldPresent(fd *desc.FieldDescriptor) map[string]interface{} {
+	if fd.GetName() == fd.GetJSONName() {
+		return map[string]interface{}{"required": []string{fd.GetName()}}
+	}
+	return map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"required": []string{fd.GetJSONName()}},
+		map[string]interface{}{"required": []string{fd.GetName()}},
+	}}
+}
+
+func (g *jsonSchemaGenerator) fieldSchema(fd *desc.FieldDescriptor) map[string]interface{} {
+	var s map[string]interface{}
+	if fd.IsMap() {
+		s = map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": g.singularFieldSchema(fd.GetMapValueType()),
+		}
+		if keySchema := g.singularFieldSchema(fd.GetMapKeyType()); keySchema["type"] != "string" {
+			// map keys are always strings in JSON, but the string must
+			// still be a valid value for the key type
+			s["propertyNames"] = mapKeySchema(fd.GetMapKeyType())
+		}
+	} else if fd.IsRepeated() {
+		s = map[string]interface{}{
+			"type":  "array",
+			"items": g.singularFieldSchema(fd),
+		}
+	} else {
+		s = g.singularFieldSchema(fd)
+	}
+	addDescription(s, fd)
+	return s
+}
+
+func (g *jsonSchemaGenerator) singularFieldSchema(fd *desc.FieldDescriptor) map[string]interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return g.messageSchema(fd.GetMessageType())
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return g.enumRef(fd.GetEnumType())
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return map[string]interface{}{"type": "string"}
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return map[string]interface{}{"type": "boolean"}
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "number"},
+				map[string]interface{}{"type": "string", "enum": []string{"NaN", "Infinity", "-Infinity"}},
+			},
+		}
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return map[string]interface{}{"type": "integer", "minimum": math.MinInt32, "maximum": math.MaxInt32}
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return map[string]interface{}{"type": "integer", "minimum": 0, "maximum": math.MaxUint32}
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		// 64-bit int values are represented as strings in JSON, but
+		// parsers also accept numbers
+		return map[string]interface{}{"type": []string{"string", "integer"}, "pattern": "^-?[0-9]+$"}
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return map[string]interface{}{"type": []string{"string", "integer"}, "pattern": "^[0-9]+$", "minimum": 0}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func mapKeySchema(fd *desc.FieldDescriptor) map[string]interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return map[string]interface{}{"enum": []string{"true", "false"}}
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return map[string]interface{}{"pattern": "^[0-9]+$"}
+	default:
+		return map[string]interface{}{"pattern": "^-?[0-9]+$"}
+	}
+}
+
+func (g *jsonSchemaGenerator) enumRef(ed *desc.EnumDescriptor) map[string]interface{} {
+	if ed.GetFullyQualifiedName() == "google.protobuf.NullValue" {
+		return map[string]interface{}{"type": "null"}
+	}
+	name := ed.GetFullyQualifiedName()
+	if _, ok := g.defs[name]; !ok {
+		names := make([]string, len(ed.GetValues()))
+		nums := make([]int32, len(ed.GetValues()))
+		for i, evd := range ed.GetValues() {
+			names[i] = evd.GetName()
+			nums[i] = evd.GetNumber()
+		}
+		def := map[string]interface{}{
+			"title": name,
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string", "enum": names},
+				map[string]interface{}{"type": "integer", "enum": nums},
+			},
+		}
+		addDescription(def, ed)
+		g.defs[name] = def
+	}
+	return map[string]interface{}{"$ref": g.refPrefix + name}
+}
+
+// wellKnownSchema returns the schema for the given message if it is one of
+// the well-known types that has a special JSON format. Otherwise, it returns
+// nil.
+func wellKnownSchema(md *desc.MessageDescriptor) map[string]interface{} {
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return map[string]interface{}{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]{1,9})?s$`}
+	case "google.protobuf.FieldMask":
+		return map[string]interface{}{"type": "string"}
+	case "google.protobuf.Struct":
+		return map[string]interface{}{"type": "object"}
+	case "google.protobuf.ListValue":
+		return map[string]interface{}{"type": "array"}
+	case "google.protobuf.Value":
+		return map[string]interface{}{}
+	case "google.protobuf.Empty":
+		return map[string]interface{}{"type": "object", "additionalProperties": false}
+	case "google.protobuf.Any":
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"@type": map[string]interface{}{"type": "string"}},
+			"required":   []string{"@type"},
+		}
+	case "google.protobuf.StringValue":
+		return map[string]interface{}{"type": []string{"string", "null"}}
+	case "google.protobuf.BytesValue":
+		return map[string]interface{}{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+	case "google.protobuf.BoolValue":
+		return map[string]interface{}{"type": []string{"boolean", "null"}}
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue":
+		return map[string]interface{}{"type": []string{"number", "string", "null"}}
+	case "google.protobuf.Int32Value":
+		return map[string]interface{}{"type": []string{"integer", "null"}, "minimum": math.MinInt32, "maximum": math.MaxInt32}
+	case "google.protobuf.UInt32Value":
+		return map[string]interface{}{"type": []string{"integer", "null"}, "minimum": 0, "maximum": math.MaxUint32}
+	case "google.protobuf.Int64Value":
+		return map[string]interface{}{"type": []string{"string", "integer", "null"}, "pattern": "^-?[0-9]+$"}
+	case "google.protobuf.UInt64Value":
+		return map[string]interface{}{"type": []string{"string", "integer", "null"}, "pattern": "^[0-9]+$"}
+	default:
+		return nil
+	}
+}
+
+func addDescription(s map[string]interface{}, d desc.Descriptor) {
+	if info := d.GetSourceInfo(); info != nil {
+		if comment := strings.TrimSpace(info.GetLeadingComments()); comment != "" {
+			s["description"] = comment
+		}
+	}
+}
+
+func openAPIDocument(methods []*desc.MethodDescriptor) map[string]interface{} {
+	gen := newJSONSchemaGenerator("#/components/schemas/")
+	paths := map[string]map[string]interface{}{}
+	var tags []string
+	seenTags := map[string]struct{}{}
+	for _, md := range methods {
+		rule := httpRule(md)
+		if rule == nil {
+			continue
+		}
+		svcName := md.GetService().GetFullyQualifiedName()
+		if _, ok := seenTags[svcName]; !ok {
+			seenTags[svcName] = struct{}{}
+			tags = append(tags, svcName)
+		}
+		rules := append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...)
+		for i, rule := range rules {
+			verb, pattern := httpRulePattern(rule)
+			if verb == "" {
+				continue
+			}
+			path, pathParams := openAPIPath(pattern)
+			op := map[string]interface{}{
+				"operationId": md.GetFullyQualifiedName(),
+				"tags":        []string{svcName},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "OK",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": gen.bodySchema(md.GetOutputType(), rule.GetResponseBody()),
+							},
+						},
+					},
+				},
+			}
+			if i > 0 {
+				op["operationId"] = md.GetFullyQualifiedName() + "_" + strconv.Itoa(i)
+			}
+			if info := md.GetSourceInfo(); info != nil {
+				if comment := strings.TrimSpace(info.GetLeadingComments()); comment != "" {
+					op["description"] = comment
+				}
+			}
+			var params []interface{}
+			for _, p := range pathParams {
+				params = append(params, map[string]interface{}{
+					"name":     p,
+					"in":       "path",
+					"required": true,
+					"schema":   gen.fieldPathSchema(md.GetInputType(), p),
+				})
+			}
+			if rule.GetBody() != "" {
+				op["requestBody"] = map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": gen.bodySchema(md.GetInputType(), rule.GetBody()),
+						},
+					},
+				}
+			}
+			if rule.GetBody() != "*" {
+				// remaining top-level scalar fields may be supplied as query parameters
+				for _, fd := range md.GetInputType().GetFields() {
+					if fd.GetName() == rule.GetBody() || containsFieldPath(pathParams, fd.GetName()) || fd.GetMessageType() != nil {
+						continue
+					}
+					params = append(params, map[string]interface{}{
+						"name":   fd.GetJSONName(),
+						"in":     "query",
+						"schema": gen.fieldSchema(fd),
+					})
+				}
+			}
+			if len(params) > 0 {
+				op["parameters"] = params
+			}
+			if paths[path] == nil {
+				paths[path] = map[string]interface{}{}
+			}
+			paths[path][verb] = op
+		}
+	}
+	sort.Strings(tags)
+	tagDefs := make([]interface{}, len(tags))
+	for i, t := range tags {
+		tagDefs[i] = map[string]interface{}{"name": t}
+	}
+
+	return map[string]interface{}{
+		"openapi":           "3.1.0",
+		"jsonSchemaDialect": jsonSchemaDialect,
+		"info": map[string]interface{}{
+			"title":   "gRPC HTTP bindings",
+			"version": "unspecified",
+		},
+		"tags":  tagDefs,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": gen.defs,
+		},
+	}
+}
+
+// httpRule returns the google.api.http option for the given method, or nil if
+// the method has no such option.
+func httpRule(md *desc.MethodDescriptor) *annotations.HttpRule {
+	opts := md.GetMethodOptions()
+	if opts == nil {
+		return nil
+	}
+	if !proto.HasExtension(opts, annotations.E_Http) {
+		// The options may have been unmarshalled without the extension being
+		// known, in which case it is stored as an unrecognized field. So we
+		// round-trip the options to try to resolve it.
+		b, err := proto.Marshal(opts)
+		if err != nil {
+			return nil
+		}
+		opts = &descriptorpb.MethodOptions{}
+		if err := (proto.UnmarshalOptions{Resolver: protoregistry.GlobalTypes}).Unmarshal(b, opts); err != nil {
+			return nil
+		}
+		if !proto.HasExtension(opts, annotations.E_Http) {
+			return nil
+		}
+	}
+	rule, _ := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+	return rule
+}
+
+func httpRulePattern(rule *annotations.HttpRule) (verb, pattern string) {
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "get", p.Get
+	case *annotations.HttpRule_Put:
+		return "put", p.Put
+	case *annotations.HttpRule_Post:
+		return "post", p.Post
+	case *annotations.HttpRule_Delete:
+		return "delete", p.Delete
+	case *annotations.HttpRule_Patch:
+		return "patch", p.Patch
+	case *annotations.HttpRule_Custom:
+		return strings.ToLower(p.Custom.GetKind()), p.Custom.GetPath()
+	default:
+		return "", ""
+	}
+}
+
+// openAPIPath converts a google.api.http path template into an OpenAPI path,
+// by removing the sub-patterns of variables. For example, the template
+// "/v1/{name=projects/*}" becomes "/v1/{name}". It also returns the names of
+// the variables, which are field paths in the request message.
+func openAPIPath(template string) (string, []string) {
+	var sb strings.Builder
+	var params []string
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			sb.WriteString(template)
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			sb.WriteString(template)
+			break
+		}
+		end += start
+		name := template[start+1 : end]
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name = name[:eq]
+		}
+		params = append(params, name)
+		sb.WriteString(template[:start])
+		sb.WriteString("{" + name + "}")
+		template = template[end+1:]
+	}
+	return sb.String(), params
+}
+
+func containsFieldPath(paths []string, fieldName string) bool {
+	for _, p := range paths {
+		if p == fieldName || strings.HasPrefix(p, fieldName+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// bodySchema returns the schema for the given message or, if fieldName is a
+// field name (i.e. not empty or "*"), the schema for that field of the message.
+func (g *jsonSchemaGenerator) bodySchema(md *desc.MessageDescriptor, fieldName string) map[string]interface{} {
+	if fieldName == "" || fieldName == "*" {
+		return g.messageSchema(md)
+	}
+	if fd := md.FindFieldByName(fieldName); fd != nil {
+		return g.fieldSchema(fd)
+	}
+	return g.messageSchema(md)
+}
+
+func (g *jsonSchemaGenerator) fieldPathSchema(md *desc.MessageDescriptor, path string) map[string]interface{} {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		if md == nil {
+			break
+		}
+		fd := md.FindFieldByName(part)
+		if fd == nil {
+			break
+		}
+		if i == len(parts)-1 {
+			return g.fieldSchema(fd)
+		}
+		md = fd.GetMessageType()
+	}
+	return map[string]interface{}{"type": "string"}
+}
@@ -0,0 +1,239 @@
+package grpcui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func serveJSON(t *testing.T, h http.Handler, uri string) map[string]interface{} {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", uri, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s returned status %d: %s", uri, rec.Code, rec.Body.String())
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("GET %s returned invalid JSON: %v", uri, err)
+	}
+	return result
+}
+
+func lookup(v interface{}, path ...string) interface{} {
+	for _, p := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
+
+func TestRPCJSONSchemaHandler(t *testing.T) {
+	methods := loadTestMethods(t)
+	h := RPCJSONSchemaHandler(methods, []*desc.FileDescriptor{methods[0].GetFile()})
+
+	s := serveJSON(t, h, "/jsonschema?type=test.Person")
+	if s["$ref"] != "#/$defs/test.Person" {
+		t.Errorf("unexpected top-level ref: %v", s["$ref"])
+	}
+	person := lookup(s, "$defs", "test.Person")
+	if person == nil {
+		t.Fatalf("schema is missing definition for test.Person: %v", s)
+	}
+	if got := lookup(person, "properties", "id", "type"); !reflect.DeepEqual(got, []interface{}{"string", "integer"}) {
+		t.Errorf("uint64 field should allow strings and integers; got %v", got)
+	}
+	if got := lookup(person, "properties", "isReal", "type"); got != "boolean" {
+		t.Errorf("bool field should use JSON name and boolean type; got %v", got)
+	}
+	if got := lookup(person, "properties", "parent", "$ref"); got != "#/$defs/test.Person" {
+		t.Errorf("recursive field should refer to definition; got %v", got)
+	}
+	if got := lookup(person, "required"); !reflect.DeepEqual(got, []interface{}{"id", "name"}) {
+		t.Errorf("unexpected required fields: %v", got)
+	}
+	oneOfs, _ := lookup(person, "allOf").([]interface{})
+	if len(oneOfs) != 1 {
+		t.Fatalf("expecting one oneof constraint; got %v", lookup(person, "allOf"))
+	}
+	if choices, _ := lookup(oneOfs[0], "oneOf").([]interface{}); len(choices) != 5 {
+		t.Errorf("expecting 4 oneof choices plus none; got %v", choices)
+	}
+
+	s = serveJSON(t, h, "/jsonschema?type=test.WellKnowns")
+	wk := lookup(s, "$defs", "test.WellKnowns")
+	if got := lookup(wk, "properties", "now", "format"); got != "date-time" {
+		t.Errorf("timestamp should be date-time string; got %v", lookup(wk, "properties", "now"))
+	}
+	if got := lookup(wk, "properties", "futures", "items", "format"); got != "date-time" {
+		t.Errorf("repeated timestamp should be array of date-time strings; got %v", lookup(wk, "properties", "futures"))
+	}
+	if got := lookup(wk, "properties", "neat", "required"); !reflect.DeepEqual(got, []interface{}{"@type"}) {
+		t.Errorf("any should require @type; got %v", lookup(wk, "properties", "neat"))
+	}
+
+	s = serveJSON(t, h, "/jsonschema?method=test.KitchenSink.Fail&part=response")
+	if s["$ref"] != "#/$defs/test.TestMessage" {
+		t.Errorf("unexpected top-level ref for response: %v", s["$ref"])
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/jsonschema?method=foo.Bar.Baz", nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expecting unknown method to be rejected; got status %d", rec.Code)
+	}
+}
+
+// satisfies checks the given object against the object-level keywords of the
+// given schema that the JSON Schema generator uses for messages.
+func satisfies(schema, obj map[string]interface{}) bool {
+	props, _ := schema["properties"].(map[string]interface{})
+	if schema["additionalProperties"] == false {
+		for k := range obj {
+			if _, ok := props[k]; !ok {
+				return false
+			}
+		}
+	}
+	if req, ok := schema["required"].([]interface{}); ok {
+		for _, name := range req {
+			if _, ok := obj[name.(string)]; !ok {
+				return false
+			}
+		}
+	}
+	count := func(key string) (int, int) {
+		subs, _ := schema[key].([]interface{})
+		n := 0
+		for _, sub := range subs {
+			if satisfies(sub.(map[string]interface{}), obj) {
+				n++
+			}
+		}
+		return n, len(subs)
+	}
+	if n, total := count("allOf"); n != total {
+		return false
+	}
+	if n, total := count("anyOf"); total > 0 && n == 0 {
+		return false
+	}
+	if n, total := count("oneOf"); total > 0 && n != 1 {
+		return false
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok && satisfies(not, obj) {
+		return false
+	}
+	return true
+}
+
+func TestRPCJSONSchemaHandler_OrigNames(t *testing.T) {
+	methods := loadTestMethods(t)
+	h := RPCJSONSchemaHandler(methods, []*desc.FileDescriptor{methods[0].GetFile()})
+	s := serveJSON(t, h, "/jsonschema?type=test.Person")
+	person, _ := lookup(s, "$defs", "test.Person").(map[string]interface{})
+
+	md := methods[0].GetFile().FindMessage("test.Person")
+	msg := dynamic.NewMessage(md)
+	msg.SetFieldByName("id", uint64(1))
+	msg.SetFieldByName("name", "Bob")
+	msg.SetFieldByName("is_real", false)
+	msg.SetFieldByName("parent", dynamic.NewMessage(md))
+	descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+	if err != nil {
+		t.Fatalf("failed to create descriptor source: %v", err)
+	}
+	// responses, history, and examples use the original proto names
+	resp := responseToJSON(descSource, msg, false, nil, md)
+	var obj map[string]interface{}
+	if err := json.Unmarshal(resp.Data, &obj); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if _, ok := obj["is_real"]; !ok {
+		t.Fatalf("expecting original name in JSON: %s", resp.Data)
+	}
+	if !satisfies(person, obj) {
+		t.Errorf("message with original names does not match schema: %s", resp.Data)
+	}
+	obj["isReal"] = obj["is_real"]
+	delete(obj, "is_real")
+	if !satisfies(person, obj) {
+		t.Errorf("message with JSON names does not match schema")
+	}
+	obj["bogus"] = 1
+	if satisfies(person, obj) {
+		t.Errorf("expecting unknown property to be rejected")
+	}
+}
+
+func TestRPCOpenAPIHandler(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"things.proto": `
+				syntax = "proto3";
+				package things;
+				import "google/api/annotations.proto";
+				message Thing {
+					string name = 1;
+					int64 size = 2;
+				}
+				message GetThingRequest {
+					string name = 1;
+					bool verbose = 2;
+				}
+				service Things {
+					rpc GetThing(GetThingRequest) returns (Thing) {
+						option (google.api.http) = { get: "/v1/{name=things/*}" };
+					}
+					rpc UpdateThing(Thing) returns (Thing) {
+						option (google.api.http) = { patch: "/v1/{name=things/*}" body: "*" };
+					}
+					rpc Unbound(Thing) returns (Thing);
+				}`,
+		}),
+		LookupImport: desc.LoadFileDescriptor,
+	}
+	fds, err := p.ParseFiles("things.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	h := RPCOpenAPIHandler(AllMethodsForServices(fds[0].GetServices()))
+
+	doc := serveJSON(t, h, "/openapi.json")
+	paths, _ := doc["paths"].(map[string]interface{})
+	if len(paths) != 1 {
+		t.Fatalf("expecting exactly one path; got %v", paths)
+	}
+	get := lookup(paths, "/v1/{name}", "get")
+	if get == nil {
+		t.Fatalf("missing GET operation: %v", paths)
+	}
+	if got := lookup(get, "operationId"); got != "things.Things.GetThing" {
+		t.Errorf("unexpected operation ID: %v", got)
+	}
+	params, _ := lookup(get, "parameters").([]interface{})
+	var names []string
+	for _, p := range params {
+		names = append(names, lookup(p, "name").(string)+"/"+lookup(p, "in").(string))
+	}
+	if !reflect.DeepEqual(names, []string{"name/path", "verbose/query"}) {
+		t.Errorf("unexpected parameters: %v", names)
+	}
+	patch := lookup(paths, "/v1/{name}", "patch")
+	if got := lookup(patch, "requestBody", "content", "application/json", "schema", "$ref"); got != "#/components/schemas/things.Thing" {
+		t.Errorf("unexpected request body schema: %v", got)
+	}
+	if got := lookup(doc, "components", "schemas", "things.Thing", "properties", "size", "pattern"); got != "^-?[0-9]+$" {
+		t.Errorf("unexpected schema for int64 field: %v", got)
+	}
+}
@@ -0,0 +1,291 @@
+package grpcui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// redactedValue replaces the values of sensitive fields and metadata.
+const redactedValue = "REDACTED"
+
+// Redactor masks sensitive values in messages and metadata, so they are not
+// shown in the web UI or written to history or logs. A field is sensitive if
+// it is marked with the option "debug_redact = true" or if it is one of the
+// fields given to NewRedactor.
+type Redactor struct {
+	fields   []string
+	metadata []string
+
+	fieldSet    map[string]bool
+	metadataSet map[string]bool
+}
+
+// NewRedactor returns a Redactor that masks the given fields and metadata, in
+// addition to fields marked with the debug_redact option. Fields are named
+// either by their fully-qualified name, like "acme.Login.password", or by
+// their name alone, like "password", which matches the field in any message.
+// Metadata names are matched ignoring case.
+func NewRedactor(fields, metadataNames []string) *Redactor {
+	r := &Redactor{
+		fields:      fields,
+		fieldSet:    make(map[string]bool, len(fields)),
+		metadataSet: make(map[string]bool, len(metadataNames)),
+	}
+	for _, f := range fields {
+		r.fieldSet[f] = true
+	}
+	for _, name := range metadataNames {
+		name = strings.ToLower(name)
+		r.metadata = append(r.metadata, name)
+		r.metadataSet[name] = true
+	}
+	return r
+}
+
+// IsSensitiveField returns true if values of the given field are masked.
+func (r *Redactor) IsSensitiveField(fd *desc.FieldDescriptor) bool {
+	return fd.GetFieldOptions().GetDebugRedact() ||
+		r.fieldSet[fd.GetFullyQualifiedName()] ||
+		r.fieldSet[fd.GetName()]
+}
+
+// IsSensitiveMetadata returns true if values of the given metadata are masked.
+func (r *Redactor) IsSensitiveMetadata(name string) bool {
+	return r.metadataSet[strings.ToLower(name)]
+}
+
+// RedactJSON masks sensitive fields in the given JSON form of a message of the
+// given type. Messages packed into google.protobuf.Any fields are masked, too,
+// as long as their types are known to the file that defines the given type or
+// to its dependencies. If js is not valid JSON, it is returned unchanged.
+func (r *Redactor) RedactJSON(md *desc.MessageDescriptor, js []byte) []byte {
+	return r.redactJSON(md, js, fileResolver(md.GetFile()))
+}
+
+// RedactRequest masks sensitive metadata and fields in the body of a request
+// to invoke the given method, in the form accepted by RPCInvokeHandler. The
+// request data may be a single message or an array of messages. If the body
+// cannot be parsed, it is returned unchanged.
+func (r *Redactor) RedactRequest(md *desc.MethodDescriptor, body []byte) []byte {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body
+	}
+	if js, ok := req["metadata"]; ok {
+		var hdrs []rpcMetadata
+		if err := json.Unmarshal(js, &hdrs); err == nil {
+			hdrs = r.redactMetadata(hdrs)
+			req["metadata"], _ = json.Marshal(hdrs)
+		}
+	}
+	if js, ok := req["data"]; ok {
+		resolve := fileResolver(md.GetFile())
+		var msgs []json.RawMessage
+		if err := json.Unmarshal(js, &msgs); err == nil {
+			for i := range msgs {
+				msgs[i] = r.redactJSON(md.GetInputType(), msgs[i], resolve)
+			}
+			req["data"], _ = json.Marshal(msgs)
+		} else {
+			req["data"] = r.redactJSON(md.GetInputType(), js, resolve)
+		}
+	}
+	redacted, err := json.Marshal(req)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+func (r *Redactor) redactMetadata(hdrs []rpcMetadata) []rpcMetadata {
+	for i := range hdrs {
+		if r.IsSensitiveMetadata(hdrs[i].Name) {
+			hdrs[i].Value = redactedValue
+		}
+	}
+	return hdrs
+}
+
+// redactJSON masks sensitive fields in the given JSON form of a message of the
+// given type. The given function resolves the types of messages packed into
+// google.protobuf.Any. If md is nil, the message is assumed to be an Any.
+//
+// If nothing is masked, js is returned as is. Otherwise, the result is
+// indented like the output of responseToJSON.
+func (r *Redactor) redactJSON(md *desc.MessageDescriptor, js []byte, resolve func(string) *desc.MessageDescriptor) []byte {
+	redacted, changed := r.redactMessage(md, js, resolve)
+	if !changed {
+		return js
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, redacted, "", "  "); err != nil {
+		return redacted
+	}
+	return buf.Bytes()
+}
+
+func (r *Redactor) redactMessage(md *desc.MessageDescriptor, js []byte, resolve func(string) *desc.MessageDescriptor) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return js, false
+	}
+	if md == nil || md.GetFullyQualifiedName() == "google.protobuf.Any" {
+		md = anyType(js, resolve)
+		if md == nil || strings.HasPrefix(md.GetFullyQualifiedName(), "google.protobuf.") {
+			// unknown types can't be masked, and well-known types have no
+			// sensitive fields
+			return js, false
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	changed := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return js, false
+		}
+		key, _ := tok.(string)
+		var val json.RawMessage
+		if err := dec.Decode(&val); err != nil {
+			return js, false
+		}
+		if fd := findField(md, key); fd != nil {
+			if r.IsSensitiveField(fd) {
+				val, changed = json.RawMessage(`"`+redactedValue+`"`), true
+			} else if fd.GetMessageType() != nil {
+				var fieldChanged bool
+				val, fieldChanged = r.redactField(fd, val, resolve)
+				changed = changed || fieldChanged
+			}
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		keyJS, _ := json.Marshal(key)
+		buf.Write(keyJS)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	if !changed {
+		return js, false
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), true
+}
+
+// redactField masks sensitive fields in the given JSON value of a field whose
+// type is a message, including repeated and map fields.
+func (r *Redactor) redactField(fd *desc.FieldDescriptor, js []byte, resolve func(string) *desc.MessageDescriptor) ([]byte, bool) {
+	md := fd.GetMessageType()
+	if fd.IsMap() {
+		md = fd.GetMapValueType().GetMessageType()
+		if md == nil {
+			return js, false
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(js, &entries); err != nil {
+			return js, false
+		}
+		changed := false
+		for k, v := range entries {
+			var entryChanged bool
+			entries[k], entryChanged = r.redactMessage(md, v, resolve)
+			changed = changed || entryChanged
+		}
+		if !changed {
+			return js, false
+		}
+		redacted, err := json.Marshal(entries)
+		if err != nil {
+			return js, false
+		}
+		return redacted, true
+	}
+	if fd.IsRepeated() {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(js, &elems); err != nil {
+			return js, false
+		}
+		changed := false
+		for i := range elems {
+			var elemChanged bool
+			elems[i], elemChanged = r.redactMessage(md, elems[i], resolve)
+			changed = changed || elemChanged
+		}
+		if !changed {
+			return js, false
+		}
+		redacted, err := json.Marshal(elems)
+		if err != nil {
+			return js, false
+		}
+		return redacted, true
+	}
+	return r.redactMessage(md, js, resolve)
+}
+
+// findField returns the field of the given message with the given JSON or
+// proto name, or nil if there is none.
+func findField(md *desc.MessageDescriptor, name string) *desc.FieldDescriptor {
+	if fd := md.FindFieldByJSONName(name); fd != nil {
+		return fd
+	}
+	return md.FindFieldByName(name)
+}
+
+// anyType returns the type of the message in the given JSON form of a
+// google.protobuf.Any, or nil if it is unknown.
+func anyType(js []byte, resolve func(string) *desc.MessageDescriptor) *desc.MessageDescriptor {
+	var packed struct {
+		Type string `json:"@type"`
+	}
+	if err := json.Unmarshal(js, &packed); err != nil || packed.Type == "" {
+		return nil
+	}
+	return resolve(packed.Type[strings.LastIndexByte(packed.Type, '/')+1:])
+}
+
+// sourceResolver returns a function that finds message types in the given
+// descriptor source.
+func sourceResolver(descSource grpcurl.DescriptorSource) func(string) *desc.MessageDescriptor {
+	return func(name string) *desc.MessageDescriptor {
+		d, err := descSource.FindSymbol(name)
+		if err != nil {
+			return nil
+		}
+		md, _ := d.(*desc.MessageDescriptor)
+		return md
+	}
+}
+
+// fileResolver returns a function that finds message types in the given file
+// and its dependencies.
+func fileResolver(fd *desc.FileDescriptor) func(string) *desc.MessageDescriptor {
+	return func(name string) *desc.MessageDescriptor {
+		seen := map[*desc.FileDescriptor]bool{}
+		var find func(*desc.FileDescriptor) *desc.MessageDescriptor
+		find = func(fd *desc.FileDescriptor) *desc.MessageDescriptor {
+			if seen[fd] {
+				return nil
+			}
+			seen[fd] = true
+			if md := fd.FindMessage(name); md != nil {
+				return md
+			}
+			for _, dep := range fd.GetDependencies() {
+				if md := find(dep); md != nil {
+					return md
+				}
+			}
+			return nil
+		}
+		return find(fd)
+	}
+}
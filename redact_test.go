@@ -0,0 +1,118 @@
+package grpcui
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+	"google.golang.org/grpc/metadata"
+)
+
+func loadRedactTestFile(t *testing.T) *desc.FileDescriptor {
+	t.Helper()
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"login.proto": `
+				syntax = "proto3";
+				package acme;
+				import "google/protobuf/any.proto";
+				message Credentials {
+					string user = 1;
+					string password = 2 [debug_redact = true];
+					string otp = 3;
+				}
+				message Login {
+					Credentials creds = 1;
+					repeated Credentials others = 2;
+					map<string, Credentials> by_name = 3;
+					string api_key = 4;
+					google.protobuf.Any extra = 5;
+				}
+				service Auth {
+					rpc LogIn(Login) returns (Login);
+				}`,
+		}),
+		LookupImport: desc.LoadFileDescriptor,
+	}
+	fds, err := p.ParseFiles("login.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	return fds[0]
+}
+
+func TestRedactor_RedactJSON(t *testing.T) {
+	fd := loadRedactTestFile(t)
+	r := NewRedactor([]string{"acme.Login.api_key", "otp"}, nil)
+	js := `{
+  "creds": {"user": "bob", "password": "secret", "otp": "123"},
+  "others": [{"user": "alice", "password": "hunter2"}],
+  "byName": {"carol": {"password": "pw"}},
+  "api_key": "k3y",
+  "extra": {"@type": "type.googleapis.com/acme.Credentials", "password": "pw2"}
+}`
+	var got map[string]interface{}
+	if err := json.Unmarshal(r.RedactJSON(fd.FindMessage("acme.Login"), []byte(js)), &got); err != nil {
+		t.Fatalf("redacted JSON is not valid: %v", err)
+	}
+	expected := map[string]interface{}{
+		"creds":   map[string]interface{}{"user": "bob", "password": "REDACTED", "otp": "REDACTED"},
+		"others":  []interface{}{map[string]interface{}{"user": "alice", "password": "REDACTED"}},
+		"byName":  map[string]interface{}{"carol": map[string]interface{}{"password": "REDACTED"}},
+		"api_key": "REDACTED",
+		"extra":   map[string]interface{}{"@type": "type.googleapis.com/acme.Credentials", "password": "REDACTED"},
+	}
+	gotJS, _ := json.Marshal(got)
+	expectedJS, _ := json.Marshal(expected)
+	if string(gotJS) != string(expectedJS) {
+		t.Errorf("unexpected redacted JSON:\n%s\nexpecting:\n%s", gotJS, expectedJS)
+	}
+
+	// messages without sensitive values are left as is
+	unchanged := `{"creds": {"user": "bob"}}`
+	if got := r.RedactJSON(fd.FindMessage("acme.Login"), []byte(unchanged)); string(got) != unchanged {
+		t.Errorf("expecting JSON to be unchanged; got %s", got)
+	}
+}
+
+func TestRedactor_RedactRequest(t *testing.T) {
+	md := loadRedactTestFile(t).FindService("acme.Auth").FindMethodByName("LogIn")
+	r := NewRedactor(nil, []string{"Authorization"})
+	for _, body := range []string{
+		`{"metadata": [{"name": "authorization", "value": "Bearer abc"}, {"name": "x-trace", "value": "1"}], "data": [{"creds": {"password": "secret"}}]}`,
+		// history items and examples may have a single message as data
+		`{"metadata": [{"name": "authorization", "value": "Bearer abc"}, {"name": "x-trace", "value": "1"}], "data": {"creds": {"password": "secret"}}, "timeout_seconds": 3}`,
+	} {
+		var got struct {
+			Metadata []rpcMetadata   `json:"metadata"`
+			Data     json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(r.RedactRequest(md, []byte(body)), &got); err != nil {
+			t.Fatalf("redacted request is not valid: %v", err)
+		}
+		if len(got.Metadata) != 2 || got.Metadata[0].Value != "REDACTED" || got.Metadata[1].Value != "1" {
+			t.Errorf("unexpected metadata: %+v", got.Metadata)
+		}
+		var data interface{}
+		_ = json.Unmarshal(got.Data, &data)
+		if arr, ok := data.([]interface{}); ok {
+			data = arr[0]
+		}
+		creds, _ := data.(map[string]interface{})["creds"].(map[string]interface{})
+		if creds["password"] != "REDACTED" {
+			t.Errorf("expecting password to be redacted; got %s", got.Data)
+		}
+	}
+	if got := r.RedactRequest(md, []byte("not json")); string(got) != "not json" {
+		t.Errorf("expecting invalid request to be unchanged; got %s", got)
+	}
+}
+
+func TestResponseMetadata_Redacted(t *testing.T) {
+	md := metadata.Pairs("authorization", "Bearer abc", "x-trace", "1")
+	hdrs := responseMetadata(md, NewRedactor(nil, []string{"AUTHORIZATION"}))
+	if len(hdrs) != 2 || hdrs[0].Value != "REDACTED" || hdrs[1].Value != "1" {
+		t.Errorf("unexpected metadata: %+v", hdrs)
+	}
+}
@@ -0,0 +1,289 @@
+package grpcui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// SampleDataOptions contains optional arguments when generating sample request
+// data.
+type SampleDataOptions struct {
+	// The seed for the random choices made when generating data. The same
+	// seed always generates the same data for a given message type.
+	Seed int64
+	// The maximum depth of nested messages. Fields of messages at this depth
+	// are only populated if they are not messages (or are required), so
+	// recursive types do not generate infinitely deep data. If zero, a
+	// default of 3 is used.
+	MaxDepth int
+}
+
+const defaultSampleDepth = 3
+
+// maxRequiredDepth is how much deeper than the maximum depth we go in order to
+// populate required message fields.
+const maxRequiredDepth = 8
+
+// SampleData returns plausible data for a message of the given type, in the
+// same JSON form used for request data by the web form. All fields are
+// populated: enums with one of their values (preferring values other than the
+// first, which is usually an "unspecified" placeholder), scalars with values
+// suggested by the field's name, repeated and map fields with one or two
+// elements, and one field of each oneof. Well-known types use their special
+// JSON formats, such as RFC 3339 strings for timestamps. So the returned value
+// is a map for most messages but may be a string or other value for
+// well-known types.
+func SampleData(md *desc.MessageDescriptor, opts SampleDataOptions) interface{} {
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultSampleDepth
+	}
+	s := sampler{rnd: rand.New(rand.NewSource(opts.Seed)), maxDepth: maxDepth}
+	return s.message(md, 0)
+}
+
+// RPCSampleDataHandler returns an HTTP handler that generates sample request
+// data for a method, using SampleData. It accepts GET requests, and the URI
+// path should name an RPC method ("/service.method"). The response is a JSON
+// payload with the request data. For client-streaming methods, it is an array
+// with a single request message.
+//
+// The "seed" query parameter can be used to make the results reproducible. If
+// absent, a random seed is used. Either way, the seed used is returned in the
+// "Grpcui-Sample-Seed" response header. The "depth" query parameter overrides
+// the maximum depth of nested messages.
+//
+// The returned handler expects to serve "/". If it will instead be handling a
+// sub-path (e.g. handling "/rpc/sample/") then use http.StripPrefix.
+func RPCSampleDataHandler(methods []*desc.MethodDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		var md *desc.MethodDescriptor
+		for _, m := range methods {
+			if m.GetFullyQualifiedName() == method {
+				md = m
+				break
+			}
+		}
+		if md == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		opts := SampleDataOptions{Seed: time.Now().UnixNano()}
+		if seed := r.URL.Query().Get("seed"); seed != "" {
+			var err error
+			if opts.Seed, err = strconv.ParseInt(seed, 10, 64); err != nil {
+				http.Error(w, "Invalid seed: "+seed, http.StatusBadRequest)
+				return
+			}
+		}
+		if depth := r.URL.Query().Get("depth"); depth != "" {
+			var err error
+			if opts.MaxDepth, err = strconv.Atoi(depth); err != nil || opts.MaxDepth < 1 {
+				http.Error(w, "Invalid depth: "+depth, http.StatusBadRequest)
+				return
+			}
+		}
+
+		data := SampleData(md.GetInputType(), opts)
+		if md.IsClientStreaming() {
+			data = []interface{}{data}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Grpcui-Sample-Seed", strconv.FormatInt(opts.Seed, 10))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(data)
+	})
+}
+
+type sampler struct {
+	rnd      *rand.Rand
+	maxDepth int
+}
+
+var sampleNames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi"}
+
+// sampleEpoch is the earliest time used for sample timestamps, so that they
+// are recent but still reproducible.
+var sampleEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (s *sampler) message(md *desc.MessageDescriptor, depth int) interface{} {
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		t := sampleEpoch.Add(time.Duration(s.rnd.Int63n(365*24*60*60)) * time.Second)
+		return t.Format(time.RFC3339)
+	case "google.protobuf.Duration":
+		return fmt.Sprintf("%ds", 1+s.rnd.Intn(3600))
+	case "google.protobuf.FieldMask":
+		// we don't know what message the mask applies to, so we use a path
+		// that is common to many messages
+		return "name"
+	case "google.protobuf.Struct":
+		return map[string]interface{}{"key": s.word()}
+	case "google.protobuf.Value":
+		return s.word()
+	case "google.protobuf.ListValue":
+		return []interface{}{s.word(), s.word()}
+	case "google.protobuf.Any":
+		return map[string]interface{}{
+			"@type": "type.googleapis.com/google.protobuf.StringValue",
+			"value": s.word(),
+		}
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		return s.scalar(md.FindFieldByName("value"))
+	}
+
+	result := map[string]interface{}{}
+	oneOfsSeen := map[*desc.OneOfDescriptor]bool{}
+	for _, fd := range md.GetFields() {
+		if ood := fd.GetOneOf(); ood != nil && !ood.IsSynthetic() {
+			if oneOfsSeen[ood] {
+				continue
+			}
+			oneOfsSeen[ood] = true
+			choices := ood.GetChoices()
+			if depth >= s.maxDepth {
+				choices = nonMessageFields(choices)
+				if len(choices) == 0 {
+					continue
+				}
+			}
+			fd = choices[s.rnd.Intn(len(choices))]
+		} else if depth >= s.maxDepth && isMessageField(fd) {
+			// required fields must still be present, but we give up if
+			// they are nested too deeply (which can only happen if the
+			// message is impossible to construct anyway)
+			if !fd.IsRequired() || depth >= s.maxDepth+maxRequiredDepth {
+				continue
+			}
+		}
+		result[fd.GetJSONName()] = s.field(fd, depth)
+	}
+	return result
+}
+
+// isMessageField returns true if the given field (or the values of the given
+// map field) is a message. Well-known types are not considered messages here,
+// since they are represented as scalars or are otherwise simple.
+func isMessageField(fd *desc.FieldDescriptor) bool {
+	if fd.IsMap() {
+		return isMessageField(fd.GetMapValueType())
+	}
+	msg := fd.GetMessageType()
+	return msg != nil && msg.GetFile().GetPackage() != "google.protobuf"
+}
+
+func nonMessageFields(fields []*desc.FieldDescriptor) []*desc.FieldDescriptor {
+	var result []*desc.FieldDescriptor
+	for _, fd := range fields {
+		if !isMessageField(fd) {
+			result = append(result, fd)
+		}
+	}
+	return result
+}
+
+func (s *sampler) field(fd *desc.FieldDescriptor, depth int) interface{} {
+	switch {
+	case fd.IsMap():
+		m := map[string]interface{}{}
+		for i := 1 + s.rnd.Intn(2); i > 0; i-- {
+			key := fmt.Sprint(s.single(fd.GetMapKeyType(), depth))
+			m[key] = s.single(fd.GetMapValueType(), depth)
+		}
+		return m
+	case fd.IsRepeated():
+		var elems []interface{}
+		for i := 1 + s.rnd.Intn(2); i > 0; i-- {
+			elems = append(elems, s.single(fd, depth))
+		}
+		return elems
+	default:
+		return s.single(fd, depth)
+	}
+}
+
+func (s *sampler) single(fd *desc.FieldDescriptor, depth int) interface{} {
+	if msg := fd.GetMessageType(); msg != nil {
+		return s.message(msg, depth+1)
+	}
+	if enum := fd.GetEnumType(); enum != nil {
+		vals := enum.GetValues()
+		if len(vals) > 1 {
+			// the first value is usually a placeholder, like "UNKNOWN"
+			vals = vals[1:]
+		}
+		return vals[s.rnd.Intn(len(vals))].GetName()
+	}
+	return s.scalar(fd)
+}
+
+func (s *sampler) scalar(fd *desc.FieldDescriptor) interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return s.stringFor(fd.GetName())
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		b := make([]byte, 4+s.rnd.Intn(5))
+		s.rnd.Read(b)
+		return base64.StdEncoding.EncodeToString(b)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return s.rnd.Intn(2) == 1
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return math.Round(s.rnd.Float64()*10000) / 100
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64, descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		// 64-bit int values are represented as strings in JSON
+		return strconv.Itoa(1 + s.rnd.Intn(100000))
+	default:
+		return 1 + s.rnd.Intn(100)
+	}
+}
+
+// stringFor returns a sample string for a field with the given name. Common
+// kinds of fields, such as email addresses and IDs, get values in the format
+// one would expect.
+func (s *sampler) stringFor(fieldName string) string {
+	name := strings.ToLower(fieldName)
+	switch {
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("%s@example.com", strings.ToLower(sampleNames[s.rnd.Intn(len(sampleNames))]))
+	case strings.Contains(name, "url") || strings.Contains(name, "uri") || strings.Contains(name, "link"):
+		return fmt.Sprintf("https://example.com/%s", s.word())
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1-555-01%02d", s.rnd.Intn(100))
+	case name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "uuid"):
+		return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", s.rnd.Uint32(), s.rnd.Intn(1<<16), 0x4000|s.rnd.Intn(1<<12), 0x8000|s.rnd.Intn(1<<14), s.rnd.Int63n(1<<48))
+	case strings.Contains(name, "name"):
+		return sampleNames[s.rnd.Intn(len(sampleNames))]
+	default:
+		return fmt.Sprintf("%s %d", fieldName, 1+s.rnd.Intn(100))
+	}
+}
+
+var sampleWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel"}
+
+func (s *sampler) word() string {
+	return sampleWords[s.rnd.Intn(len(sampleWords))]
+}
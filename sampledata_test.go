@@ -0,0 +1,98 @@
+package grpcui
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func TestSampleData_Valid(t *testing.T) {
+	for _, md := range loadTestMethods(t) {
+		descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+		if err != nil {
+			t.Fatalf("failed to create descriptor source: %v", err)
+		}
+		unmarshaler := jsonpb.Unmarshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSource(descSource)}
+		for seed := int64(0); seed < 20; seed++ {
+			for _, depth := range []int{1, 3} {
+				data := SampleData(md.GetInputType(), SampleDataOptions{Seed: seed, MaxDepth: depth})
+				js, err := json.Marshal(data)
+				if err != nil {
+					t.Fatalf("%s: failed to marshal sample data: %v", md.GetFullyQualifiedName(), err)
+				}
+				// this also verifies that all required fields are present
+				if err := unmarshaler.Unmarshal(bytes.NewReader(js), dynamic.NewMessage(md.GetInputType())); err != nil {
+					t.Errorf("%s (seed %d, depth %d): sample data is not valid: %v\n%s", md.GetFullyQualifiedName(), seed, depth, err, js)
+				}
+			}
+		}
+	}
+}
+
+func TestSampleData_Depth(t *testing.T) {
+	exchange := loadTestMethods(t)[1]
+	if exchange.GetName() != "Exchange" {
+		t.Fatalf("unexpected method: %s", exchange.GetName())
+	}
+	data := SampleData(exchange.GetInputType(), SampleDataOptions{MaxDepth: 1}).(map[string]interface{})
+	recurse, ok := data["recurse"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expecting nested message at depth 1; got %v", data["recurse"])
+	}
+	if _, ok := recurse["recurse"]; ok {
+		t.Errorf("expecting no nested message beyond max depth")
+	}
+	if _, ok := recurse["person"]; !ok {
+		t.Errorf("expecting required message field beyond max depth")
+	}
+	if _, ok := recurse["misc"]; ok {
+		t.Errorf("expecting no optional group beyond max depth")
+	}
+	if _, ok := data["wk"].(map[string]interface{})["now"].(string); !ok {
+		t.Errorf("expecting timestamp as string; got %v", data["wk"])
+	}
+}
+
+func TestRPCSampleDataHandler(t *testing.T) {
+	h := RPCSampleDataHandler(loadTestMethods(t))
+	get := func(uri string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", uri, nil))
+		return rec
+	}
+
+	first := get("/test.KitchenSink.UploadMany?seed=42")
+	if first.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", first.Code, first.Body.String())
+	}
+	if first.Header().Get("Grpcui-Sample-Seed") != "42" {
+		t.Errorf("unexpected seed header: %q", first.Header().Get("Grpcui-Sample-Seed"))
+	}
+	var data []interface{}
+	if err := json.Unmarshal(first.Body.Bytes(), &data); err != nil || len(data) != 1 {
+		t.Errorf("expecting array with one message for client-streaming method; got %s", first.Body.String())
+	}
+	if second := get("/test.KitchenSink.UploadMany?seed=42"); !reflect.DeepEqual(first.Body.String(), second.Body.String()) {
+		t.Errorf("expecting same data for same seed")
+	}
+	if other := get("/test.KitchenSink.UploadMany?seed=43"); other.Body.String() == first.Body.String() {
+		t.Errorf("expecting different data for different seed")
+	}
+
+	if rec := get("/test.KitchenSink.Nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("expecting 404 for unknown method; got %d", rec.Code)
+	}
+	if rec := get("/test.KitchenSink.Exchange?depth=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expecting 400 for invalid depth; got %d", rec.Code)
+	}
+	if rec := get("/test.KitchenSink.Exchange?seed=x"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expecting 400 for invalid seed; got %d", rec.Code)
+	}
+}
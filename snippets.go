@@ -0,0 +1,653 @@
+package grpcui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// SnippetOptions contains optional arguments when creating a handler that
+// generates client code snippets.
+type SnippetOptions struct {
+	// If true, the generated code will use plain-text connections to the
+	// target. Otherwise, it will use TLS.
+	Plaintext bool
+}
+
+// RPCSnippetHandler returns an HTTP handler that generates client code that
+// invokes an RPC with the same request data, metadata, and timeout as a
+// request in the web form. The code uses the actual names from the method's
+// descriptors, so it can be used to quickly move from an exploratory call in
+// the web UI to a working client.
+//
+// The handler accepts POST requests with JSON bodies, in the same format as
+// bodies accepted by the handler returned from RPCInvokeHandler, and returns a
+// plain text response. The URI path should name an RPC method
+// ("/service/method") and the "lang" query parameter indicates the language
+// of the generated code: "go", "python", "java", "node", or "buf" (for a
+// "buf curl" command line).
+//
+// The given target is the address of the server that the generated code will
+// connect to.
+//
+// The returned handler expects to serve "/". If it will instead be handling a
+// sub-path (e.g. handling "/rpc/snippet/") then use http.StripPrefix.
+func RPCSnippetHandler(target string, methods []*desc.MethodDescriptor, options SnippetOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "Request must be JSON", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		var md *desc.MethodDescriptor
+		for _, m := range methods {
+			if m.GetFullyQualifiedName() == method {
+				md = m
+				break
+			}
+		}
+		if md == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		js, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request", 499)
+			return
+		}
+		var input rpcInput
+		if err := json.Unmarshal(js, &input); err != nil {
+			http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sn := snippet{
+			target:    target,
+			plaintext: options.Plaintext,
+			md:        md,
+			metadata:  input.Metadata,
+			timeoutMs: int64(input.TimeoutSeconds * 1000),
+		}
+		for _, d := range input.Data {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, d, "", "  "); err != nil {
+				http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			sn.messages = append(sn.messages, buf.String())
+		}
+		if len(sn.messages) == 0 {
+			sn.messages = []string{"{}"}
+		}
+
+		var code string
+		switch lang := r.URL.Query().Get("lang"); lang {
+		case "go":
+			code = sn.golang()
+		case "python":
+			code = sn.python()
+		case "java":
+			code = sn.java()
+		case "node":
+			code = sn.node()
+		case "buf":
+			code = sn.bufCurl()
+		default:
+			http.Error(w, fmt.Sprintf("Unsupported language %q", lang), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, code)
+	})
+}
+
+type snippet struct {
+	target    string
+	plaintext bool
+	md        *desc.MethodDescriptor
+	metadata  []rpcMetadata
+	timeoutMs int64
+	messages  []string
+}
+
+// stringLiteral returns a double-quoted string literal for the given value.
+// The escaping used by JSON is valid in all languages for which we generate
+// code.
+func stringLiteral(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// indentLines adds the given prefix to all but the first line of s.
+func indentLines(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+func (sn *snippet) golang() string {
+	svc := sn.md.GetService()
+	svcPkg, svcAlias := goPackage(svc.GetFile())
+	inPkg, inAlias := goPackage(sn.md.GetInputType().GetFile())
+	imports := map[string]string{svcPkg: svcAlias, inPkg: inAlias}
+	inName := inAlias + "." + goTypeName(sn.md.GetInputType())
+	methodName := goCamelCase(sn.md.GetName())
+
+	var buf bytes.Buffer
+	buf.WriteString("package main\n\nimport (\n\t\"context\"\n")
+	if sn.md.IsServerStreaming() {
+		buf.WriteString("\t\"io\"\n")
+	}
+	buf.WriteString("\t\"log\"\n")
+	if sn.timeoutMs > 0 {
+		buf.WriteString("\t\"time\"\n")
+	}
+	buf.WriteString("\n\t\"google.golang.org/grpc\"\n")
+	if sn.plaintext {
+		buf.WriteString("\t\"google.golang.org/grpc/credentials/insecure\"\n")
+	} else {
+		buf.WriteString("\t\"google.golang.org/grpc/credentials\"\n")
+	}
+	if len(sn.metadata) > 0 {
+		buf.WriteString("\t\"google.golang.org/grpc/metadata\"\n")
+	}
+	buf.WriteString("\t\"google.golang.org/protobuf/encoding/protojson\"\n\n")
+	importPaths := make([]string, 0, len(imports))
+	for p := range imports {
+		importPaths = append(importPaths, p)
+	}
+	sort.Strings(importPaths)
+	for _, p := range importPaths {
+		fmt.Fprintf(&buf, "\t%s %q\n", imports[p], p)
+	}
+	buf.WriteString(")\n\nfunc main() {\n")
+	if sn.plaintext {
+		fmt.Fprintf(&buf, "\tconn, err := grpc.NewClient(%q, grpc.WithTransportCredentials(insecure.NewCredentials()))\n", sn.target)
+	} else {
+		fmt.Fprintf(&buf, "\tconn, err := grpc.NewClient(%q, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, \"\")))\n", sn.target)
+	}
+	buf.WriteString("\tif err != nil {\n\t\tlog.Fatalf(\"failed to create client: %v\", err)\n\t}\n\tdefer conn.Close()\n")
+	fmt.Fprintf(&buf, "\tclient := %s.New%sClient(conn)\n\n", svcAlias, goCamelCase(svc.GetName()))
+
+	buf.WriteString("\tctx := context.Background()\n")
+	if len(sn.metadata) > 0 {
+		buf.WriteString("\tctx = metadata.AppendToOutgoingContext(ctx,\n")
+		for _, m := range sn.metadata {
+			fmt.Fprintf(&buf, "\t\t%s, %s,\n", stringLiteral(m.Name), stringLiteral(m.Value))
+		}
+		buf.WriteString("\t)\n")
+	}
+	if sn.timeoutMs > 0 {
+		fmt.Fprintf(&buf, "\tctx, cancel := context.WithTimeout(ctx, %d*time.Millisecond)\n\tdefer cancel()\n", sn.timeoutMs)
+	}
+	buf.WriteString("\n")
+
+	goRequest := func(varName, data, indent string) {
+		fmt.Fprintf(&buf, "%s%s := &%s{}\n", indent, varName, inName)
+		lit := stringLiteral(data)
+		if !strings.Contains(data, "`") {
+			lit = "`" + indentLines(data, indent) + "`"
+		}
+		fmt.Fprintf(&buf, "%sif err := protojson.Unmarshal([]byte(%s), %s); err != nil {\n", indent, lit, varName)
+		fmt.Fprintf(&buf, "%s\tlog.Fatalf(\"invalid request: %%v\", err)\n%s}\n", indent, indent)
+	}
+	goRecvLoop := func() {
+		buf.WriteString("\tfor {\n\t\tresp, err := stream.Recv()\n\t\tif err == io.EOF {\n\t\t\tbreak\n\t\t}\n")
+		buf.WriteString("\t\tif err != nil {\n\t\t\tlog.Fatalf(\"RPC failed: %v\", err)\n\t\t}\n")
+		buf.WriteString("\t\tlog.Println(protojson.Format(resp))\n\t}\n")
+	}
+
+	switch {
+	case !sn.md.IsClientStreaming():
+		goRequest("req", sn.messages[0], "\t")
+		if sn.md.IsServerStreaming() {
+			fmt.Fprintf(&buf, "\tstream, err := client.%s(ctx, req)\n", methodName)
+			buf.WriteString("\tif err != nil {\n\t\tlog.Fatalf(\"RPC failed: %v\", err)\n\t}\n")
+			goRecvLoop()
+		} else {
+			fmt.Fprintf(&buf, "\tresp, err := client.%s(ctx, req)\n", methodName)
+			buf.WriteString("\tif err != nil {\n\t\tlog.Fatalf(\"RPC failed: %v\", err)\n\t}\n")
+			buf.WriteString("\tlog.Println(protojson.Format(resp))\n")
+		}
+	default:
+		fmt.Fprintf(&buf, "\tstream, err := client.%s(ctx)\n", methodName)
+		buf.WriteString("\tif err != nil {\n\t\tlog.Fatalf(\"RPC failed: %v\", err)\n\t}\n")
+		for _, m := range sn.messages {
+			buf.WriteString("\t{\n")
+			goRequest("req", m, "\t\t")
+			buf.WriteString("\t\tif err := stream.Send(req); err != nil {\n\t\t\tlog.Fatalf(\"failed to send request: %v\", err)\n\t\t}\n\t}\n")
+		}
+		if sn.md.IsServerStreaming() {
+			buf.WriteString("\tif err := stream.CloseSend(); err != nil {\n\t\tlog.Fatalf(\"failed to close stream: %v\", err)\n\t}\n")
+			goRecvLoop()
+		} else {
+			buf.WriteString("\tresp, err := stream.CloseAndRecv()\n")
+			buf.WriteString("\tif err != nil {\n\t\tlog.Fatalf(\"RPC failed: %v\", err)\n\t}\n")
+			buf.WriteString("\tlog.Println(protojson.Format(resp))\n")
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// goPackage returns the import path and package name for the Go code
+// generated for the given file.
+func goPackage(fd *desc.FileDescriptor) (importPath, pkgName string) {
+	goPkg := fd.GetFileOptions().GetGoPackage()
+	if goPkg == "" {
+		// no way to know where the generated code lives
+		pkgName = strings.ReplaceAll(fd.GetPackage(), ".", "_") + "pb"
+		return "your/module/path/to/" + pkgName, pkgName
+	}
+	if semicolon := strings.IndexByte(goPkg, ';'); semicolon >= 0 {
+		return goPkg[:semicolon], goPkg[semicolon+1:]
+	}
+	pkgName = path.Base(goPkg)
+	pkgName = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, pkgName)
+	return goPkg, pkgName
+}
+
+// goTypeName returns the name of the Go type generated for the given message.
+func goTypeName(md *desc.MessageDescriptor) string {
+	name := goCamelCase(md.GetName())
+	for parent, ok := md.GetParent().(*desc.MessageDescriptor); ok; parent, ok = parent.GetParent().(*desc.MessageDescriptor) {
+		name = goCamelCase(parent.GetName()) + "_" + name
+	}
+	return name
+}
+
+// goCamelCase converts the given proto name to the identifier that is used by
+// protoc-gen-go.
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// skip over '.' in ".{{lowercase}}"
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// skip over '_' in "_{{lowercase}}"
+		case isASCIIDigit(c):
+			b = append(b, c)
+		default:
+			if isASCIILower(c) && (i == 0 || s[i-1] == '_' || s[i-1] == '.') {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func (sn *snippet) python() string {
+	svc := sn.md.GetService()
+	svcModule := pythonModule(svc.GetFile())
+	inModule := pythonModule(sn.md.GetInputType().GetFile())
+	_, inAlias := pythonSplit(inModule)
+	inType := inAlias + "." + pythonTypeName(sn.md.GetInputType())
+	_, grpcMod := pythonSplit(svcModule + "_grpc")
+
+	var buf bytes.Buffer
+	buf.WriteString("import grpc\nfrom google.protobuf import json_format\n\n")
+	modules := map[string]bool{}
+	for _, m := range []string{svcModule, inModule, svcModule + "_grpc"} {
+		if modules[m] {
+			continue
+		}
+		modules[m] = true
+		pkg, mod := pythonSplit(m)
+		if pkg == "" {
+			fmt.Fprintf(&buf, "import %s\n", mod)
+		} else {
+			fmt.Fprintf(&buf, "from %s import %s\n", pkg, mod)
+		}
+	}
+	buf.WriteString("\n")
+	if sn.plaintext {
+		fmt.Fprintf(&buf, "channel = grpc.insecure_channel(%s)\n", stringLiteral(sn.target))
+	} else {
+		fmt.Fprintf(&buf, "channel = grpc.secure_channel(%s, grpc.ssl_channel_credentials())\n", stringLiteral(sn.target))
+	}
+	fmt.Fprintf(&buf, "stub = %s.%sStub(channel)\n\n", grpcMod, svc.GetName())
+
+	var args []string
+	if len(sn.metadata) > 0 {
+		buf.WriteString("metadata = [\n")
+		for _, m := range sn.metadata {
+			fmt.Fprintf(&buf, "    (%s, %s),\n", stringLiteral(strings.ToLower(m.Name)), stringLiteral(m.Value))
+		}
+		buf.WriteString("]\n")
+		args = append(args, "metadata=metadata")
+	}
+	if sn.timeoutMs > 0 {
+		args = append(args, fmt.Sprintf("timeout=%g", float64(sn.timeoutMs)/1000))
+	}
+	argStr := ""
+	if len(args) > 0 {
+		argStr = ", " + strings.Join(args, ", ")
+	}
+
+	if sn.md.IsClientStreaming() {
+		buf.WriteString("requests = [\n")
+		for _, m := range sn.messages {
+			fmt.Fprintf(&buf, "    json_format.Parse(%s, %s()),\n", stringLiteral(m), inType)
+		}
+		buf.WriteString("]\n")
+		if sn.md.IsServerStreaming() {
+			fmt.Fprintf(&buf, "for response in stub.%s(iter(requests)%s):\n    print(json_format.MessageToJson(response))\n", sn.md.GetName(), argStr)
+		} else {
+			fmt.Fprintf(&buf, "response = stub.%s(iter(requests)%s)\nprint(json_format.MessageToJson(response))\n", sn.md.GetName(), argStr)
+		}
+	} else {
+		fmt.Fprintf(&buf, "request = json_format.Parse(%s, %s())\n", pythonString(sn.messages[0]), inType)
+		if sn.md.IsServerStreaming() {
+			fmt.Fprintf(&buf, "for response in stub.%s(request%s):\n    print(json_format.MessageToJson(response))\n", sn.md.GetName(), argStr)
+		} else {
+			fmt.Fprintf(&buf, "response = stub.%s(request%s)\nprint(json_format.MessageToJson(response))\n", sn.md.GetName(), argStr)
+		}
+	}
+	return buf.String()
+}
+
+// pythonModule returns the fully-qualified name of the Python module that
+// protoc generates for the given file, e.g. "foo.bar.baz_pb2".
+func pythonModule(fd *desc.FileDescriptor) string {
+	name := strings.TrimSuffix(fd.GetName(), ".proto")
+	name = strings.ReplaceAll(name, "-", "_")
+	return strings.ReplaceAll(name, "/", ".") + "_pb2"
+}
+
+func pythonSplit(module string) (pkg, mod string) {
+	dot := strings.LastIndexByte(module, '.')
+	if dot < 0 {
+		return "", module
+	}
+	return module[:dot], module[dot+1:]
+}
+
+func pythonTypeName(md *desc.MessageDescriptor) string {
+	pkg := md.GetFile().GetPackage()
+	return strings.TrimPrefix(md.GetFullyQualifiedName(), pkg+".")
+}
+
+func pythonString(s string) string {
+	if !strings.Contains(s, `\`) && !strings.Contains(s, `'''`) && !strings.HasSuffix(s, "'") {
+		return "'''" + s + "'''"
+	}
+	return stringLiteral(s)
+}
+
+func (sn *snippet) java() string {
+	svc := sn.md.GetService()
+	svcPkg := javaPackage(svc.GetFile())
+	grpcClass := svc.GetName() + "Grpc"
+	inType := javaTypeName(sn.md.GetInputType())
+	outType := javaTypeName(sn.md.GetOutputType())
+	methodName := goCamelCase(sn.md.GetName())
+	methodName = strings.ToLower(methodName[:1]) + methodName[1:]
+
+	var buf bytes.Buffer
+	if svcPkg != "" {
+		fmt.Fprintf(&buf, "import %s.%s;\n", svcPkg, grpcClass)
+	} else {
+		fmt.Fprintf(&buf, "// %s is in the default package\n", grpcClass)
+	}
+	buf.WriteString("import com.google.protobuf.util.JsonFormat;\n")
+	buf.WriteString("import io.grpc.ManagedChannel;\nimport io.grpc.ManagedChannelBuilder;\n")
+	if len(sn.metadata) > 0 {
+		buf.WriteString("import io.grpc.Metadata;\nimport io.grpc.stub.MetadataUtils;\n")
+	}
+	for _, m := range sn.metadata {
+		if strings.HasSuffix(strings.ToLower(m.Name), "-bin") {
+			buf.WriteString("import java.util.Base64;\n")
+			break
+		}
+	}
+	streaming := sn.md.IsClientStreaming()
+	if streaming {
+		buf.WriteString("import io.grpc.stub.StreamObserver;\nimport java.util.concurrent.CountDownLatch;\n")
+	} else if sn.md.IsServerStreaming() {
+		buf.WriteString("import java.util.Iterator;\n")
+	}
+	buf.WriteString("import java.util.concurrent.TimeUnit;\n")
+	buf.WriteString("\npublic class GrpcClient {\n  public static void main(String[] args) throws Exception {\n")
+	fmt.Fprintf(&buf, "    ManagedChannel channel = ManagedChannelBuilder.forTarget(%s)", stringLiteral(sn.target))
+	if sn.plaintext {
+		buf.WriteString(".usePlaintext()")
+	}
+	buf.WriteString(".build();\n")
+	if len(sn.metadata) > 0 {
+		buf.WriteString("    Metadata metadata = new Metadata();\n")
+		for _, m := range sn.metadata {
+			if strings.HasSuffix(strings.ToLower(m.Name), "-bin") {
+				// binary header values are base64-encoded in the web form, but
+				// must be sent as raw bytes
+				fmt.Fprintf(&buf, "    metadata.put(Metadata.Key.of(%s, Metadata.BINARY_BYTE_MARSHALLER), Base64.getDecoder().decode(%s));\n", stringLiteral(m.Name), stringLiteral(m.Value))
+				continue
+			}
+			fmt.Fprintf(&buf, "    metadata.put(Metadata.Key.of(%s, Metadata.ASCII_STRING_MARSHALLER), %s);\n", stringLiteral(m.Name), stringLiteral(m.Value))
+		}
+	}
+
+	stubKind, stubFactory := "BlockingStub", "newBlockingStub"
+	if streaming {
+		stubKind, stubFactory = "Stub", "newStub"
+	}
+	fmt.Fprintf(&buf, "    %s.%s%s stub = %s.%s(channel)", grpcClass, svc.GetName(), stubKind, grpcClass, stubFactory)
+	if len(sn.metadata) > 0 {
+		buf.WriteString("\n        .withInterceptors(MetadataUtils.newAttachHeadersInterceptor(metadata))")
+	}
+	if sn.timeoutMs > 0 {
+		fmt.Fprintf(&buf, "\n        .withDeadlineAfter(%d, TimeUnit.MILLISECONDS)", sn.timeoutMs)
+	}
+	buf.WriteString(";\n\n")
+
+	javaRequest := func(varName, data string) {
+		fmt.Fprintf(&buf, "    %s.Builder %s = %s.newBuilder();\n", inType, varName, inType)
+		fmt.Fprintf(&buf, "    JsonFormat.parser().merge(%s, %s);\n", stringLiteral(data), varName)
+	}
+
+	switch {
+	case !streaming:
+		javaRequest("request", sn.messages[0])
+		if sn.md.IsServerStreaming() {
+			fmt.Fprintf(&buf, "    Iterator<%s> responses = stub.%s(request.build());\n", outType, methodName)
+			buf.WriteString("    while (responses.hasNext()) {\n      System.out.println(JsonFormat.printer().print(responses.next()));\n    }\n")
+		} else {
+			fmt.Fprintf(&buf, "    %s response = stub.%s(request.build());\n", outType, methodName)
+			buf.WriteString("    System.out.println(JsonFormat.printer().print(response));\n")
+		}
+	default:
+		buf.WriteString("    CountDownLatch done = new CountDownLatch(1);\n")
+		fmt.Fprintf(&buf, "    StreamObserver<%s> requests = stub.%s(new StreamObserver<%s>() {\n", inType, methodName, outType)
+		fmt.Fprintf(&buf, "      @Override public void onNext(%s response) {\n", outType)
+		buf.WriteString("        try {\n          System.out.println(JsonFormat.printer().print(response));\n")
+		buf.WriteString("        } catch (Exception e) {\n          throw new RuntimeException(e);\n        }\n      }\n")
+		buf.WriteString("      @Override public void onError(Throwable t) {\n        t.printStackTrace();\n        done.countDown();\n      }\n")
+		buf.WriteString("      @Override public void onCompleted() {\n        done.countDown();\n      }\n    });\n")
+		for i, m := range sn.messages {
+			javaRequest(fmt.Sprintf("request%d", i+1), m)
+			fmt.Fprintf(&buf, "    requests.onNext(request%d.build());\n", i+1)
+		}
+		buf.WriteString("    requests.onCompleted();\n    done.await();\n")
+	}
+	buf.WriteString("    channel.shutdown().awaitTermination(5, TimeUnit.SECONDS);\n  }\n}\n")
+	return buf.String()
+}
+
+func javaPackage(fd *desc.FileDescriptor) string {
+	if pkg := fd.GetFileOptions().GetJavaPackage(); pkg != "" {
+		return pkg
+	}
+	return fd.GetPackage()
+}
+
+// javaTypeName returns the fully-qualified name of the Java class generated
+// for the given message.
+func javaTypeName(md *desc.MessageDescriptor) string {
+	fd := md.GetFile()
+	name := strings.TrimPrefix(md.GetFullyQualifiedName(), fd.GetPackage()+".")
+	if !fd.GetFileOptions().GetJavaMultipleFiles() {
+		name = javaOuterClassName(fd) + "." + name
+	}
+	if pkg := javaPackage(fd); pkg != "" {
+		name = pkg + "." + name
+	}
+	return name
+}
+
+func javaOuterClassName(fd *desc.FileDescriptor) string {
+	if name := fd.GetFileOptions().GetJavaOuterClassname(); name != "" {
+		return name
+	}
+	base := strings.TrimSuffix(path.Base(fd.GetName()), ".proto")
+	var sb strings.Builder
+	upper := true
+	for _, r := range base {
+		switch {
+		case r == '_' || r == '-' || r == '.':
+			upper = true
+		case upper:
+			sb.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			sb.WriteRune(r)
+			upper = unicode.IsDigit(r)
+		}
+	}
+	name := sb.String()
+	// protoc adds a suffix if the name conflicts with a type in the file
+	for _, md := range fd.GetMessageTypes() {
+		if md.GetName() == name {
+			return name + "OuterClass"
+		}
+	}
+	for _, ed := range fd.GetEnumTypes() {
+		if ed.GetName() == name {
+			return name + "OuterClass"
+		}
+	}
+	for _, sd := range fd.GetServices() {
+		if sd.GetName() == name {
+			return name + "OuterClass"
+		}
+	}
+	return name
+}
+
+func (sn *snippet) node() string {
+	svc := sn.md.GetService()
+	var buf bytes.Buffer
+	buf.WriteString("const grpc = require(\"@grpc/grpc-js\");\nconst protoLoader = require(\"@grpc/proto-loader\");\n\n")
+	buf.WriteString("// includeDirs should contain the proto sources, which can be downloaded from gRPC UI\n")
+	fmt.Fprintf(&buf, "const packageDefinition = protoLoader.loadSync(%s, {\n  includeDirs: [\"protos\"],\n  keepCase: true,\n  longs: String,\n  enums: String,\n  defaults: true,\n});\n", stringLiteral(svc.GetFile().GetName()))
+	buf.WriteString("const proto = grpc.loadPackageDefinition(packageDefinition);\n")
+	creds := "grpc.credentials.createSsl()"
+	if sn.plaintext {
+		creds = "grpc.credentials.createInsecure()"
+	}
+	fmt.Fprintf(&buf, "const client = new proto.%s(%s, %s);\n\n", svc.GetFullyQualifiedName(), stringLiteral(sn.target), creds)
+
+	buf.WriteString("const metadata = new grpc.Metadata();\n")
+	for _, m := range sn.metadata {
+		fmt.Fprintf(&buf, "metadata.add(%s, %s);\n", stringLiteral(strings.ToLower(m.Name)), stringLiteral(m.Value))
+	}
+	options := "{}"
+	if sn.timeoutMs > 0 {
+		options = fmt.Sprintf("{ deadline: new Date(Date.now() + %d) }", sn.timeoutMs)
+	}
+	fmt.Fprintf(&buf, "const options = %s;\n\n", options)
+
+	callback := "(err, response) => {\n  if (err) {\n    console.error(err);\n    return;\n  }\n  console.log(JSON.stringify(response, null, 2));\n}"
+	handlers := "call.on(\"data\", (response) => console.log(JSON.stringify(response, null, 2)));\ncall.on(\"error\", (err) => console.error(err));\n"
+	switch {
+	case !sn.md.IsClientStreaming() && !sn.md.IsServerStreaming():
+		fmt.Fprintf(&buf, "const request = %s;\n", sn.messages[0])
+		fmt.Fprintf(&buf, "client.%s(request, metadata, options, %s);\n", sn.md.GetName(), callback)
+	case !sn.md.IsClientStreaming():
+		fmt.Fprintf(&buf, "const request = %s;\n", sn.messages[0])
+		fmt.Fprintf(&buf, "const call = client.%s(request, metadata, options);\n%s", sn.md.GetName(), handlers)
+	default:
+		if sn.md.IsServerStreaming() {
+			fmt.Fprintf(&buf, "const call = client.%s(metadata, options);\n%s", sn.md.GetName(), handlers)
+		} else {
+			fmt.Fprintf(&buf, "const call = client.%s(metadata, options, %s);\n", sn.md.GetName(), callback)
+		}
+		for _, m := range sn.messages {
+			fmt.Fprintf(&buf, "call.write(%s);\n", m)
+		}
+		buf.WriteString("call.end();\n")
+	}
+	return buf.String()
+}
+
+func (sn *snippet) bufCurl() string {
+	var buf bytes.Buffer
+	if sn.timeoutMs > 0 {
+		// buf curl has no flag for the RPC deadline, so we use a process timeout
+		fmt.Fprintf(&buf, "timeout %gs ", float64(sn.timeoutMs)/1000)
+	}
+	buf.WriteString("buf curl --protocol grpc")
+	scheme := "https"
+	if sn.plaintext {
+		scheme = "http"
+		buf.WriteString(" --http2-prior-knowledge")
+	}
+	buf.WriteString(" \\\n")
+	for _, m := range sn.metadata {
+		fmt.Fprintf(&buf, "  -H %s \\\n", shellQuote(m.Name+": "+m.Value))
+	}
+	var data string
+	if len(sn.messages) == 1 {
+		data = sn.messages[0]
+	} else {
+		// multiple messages in a stream are separated by whitespace
+		data = strings.Join(sn.messages, "\n")
+	}
+	fmt.Fprintf(&buf, "  -d %s \\\n", shellQuote(data))
+	fmt.Fprintf(&buf, "  %s://%s/%s/%s\n", scheme, sn.target, sn.md.GetService().GetFullyQualifiedName(), sn.md.GetName())
+	return buf.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
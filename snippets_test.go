@@ -0,0 +1,217 @@
+package grpcui
+
+import (
+	"go/format"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+)
+
+func loadSnippetMethods(t *testing.T) []*desc.MethodDescriptor {
+	t.Helper()
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"acme/things/v1/things.proto": `
+				syntax = "proto3";
+				package acme.things.v1;
+				option go_package = "example.com/acme/gen/thingspb";
+				option java_package = "com.acme.things.v1";
+				import "google/protobuf/empty.proto";
+				message Thing {
+					message Label {
+						string key = 1;
+					}
+					string name = 1;
+					repeated Label labels = 2;
+				}
+				service ThingService {
+					rpc get_thing(Thing) returns (Thing);
+					rpc WatchThings(google.protobuf.Empty) returns (stream Thing);
+					rpc UploadThings(stream Thing) returns (google.protobuf.Empty);
+					rpc SyncThings(stream Thing.Label) returns (stream Thing);
+				}`,
+		}),
+		LookupImport: desc.LoadFileDescriptor,
+	}
+	fds, err := p.ParseFiles("acme/things/v1/things.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	return AllMethodsForServices(fds[0].GetServices())
+}
+
+func serveSnippet(t *testing.T, h http.Handler, method, lang, body string) string {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/"+method+"?lang="+lang, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s %s: unexpected status %d: %s", method, lang, rec.Code, rec.Body.String())
+	}
+	return rec.Body.String()
+}
+
+func TestRPCSnippetHandler(t *testing.T) {
+	h := RPCSnippetHandler("localhost:8080", loadSnippetMethods(t), SnippetOptions{Plaintext: true})
+	body := `{"timeout_seconds": 2.5, "metadata": [{"name": "Authorization", "value": "it's me"}], "data": [{"name": "foo"}]}`
+
+	testCases := []struct {
+		method, lang string
+		expected     []string
+	}{
+		{"acme.things.v1.ThingService.get_thing", "go", []string{
+			`thingspb "example.com/acme/gen/thingspb"`,
+			`thingspb.NewThingServiceClient(conn)`,
+			`client.GetThing(ctx, req)`,
+			`"Authorization", "it's me",`,
+			`2500*time.Millisecond`,
+			`insecure.NewCredentials()`,
+		}},
+		{"acme.things.v1.ThingService.WatchThings", "go", []string{
+			`emptypb "google.golang.org/protobuf/types/known/emptypb"`,
+			`req := &emptypb.Empty{}`,
+			`stream.Recv()`,
+		}},
+		{"acme.things.v1.ThingService.SyncThings", "go", []string{
+			`req := &thingspb.Thing_Label{}`,
+			`stream.CloseSend()`,
+		}},
+		{"acme.things.v1.ThingService.get_thing", "python", []string{
+			"from acme.things.v1 import things_pb2\n",
+			"from acme.things.v1 import things_pb2_grpc\n",
+			`things_pb2_grpc.ThingServiceStub(channel)`,
+			`json_format.Parse('''{`,
+			`things_pb2.Thing()`,
+			`stub.get_thing(request, metadata=metadata, timeout=2.5)`,
+			`("authorization", "it's me")`,
+		}},
+		{"acme.things.v1.ThingService.UploadThings", "python", []string{
+			`stub.UploadThings(iter(requests), metadata=metadata, timeout=2.5)`,
+		}},
+		{"acme.things.v1.ThingService.get_thing", "java", []string{
+			`import com.acme.things.v1.ThingServiceGrpc;`,
+			`com.acme.things.v1.Things.Thing.Builder request`,
+			`stub.getThing(request.build())`,
+			`.usePlaintext()`,
+			`.withDeadlineAfter(2500, TimeUnit.MILLISECONDS)`,
+		}},
+		{"acme.things.v1.ThingService.SyncThings", "java", []string{
+			`ThingServiceGrpc.ThingServiceStub stub = ThingServiceGrpc.newStub(channel)`,
+			`StreamObserver<com.acme.things.v1.Things.Thing.Label> requests`,
+		}},
+		{"acme.things.v1.ThingService.get_thing", "node", []string{
+			`protoLoader.loadSync("acme/things/v1/things.proto"`,
+			// requests use the original proto names
+			`keepCase: true,`,
+			`new proto.acme.things.v1.ThingService("localhost:8080", grpc.credentials.createInsecure())`,
+			`metadata.add("authorization", "it's me");`,
+			`client.get_thing(request, metadata, options,`,
+		}},
+		{"acme.things.v1.ThingService.get_thing", "buf", []string{
+			`timeout 2.5s buf curl --protocol grpc --http2-prior-knowledge`,
+			`-H 'Authorization: it'\''s me'`,
+			`http://localhost:8080/acme.things.v1.ThingService/get_thing`,
+		}},
+	}
+	for _, tc := range testCases {
+		code := serveSnippet(t, h, tc.method, tc.lang, body)
+		for _, exp := range tc.expected {
+			if !strings.Contains(code, exp) {
+				t.Errorf("%s %s: snippet should contain %q:\n%s", tc.method, tc.lang, exp, code)
+			}
+		}
+	}
+}
+
+func TestRPCSnippetHandler_BinaryMetadata(t *testing.T) {
+	h := RPCSnippetHandler("localhost:8080", loadSnippetMethods(t), SnippetOptions{})
+	body := `{"metadata": [{"name": "trace-bin", "value": "AQID"}], "data": [{}]}`
+	code := serveSnippet(t, h, "acme.things.v1.ThingService.get_thing", "java", body)
+	for _, exp := range []string{
+		"import java.util.Base64;\n",
+		`metadata.put(Metadata.Key.of("trace-bin", Metadata.BINARY_BYTE_MARSHALLER), Base64.getDecoder().decode("AQID"));`,
+	} {
+		if !strings.Contains(code, exp) {
+			t.Errorf("snippet should contain %q:\n%s", exp, code)
+		}
+	}
+}
+
+func TestRPCSnippetHandler_RootProto(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"test.proto": `
+				syntax = "proto3";
+				message Hello {}
+				service Pinger {
+					rpc Ping(Hello) returns (Hello);
+				}`,
+		}),
+	}
+	fds, err := p.ParseFiles("test.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	h := RPCSnippetHandler("localhost:8080", AllMethodsForServices(fds[0].GetServices()), SnippetOptions{})
+	// a proto file without a directory is a module without a package
+	code := serveSnippet(t, h, "Pinger.Ping", "python", `{"data": [{}]}`)
+	for _, exp := range []string{"import test_pb2\n", "import test_pb2_grpc\n", "test_pb2.Hello()"} {
+		if !strings.Contains(code, exp) {
+			t.Errorf("snippet should contain %q:\n%s", exp, code)
+		}
+	}
+}
+
+func TestRPCSnippetHandler_GoIsValid(t *testing.T) {
+	methods := loadSnippetMethods(t)
+	for _, plaintext := range []bool{true, false} {
+		h := RPCSnippetHandler("localhost:8080", methods, SnippetOptions{Plaintext: plaintext})
+		for _, md := range methods {
+			code := serveSnippet(t, h, md.GetFullyQualifiedName(), "go", `{"data": [{"name": "a"}, {"name": "b"}]}`)
+			if _, err := format.Source([]byte(code)); err != nil {
+				t.Errorf("%s: generated Go code is invalid: %v\n%s", md.GetFullyQualifiedName(), err, code)
+			}
+		}
+	}
+}
+
+func TestRPCSnippetHandler_Errors(t *testing.T) {
+	h := RPCSnippetHandler("localhost:8080", loadSnippetMethods(t), SnippetOptions{})
+
+	testCases := []struct {
+		method, uri, body string
+		code              int
+	}{
+		{"POST", "/acme.things.v1.ThingService.get_thing?lang=cobol", `{}`, http.StatusBadRequest},
+		{"POST", "/acme.things.v1.ThingService.get_thing?lang=go", `{"data": [{`, http.StatusBadRequest},
+		{"POST", "/foo.Bar.Baz?lang=go", `{}`, http.StatusNotFound},
+		{"GET", "/acme.things.v1.ThingService.get_thing?lang=go", ``, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.uri, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.uri, tc.code, rec.Code)
+		}
+	}
+
+	for contentType, code := range map[string]int{
+		"application/json; charset=utf-8": http.StatusOK,
+		"text/plain":                      http.StatusUnsupportedMediaType,
+	} {
+		req := httptest.NewRequest("POST", "/acme.things.v1.ThingService.get_thing?lang=go", strings.NewReader(`{"data": [{}]}`))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Errorf("content type %q: expected status %d, got %d", contentType, code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+
+	"github.com/fullstorydev/grpcui"
+	"github.com/fullstorydev/grpcui/internal"
+)
+
+// AuditRecord describes an RPC invocation made from the web UI.
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remote_addr"`
+	// The authenticated user that invoked the RPC, if known (see
+	// WithUserHeader).
+	User   string `json:"user,omitempty"`
+	Method string `json:"method"`
+	// The name of the RPC's status code, such as "OK" or "NotFound". It is
+	// empty if the RPC could not be invoked, for example because the request
+	// was malformed, in which case HTTPStatus indicates why.
+	Code string `json:"code,omitempty"`
+	// The status of the HTTP response to the browser.
+	HTTPStatus       int     `json:"http_status"`
+	DurationMS       float64 `json:"duration_ms"`
+	RequestMessages  int     `json:"request_messages"`
+	ResponseMessages int     `json:"response_messages"`
+	// The request and response messages, as JSON arrays. These are only
+	// present if AuditOptions.IncludeBodies is set.
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// AuditSink receives a record of every RPC invocation made from the web UI.
+// Audit is called after the RPC completes, possibly from multiple goroutines
+// at once.
+type AuditSink interface {
+	Audit(rec *AuditRecord) error
+}
+
+// AuditOptions control the records given to an AuditSink.
+type AuditOptions struct {
+	// If true, records include the request and response messages, with
+	// sensitive fields masked by the redactor given via WithRedactor.
+	// Metadata is never included, since it often holds credentials.
+	IncludeBodies bool
+}
+
+// NewJSONAuditSink returns an AuditSink that writes each record to the given
+// writer as a single line of JSON. To write to a file that is rotated when it
+// gets too big, use a RotatingFileWriter.
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{w: w}
+}
+
+type jsonAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *jsonAuditSink) Audit(rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// a single write per record, so a rotating writer never splits one
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// RotatingFileWriter is an io.WriteCloser that appends to a file and, when
+// the file would grow past a maximum size, renames it and starts a new one.
+// Old files are named after the original with a numeric suffix, ".1" being
+// the most recent.
+type RotatingFileWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewRotatingFileWriter opens the given file for appending, creating it if
+// necessary. The file is rotated when a write would make it larger than
+// maxSize bytes, and at most maxBackups old files are kept. If maxSize is
+// zero or negative, it defaults to 100 MiB. If maxBackups is zero or
+// negative, it defaults to 5.
+func NewRotatingFileWriter(path string, maxSize int64, maxBackups int) (*RotatingFileWriter, error) {
+	if maxSize <= 0 {
+		maxSize = 100 << 20
+	}
+	if maxBackups <= 0 {
+		maxBackups = 5
+	}
+	w := &RotatingFileWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingFileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer. A single write is never split across files.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate %s: %v", w.path, err)
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file, and its backups, and opens a new one.
+// Must be called with w.mu held.
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+	backup := func(i int) string {
+		return fmt.Sprintf("%s.%d", w.path, i)
+	}
+	if err := os.Remove(backup(w.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := w.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, backup(1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the current file.
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// auditHandler wraps the given handler, which invokes the given methods, so
+// that each invocation is recorded in the given sink. The user is identified
+// by the given function. If the redactor is not nil, it masks the request
+// messages in records. Responses need no masking, since the invocation
+// handler masks them with the same redactor.
+func auditHandler(h http.Handler, sink AuditSink, opts AuditOptions, methods []*desc.MethodDescriptor, redactor *grpcui.Redactor, user func(*http.Request) string) http.Handler {
+	byName := make(map[string]*desc.MethodDescriptor, len(methods))
+	for _, md := range methods {
+		byName[md.GetFullyQualifiedName()] = md
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		reqBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request", 499)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(reqBody))
+		rec := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+
+		record := &AuditRecord{
+			Time:       start,
+			RemoteAddr: r.RemoteAddr,
+			User:       user(r),
+			Method:     strings.TrimPrefix(r.URL.Path, "/"),
+			HTTPStatus: rec.status,
+			DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+		}
+		if md := byName[record.Method]; md != nil && redactor != nil && opts.IncludeBodies {
+			reqBody = redactor.RedactRequest(md, reqBody)
+		}
+		var input struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		_ = json.Unmarshal(reqBody, &input)
+		if rec.status == http.StatusOK {
+			var result struct {
+				Error *struct {
+					Name string `json:"name"`
+				} `json:"error"`
+				Responses []struct {
+					Message json.RawMessage `json:"message"`
+					IsError bool            `json:"isError"`
+				} `json:"responses"`
+				Requests *struct {
+					Sent int `json:"sent"`
+				} `json:"requests"`
+			}
+			if err := json.Unmarshal(rec.body.Bytes(), &result); err == nil {
+				record.Code = "OK"
+				if result.Error != nil {
+					record.Code = result.Error.Name
+				}
+				if result.Requests != nil {
+					record.RequestMessages = result.Requests.Sent
+					input.Data = input.Data[:min(len(input.Data), result.Requests.Sent)]
+				}
+				record.ResponseMessages = len(result.Responses)
+				if opts.IncludeBodies {
+					msgs := make([]json.RawMessage, len(result.Responses))
+					for i, resp := range result.Responses {
+						msgs[i] = resp.Message
+					}
+					record.Response, _ = json.Marshal(msgs)
+				}
+			}
+		}
+		if opts.IncludeBodies && input.Data != nil {
+			record.Request, _ = json.Marshal(input.Data)
+		}
+		if err := sink.Audit(record); err != nil {
+			internal.LogErrorf("failed to write audit record for %s: %v", record.Method, err)
+		}
+	})
+}
+
+// auditResponseWriter keeps a copy of the response to an invocation, so it
+// can be summarized in an audit record.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,183 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+
+	"github.com/fullstorydev/grpcui"
+)
+
+func TestRotatingFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	w, err := NewRotatingFileWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeeeeeeeeeeeeeee\n", "ffff\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Errorf("expecting error writing to closed writer")
+	}
+
+	for file, expected := range map[string]string{
+		path:        "ffff\n",
+		path + ".1": "eeeeeeeeeeeeeeee\n",
+		path + ".2": "cccc\ndddd\n",
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Errorf("failed to read %s: %v", file, err)
+		} else if string(data) != expected {
+			t.Errorf("unexpected contents of %s: %q", file, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expecting only 2 backups to be kept")
+	}
+
+	// re-opening appends to the existing file
+	w, err = NewRotatingFileWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("failed to re-open writer: %v", err)
+	}
+	_, _ = w.Write([]byte("gggggg\n"))
+	_ = w.Close()
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "ffff\n" {
+		t.Errorf("expecting rotation based on existing size; got %q", data)
+	}
+}
+
+type testAuditSink []*AuditRecord
+
+func (s *testAuditSink) Audit(rec *AuditRecord) error {
+	*s = append(*s, rec)
+	return nil
+}
+
+func TestAuditHandler(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"login.proto": `
+				syntax = "proto3";
+				package acme;
+				message Login {
+					string user = 1;
+					string password = 2 [debug_redact = true];
+					string otp = 3;
+				}
+				service Auth {
+					rpc LogIn(Login) returns (Login);
+				}`,
+		}),
+	}
+	fds, err := p.ParseFiles("login.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	methods := grpcui.AllMethodsForServices(fds[0].GetServices())
+	invoke := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acme.Auth.LogIn" {
+			http.NotFound(w, r)
+			return
+		}
+		var input struct{ Data []json.RawMessage }
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the invocation handler is given the real values
+		if !bytes.Contains(input.Data[0], []byte("hunter2")) {
+			http.Error(w, "Password is masked", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"headers": [], "error": {"code": 5, "name": "NotFound", "message": "nope"},
+			"responses": [{"message": {"user": "x", "password": "REDACTED"}, "isError": false}],
+			"requests": {"total": 1, "sent": 1}, "trailers": []}`))
+	})
+	redactor := grpcui.NewRedactor([]string{"otp"}, nil)
+
+	serve := func(opts AuditOptions, uri, body string) (*AuditRecord, *httptest.ResponseRecorder) {
+		var sink testAuditSink
+		h := auditHandler(invoke, &sink, opts, methods, redactor, func(r *http.Request) string {
+			return r.Header.Get("x-user")
+		})
+		req := httptest.NewRequest("POST", uri, strings.NewReader(body))
+		req.Header.Set("x-user", "alice")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if len(sink) != 1 {
+			t.Fatalf("expecting 1 audit record; got %d", len(sink))
+		}
+		return sink[0], w
+	}
+
+	body := `{"metadata": [{"name": "authorization", "value": "x"}], "data": [{"user": "x", "password": "hunter2", "otp": "123456"}]}`
+	rec, w := serve(AuditOptions{}, "/acme.Auth.LogIn", body)
+	if !strings.Contains(w.Body.String(), `"NotFound"`) {
+		t.Errorf("expecting response to be passed through; got %s", w.Body.String())
+	}
+	if rec.User != "alice" || rec.Method != "acme.Auth.LogIn" || rec.Code != "NotFound" || rec.HTTPStatus != http.StatusOK ||
+		rec.RequestMessages != 1 || rec.ResponseMessages != 1 || rec.RemoteAddr == "" || rec.Time.IsZero() {
+		t.Errorf("unexpected audit record: %+v", rec)
+	}
+	if rec.Request != nil || rec.Response != nil {
+		t.Errorf("expecting no bodies in audit record; got %s and %s", rec.Request, rec.Response)
+	}
+
+	// debug_redact fields and fields given to the redactor are masked
+	rec, w = serve(AuditOptions{IncludeBodies: true}, "/acme.Auth.LogIn", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+	if string(rec.Request) != `[{"user":"x","password":"REDACTED","otp":"REDACTED"}]` {
+		t.Errorf("unexpected request in audit record: %s", rec.Request)
+	}
+	if string(rec.Response) != `[{"user":"x","password":"REDACTED"}]` {
+		t.Errorf("unexpected response in audit record: %s", rec.Response)
+	}
+
+	rec, _ = serve(AuditOptions{IncludeBodies: true}, "/acme.Auth.LogOut", body)
+	if rec.HTTPStatus != http.StatusNotFound || rec.Code != "" || rec.Response != nil {
+		t.Errorf("unexpected audit record for unknown method: %+v", rec)
+	}
+	rec, _ = serve(AuditOptions{}, "/acme.Auth.LogIn", "{")
+	if rec.HTTPStatus != http.StatusBadRequest || rec.Code != "" {
+		t.Errorf("unexpected audit record for bad request: %+v", rec)
+	}
+}
+
+func TestJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONAuditSink(&buf)
+	for _, method := range []string{"a/B", "c/D"} {
+		if err := sink.Audit(&AuditRecord{Method: method, Code: "OK", HTTPStatus: 200}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expecting 2 lines; got %q", buf.String())
+	}
+	var rec AuditRecord
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatalf("failed to parse record: %v", err)
+	}
+	if rec.Method != "c/D" || rec.Code != "OK" {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+}
@@ -0,0 +1,454 @@
+package standalone
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fullstorydev/grpcui/internal"
+	"github.com/fullstorydev/grpcui/internal/resources/standalone"
+)
+
+const sessionCookieName = "_grpcui_session"
+
+// sessionDuration is how long a user stays logged in after logging in via
+// the login page.
+const sessionDuration = 12 * time.Hour
+
+// Authenticator verifies the identity of users of the web UI. When a Handler
+// is configured with authenticators, every request must be authenticated by
+// one of them, or by a session cookie issued by the login page.
+type Authenticator interface {
+	// Authenticate returns the user that sent the given request, based on
+	// credentials in the request. It returns false if the request has no
+	// credentials that this authenticator accepts.
+	Authenticate(r *http.Request) (user string, ok bool)
+}
+
+// PasswordAuthenticator is an Authenticator whose credentials can also be
+// entered in the login page, so that users can log in from a browser.
+type PasswordAuthenticator interface {
+	Authenticator
+	// Login returns the user for the given name and password, as entered in
+	// the login page. It returns false if they are not valid.
+	Login(name, password string) (user string, ok bool)
+}
+
+// NewHtpasswdAuthenticator returns an authenticator for users in the given
+// htpasswd-style file. Each line of the file has a user name and a password
+// hash, separated by a colon. Hashes created by "htpasswd -m" (the default
+// on most systems, which start with "$apr1$") and by "htpasswd -s" (which
+// start with "{SHA}") are supported. Requests are authenticated via HTTP
+// basic auth, and users can also log in via the login page.
+//
+// The file is read once, when this function is called.
+func NewHtpasswdAuthenticator(path string) (PasswordAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expecting \"user:hash\"", path, lineNum)
+		}
+		if !strings.HasPrefix(hash, "$apr1$") && !strings.HasPrefix(hash, "{SHA}") {
+			return nil, fmt.Errorf("%s:%d: unsupported password hash for user %q; use \"htpasswd -m\" or \"htpasswd -s\" to create it", path, lineNum, user)
+		}
+		users[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return htpasswdAuthenticator(users), nil
+}
+
+// htpasswdAuthenticator maps user names to password hashes.
+type htpasswdAuthenticator map[string]string
+
+func (a htpasswdAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	return a.Login(name, password)
+}
+
+func (a htpasswdAuthenticator) Login(name, password string) (string, bool) {
+	hash, ok := a[name]
+	if !ok {
+		return "", false
+	}
+	var computed string
+	if strings.HasPrefix(hash, "{SHA}") {
+		sum := sha1.Sum([]byte(password))
+		computed = "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	} else {
+		salt := strings.TrimPrefix(hash, "$apr1$")
+		if i := strings.IndexByte(salt, '$'); i >= 0 {
+			salt = salt[:i]
+		}
+		computed = apr1(password, salt)
+	}
+	if subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) != 1 {
+		return "", false
+	}
+	return name, true
+}
+
+// apr1 returns the Apache variant of the MD5-based crypt(3) hash of the given
+// password, which is what "htpasswd -m" produces.
+func apr1(password, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alt := md5.Sum([]byte(password + salt + password))
+	ctx := md5.New()
+	ctx.Write([]byte(password + magic + salt))
+	for i := len(pw); i > 0; i -= 16 {
+		ctx.Write(alt[:min(i, 16)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(pw[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+	for i := 0; i < 1000; i++ {
+		ctx := md5.New()
+		if i&1 != 0 {
+			ctx.Write(pw)
+		} else {
+			ctx.Write(final)
+		}
+		if i%3 != 0 {
+			ctx.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			ctx.Write(pw)
+		}
+		if i&1 != 0 {
+			ctx.Write(final)
+		} else {
+			ctx.Write(pw)
+		}
+		final = ctx.Sum(nil)
+	}
+
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	var sb strings.Builder
+	sb.WriteString(magic + salt + "$")
+	to64 := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			sb.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, i := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		to64(uint32(final[i[0]])<<16|uint32(final[i[1]])<<8|uint32(final[i[2]]), 4)
+	}
+	to64(uint32(final[11]), 2)
+	return sb.String()
+}
+
+// NewTokenAuthenticator returns an authenticator for the given static bearer
+// tokens, which map each token to the user it identifies. Requests are
+// authenticated via an "Authorization: Bearer <token>" header, and users can
+// also log in via the login page by entering a token as the password.
+func NewTokenAuthenticator(tokens map[string]string) PasswordAuthenticator {
+	a := tokenAuthenticator{}
+	for token, user := range tokens {
+		a[sha256.Sum256([]byte(token))] = user
+	}
+	return a
+}
+
+// tokenAuthenticator maps the SHA-256 hashes of tokens to users. Hashing the
+// tokens means that looking one up does not leak its contents via timing.
+type tokenAuthenticator map[[sha256.Size]byte]string
+
+func (a tokenAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	return a.Login("", strings.TrimSpace(auth[7:]))
+}
+
+func (a tokenAuthenticator) Login(name, password string) (string, bool) {
+	user, ok := a[sha256.Sum256([]byte(password))]
+	if !ok || (name != "" && name != user) {
+		return "", false
+	}
+	return user, true
+}
+
+// NewProxyHeaderAuthenticator returns an authenticator that trusts the given
+// header, set by an authenticating reverse proxy, to identify the user. The
+// header is only trusted in requests from the given networks, which should
+// be those of the proxy. If none are given, only requests from loopback
+// addresses are trusted. Requests received over a Unix domain socket are
+// always trusted, since the socket's permissions restrict who can connect.
+func NewProxyHeaderAuthenticator(header string, trusted ...*net.IPNet) Authenticator {
+	if len(trusted) == 0 {
+		trusted = []*net.IPNet{
+			{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+			{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+		}
+	}
+	return &proxyHeaderAuthenticator{header: header, trusted: trusted}
+}
+
+type proxyHeaderAuthenticator struct {
+	header  string
+	trusted []*net.IPNet
+}
+
+func (a *proxyHeaderAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	user := r.Header.Get(a.header)
+	if user == "" {
+		return "", false
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return user, true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", false
+	}
+	for _, n := range a.trusted {
+		if n.Contains(ip) {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+// NewClientCertAuthenticator returns an authenticator that identifies users
+// by the TLS client certificate they present. The user is the certificate's
+// subject common name or, if it has none, its first email address. Only
+// certificates verified by the server are accepted, so the server's TLS
+// config must have ClientCAs and a ClientAuth of at least
+// tls.VerifyClientCertIfGiven.
+func NewClientCertAuthenticator() Authenticator {
+	return clientCertAuthenticator{}
+}
+
+type clientCertAuthenticator struct{}
+
+func (clientCertAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, true
+	}
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0], true
+	}
+	return "", false
+}
+
+// authUserKey is the context key for the authenticated user of a request.
+type authUserKey struct{}
+
+// authenticatedUser returns the user that was authenticated for the given
+// request, if any.
+func authenticatedUser(r *http.Request) (string, bool) {
+	user, ok := r.Context().Value(authUserKey{}).(string)
+	return user, ok
+}
+
+var loginTemplate = template.Must(template.New("login.html").Parse(string(standalone.LoginTemplate())))
+
+// loginTemplateData is the data used to render the login page.
+type loginTemplateData struct {
+	Error     string
+	CSRFToken string
+	// Whether users can log in with a token.
+	Tokens bool
+}
+
+// authHandler wraps the given handler so that only authenticated users can
+// use it. It also serves the login page at "/login", if any of the given
+// authenticators accept passwords, and ends sessions via POST requests to
+// "/logout". Unauthenticated requests get a 401 response that challenges
+// clients to use HTTP basic auth or a bearer token, for whichever of these the
+// given authenticators accept.
+func authHandler(h http.Handler, auths []Authenticator) http.Handler {
+	var logins []PasswordAuthenticator
+	basic, tokens := false, false
+	for _, a := range auths {
+		if l, ok := a.(PasswordAuthenticator); ok {
+			logins = append(logins, l)
+		}
+		switch a.(type) {
+		case htpasswdAuthenticator:
+			basic = true
+		case tokenAuthenticator:
+			tokens = true
+		}
+	}
+	var sessionKey [32]byte
+	if _, err := rand.Read(sessionKey[:]); err != nil {
+		panic(err)
+	}
+	sessionMAC := func(user string, expiry int64) []byte {
+		mac := hmac.New(sha256.New, sessionKey[:])
+		fmt.Fprintf(mac, "%s\n%d", user, expiry)
+		return mac.Sum(nil)
+	}
+	sessionUser := func(r *http.Request) (string, bool) {
+		c, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			return "", false
+		}
+		parts := strings.Split(c.Value, ".")
+		if len(parts) != 3 {
+			return "", false
+		}
+		user, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+		expiry, err2 := strconv.ParseInt(parts[1], 10, 64)
+		mac, err3 := base64.RawURLEncoding.DecodeString(parts[2])
+		if err1 != nil || err2 != nil || err3 != nil || time.Now().Unix() > expiry {
+			return "", false
+		}
+		if !hmac.Equal(mac, sessionMAC(string(user), expiry)) {
+			return "", false
+		}
+		return string(user), true
+	}
+
+	renderLogin := func(w http.ResponseWriter, r *http.Request, status int, errMsg string) {
+		var token string
+		if c, err := r.Cookie(csrfCookieName); err == nil {
+			token = c.Value
+		}
+		var buf bytes.Buffer
+		if err := loginTemplate.Execute(&buf, loginTemplateData{Error: errMsg, CSRFToken: token, Tokens: tokens}); err != nil {
+			http.Error(w, "Failed to render login page: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_, _ = w.Write(buf.Bytes())
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if len(logins) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			switch r.Method {
+			case "GET":
+				renderLogin(w, r, http.StatusOK, "")
+			case "POST":
+				c, _ := r.Cookie(csrfCookieName)
+				if c == nil || c.Value == "" || c.Value != r.PostFormValue("csrf_token") {
+					renderLogin(w, r, http.StatusUnauthorized, "Your session has expired. Please try again.")
+					return
+				}
+				name, password := r.PostFormValue("user"), r.PostFormValue("password")
+				for _, l := range logins {
+					if user, ok := l.Login(name, password); ok {
+						expiry := time.Now().Add(sessionDuration).Unix()
+						http.SetCookie(w, &http.Cookie{
+							Name: sessionCookieName,
+							Value: base64.RawURLEncoding.EncodeToString([]byte(user)) + "." +
+								strconv.FormatInt(expiry, 10) + "." +
+								base64.RawURLEncoding.EncodeToString(sessionMAC(user, expiry)),
+							Expires:  time.Unix(expiry, 0),
+							HttpOnly: true,
+							Secure:   r.TLS != nil,
+							SameSite: http.SameSiteLaxMode,
+						})
+						internal.LogInfof("user %q logged in from %s", user, r.RemoteAddr)
+						seeOther(w, "./")
+						return
+					}
+				}
+				renderLogin(w, r, http.StatusUnauthorized, "Incorrect user name or password.")
+			default:
+				w.Header().Set("Allow", "GET, POST")
+				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		case "/logout":
+			if r.Method != "POST" {
+				w.Header().Set("Allow", "POST")
+				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if !checkCSRFToken(w, r) {
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: sessionCookieName, MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		user, ok := sessionUser(r)
+		for i := 0; !ok && i < len(auths); i++ {
+			user, ok = auths[i].Authenticate(r)
+		}
+		if !ok {
+			if r.URL.Path == "/" && r.Method == "GET" && len(logins) > 0 {
+				seeOther(w, "login")
+				return
+			}
+			if basic {
+				w.Header().Add("WWW-Authenticate", `Basic realm="grpcui", charset="UTF-8"`)
+			}
+			if tokens {
+				w.Header().Add("WWW-Authenticate", `Bearer realm="grpcui"`)
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authUserKey{}, user)))
+	})
+}
+
+// seeOther redirects to the given URI, relative to the request's URI. Unlike
+// http.Redirect, it leaves the URI relative, so that it works when the
+// handler serves a sub-path via http.StripPrefix.
+func seeOther(w http.ResponseWriter, uri string) {
+	w.Header().Set("Location", uri)
+	w.WriteHeader(http.StatusSeeOther)
+}
@@ -0,0 +1,261 @@
+package standalone
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApr1(t *testing.T) {
+	// expected values computed with "openssl passwd -apr1"
+	for _, tc := range []struct{ password, salt, hash string }{
+		{"secret", "r31lQ8qj", "$apr1$r31lQ8qj$RE/MDhDsEQmZirrHHfy9s0"},
+		{"a much longer password here!", "abc", "$apr1$abc$174tqhGlZK6nz3lDkGw3t0"},
+	} {
+		if got := apr1(tc.password, tc.salt); got != tc.hash {
+			t.Errorf("apr1(%q, %q) = %q; expecting %q", tc.password, tc.salt, got, tc.hash)
+		}
+	}
+}
+
+func writeHtpasswd(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	return path
+}
+
+func TestHtpasswdAuthenticator(t *testing.T) {
+	a, err := NewHtpasswdAuthenticator(writeHtpasswd(t, "# users\nalice:$apr1$r31lQ8qj$RE/MDhDsEQmZirrHHfy9s0\n\nbob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"))
+	if err != nil {
+		t.Fatalf("failed to load file: %v", err)
+	}
+	for _, tc := range []struct {
+		name, password string
+		ok             bool
+	}{
+		{"alice", "secret", true},
+		{"bob", "secret", true},
+		{"alice", "Secret", false},
+		{"carol", "secret", false},
+		{"", "", false},
+	} {
+		if user, ok := a.Login(tc.name, tc.password); ok != tc.ok || (ok && user != tc.name) {
+			t.Errorf("Login(%q, %q) = %q, %v", tc.name, tc.password, user, ok)
+		}
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("bob", "secret")
+	if user, ok := a.Authenticate(req); !ok || user != "bob" {
+		t.Errorf("expecting bob to be authenticated via basic auth; got %q, %v", user, ok)
+	}
+
+	for _, contents := range []string{"alice\n", "alice:$2y$05$abcdefghijklmnopqrstuv\n", ":{SHA}x\n"} {
+		if _, err := NewHtpasswdAuthenticator(writeHtpasswd(t, contents)); err == nil {
+			t.Errorf("expecting error for %q", contents)
+		}
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	a := NewTokenAuthenticator(map[string]string{"t0ken": "alice"})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "bearer t0ken")
+	if user, ok := a.Authenticate(req); !ok || user != "alice" {
+		t.Errorf("expecting alice to be authenticated via token; got %q, %v", user, ok)
+	}
+	req.Header.Set("Authorization", "Bearer nope")
+	if _, ok := a.Authenticate(req); ok {
+		t.Errorf("expecting unknown token to be rejected")
+	}
+	if _, ok := a.Login("bob", "t0ken"); ok {
+		t.Errorf("expecting token login with another user's name to be rejected")
+	}
+	if user, ok := a.Login("", "t0ken"); !ok || user != "alice" {
+		t.Errorf("expecting token login to succeed; got %q, %v", user, ok)
+	}
+}
+
+func TestProxyHeaderAuthenticator(t *testing.T) {
+	_, proxyNet, _ := net.ParseCIDR("10.1.0.0/16")
+	for _, tc := range []struct {
+		a          Authenticator
+		remoteAddr string
+		ok         bool
+	}{
+		{NewProxyHeaderAuthenticator("x-user"), "127.0.0.1:1234", true},
+		{NewProxyHeaderAuthenticator("x-user"), "[::1]:1234", true},
+		{NewProxyHeaderAuthenticator("x-user"), "10.1.2.3:1234", false},
+		{NewProxyHeaderAuthenticator("x-user", proxyNet), "10.1.2.3:1234", true},
+		{NewProxyHeaderAuthenticator("x-user", proxyNet), "127.0.0.1:1234", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		req.Header.Set("x-user", "alice")
+		if user, ok := tc.a.Authenticate(req); ok != tc.ok || (ok && user != "alice") {
+			t.Errorf("unexpected result for request from %s: %q, %v", tc.remoteAddr, user, ok)
+		}
+	}
+
+	// requests over a Unix domain socket have no remote address
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "@"
+	req.Header.Set("x-user", "alice")
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "/tmp/grpcui.sock", Net: "unix"}))
+	if user, ok := NewProxyHeaderAuthenticator("x-user", proxyNet).Authenticate(req); !ok || user != "alice" {
+		t.Errorf("expecting request over Unix domain socket to be trusted; got %q, %v", user, ok)
+	}
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	a := NewClientCertAuthenticator()
+	for _, tc := range []struct {
+		name  string
+		state *tls.ConnectionState
+		user  string
+	}{
+		{"no TLS", nil, ""},
+		{"no certificate", &tls.ConnectionState{}, ""},
+		{"unverified certificate", &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "mallory"}}},
+		}, ""},
+		{"common name", &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "alice"}, EmailAddresses: []string{"a@example.com"}}}},
+		}, "alice"},
+		{"email address", &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{EmailAddresses: []string{"bob@example.com"}}}},
+		}, "bob@example.com"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.TLS = tc.state
+		if user, ok := a.Authenticate(req); ok != (tc.user != "") || user != tc.user {
+			t.Errorf("%s: unexpected result: %q, %v", tc.name, user, ok)
+		}
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	opts := &handlerOptions{userHeader: "x-user"}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello, " + opts.user(r)))
+	})
+	h := authHandler(inner, []Authenticator{
+		NewTokenAuthenticator(map[string]string{"t0ken": "alice"}),
+		NewProxyHeaderAuthenticator("x-proxy-user"),
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "csrf"})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "login" {
+		t.Errorf("expecting redirect to login page; got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := serve(httptest.NewRequest("POST", "/invoke/foo", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 for unauthenticated request; got %d", w.Code)
+	} else if challenges := w.Header().Values("WWW-Authenticate"); len(challenges) != 1 || challenges[0] != `Bearer realm="grpcui"` {
+		t.Errorf("expecting challenge for bearer token; got %q", challenges)
+	}
+	w = serve(httptest.NewRequest("GET", "/login", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `value="csrf"`) {
+		t.Errorf("expecting login page with CSRF token; got %d %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	req.Header.Set("x-user", "mallory")
+	if w := serve(req); w.Body.String() != "hello, alice" {
+		t.Errorf("expecting authenticated user to take precedence over user header; got %q", w.Body.String())
+	}
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("x-proxy-user", "bob")
+	req.RemoteAddr = "127.0.0.1:1234"
+	if w := serve(req); w.Body.String() != "hello, bob" {
+		t.Errorf("expecting user from proxy header; got %q", w.Body.String())
+	}
+
+	login := func(password, csrf string) *httptest.ResponseRecorder {
+		form := url.Values{"user": {""}, "password": {password}, "csrf_token": {csrf}}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(req)
+	}
+	if w := login("t0ken", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 for login with wrong CSRF token; got %d", w.Code)
+	}
+	if w := login("nope", "csrf"); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Incorrect") {
+		t.Errorf("expecting status 401 for wrong password; got %d", w.Code)
+	}
+	w = login("t0ken", "csrf")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "./" {
+		t.Fatalf("expecting redirect after login; got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly || session.Secure {
+		t.Fatalf("expecting HTTP-only session cookie, not marked Secure without TLS; got %+v", session)
+	}
+	form := url.Values{"user": {""}, "password": {"t0ken"}, "csrf_token": {"csrf"}}
+	req = httptest.NewRequest("POST", "https://example.com/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c := serve(req).Result().Cookies(); len(c) != 1 || !c[0].Secure {
+		t.Errorf("expecting Secure session cookie with TLS; got %+v", c)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(session)
+	if w := serve(req); w.Body.String() != "hello, alice" {
+		t.Errorf("expecting session to authenticate alice; got %d %q", w.Code, w.Body.String())
+	}
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: strings.Replace(session.Value, "YWxpY2U", "Ym9i", 1)})
+	if w := serve(req); w.Code != http.StatusSeeOther {
+		t.Errorf("expecting tampered session to be rejected; got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/logout", nil)
+	req.Header.Set(csrfHeaderName, "csrf")
+	w = serve(req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expecting status 204 for logout; got %d", w.Code)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].Name != sessionCookieName || c[0].MaxAge >= 0 {
+		t.Errorf("expecting session cookie to be cleared; got %+v", c)
+	}
+
+	// without password authenticators, there is no login page
+	h = authHandler(inner, []Authenticator{NewProxyHeaderAuthenticator("x-proxy-user")})
+	if w := serve(httptest.NewRequest("GET", "/", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 without login page; got %d", w.Code)
+	} else if challenges := w.Header().Values("WWW-Authenticate"); len(challenges) != 0 {
+		t.Errorf("expecting no challenge without basic or token auth; got %q", challenges)
+	}
+	if w := serve(httptest.NewRequest("GET", "/login", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("expecting status 404 for login page; got %d", w.Code)
+	}
+
+	h = authHandler(inner, []Authenticator{htpasswdAuthenticator{}, NewTokenAuthenticator(nil)})
+	if w := serve(httptest.NewRequest("GET", "/metadata", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 for unauthenticated request; got %d", w.Code)
+	} else if challenges := w.Header().Values("WWW-Authenticate"); len(challenges) != 2 || !strings.HasPrefix(challenges[0], "Basic ") || !strings.HasPrefix(challenges[1], "Bearer ") {
+		t.Errorf("expecting challenges for basic auth and bearer token; got %q", challenges)
+	}
+}
@@ -0,0 +1,200 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+)
+
+// AuthzPolicy decides which methods each user of the web UI may invoke.
+// Methods that a user may not invoke are left out of their web form, and
+// requests to invoke them are rejected with "403 Forbidden".
+//
+// Methods are identified by patterns, matched against names of the form
+// "package.Service/Method" as with path.Match, so "acme.Orders/Get*" matches
+// all methods of the acme.Orders service whose names start with "Get". A
+// pattern without a slash matches the name of the service, so "acme.*"
+// matches all methods of all services in the acme package.
+type AuthzPolicy struct {
+	// Roles maps user names to their roles, which can be used in rules.
+	Roles map[string][]string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Rules are checked in order. The first rule that applies to the user
+	// and whose Allow or Deny patterns match the method decides whether the
+	// user may invoke it.
+	Rules []AuthzRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// If true, methods that no rule matches are denied. Otherwise, they are
+	// allowed.
+	DenyByDefault bool `json:"deny_by_default,omitempty" yaml:"deny_by_default,omitempty"`
+	// If true, only methods whose idempotency_level option is NO_SIDE_EFFECTS,
+	// or that match ReadOnlyAllow, may be invoked, regardless of the rules.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	// Patterns of methods that may be invoked in read-only mode even though
+	// they are not marked as having no side effects.
+	ReadOnlyAllow []string `json:"read_only_allow,omitempty" yaml:"read_only_allow,omitempty"`
+}
+
+// AuthzRule is a rule in an AuthzPolicy. It applies to the given users and
+// to users with any of the given roles. If it has neither, it applies to all
+// users.
+type AuthzRule struct {
+	Users []string `json:"users,omitempty" yaml:"users,omitempty"`
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Patterns of methods the rule allows.
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	// Patterns of methods the rule denies. These take precedence over the
+	// rule's Allow patterns.
+	Deny []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// LoadAuthzPolicy reads a policy from the given file. Files with a ".yaml" or
+// ".yml" extension are parsed as YAML; all others as JSON.
+func LoadAuthzPolicy(file string) (*AuthzPolicy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var policy AuthzPolicy
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&policy)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return &policy, nil
+}
+
+// Validate checks that all patterns in the policy are well-formed.
+func (p *AuthzPolicy) Validate() error {
+	check := func(patterns []string, where string) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q", where, pattern)
+			}
+		}
+		return nil
+	}
+	for i, rule := range p.Rules {
+		where := fmt.Sprintf("rule #%d", i+1)
+		if len(rule.Allow) == 0 && len(rule.Deny) == 0 {
+			return fmt.Errorf("%s: must allow or deny some methods", where)
+		}
+		if err := check(rule.Allow, where); err != nil {
+			return err
+		}
+		if err := check(rule.Deny, where); err != nil {
+			return err
+		}
+	}
+	return check(p.ReadOnlyAllow, "read_only_allow")
+}
+
+// Allowed returns true if the given user may invoke the given method.
+func (p *AuthzPolicy) Allowed(user string, md *desc.MethodDescriptor) bool {
+	name := md.GetService().GetFullyQualifiedName() + "/" + md.GetName()
+	if p.ReadOnly && !hasNoSideEffects(md) && !matchesMethod(p.ReadOnlyAllow, name) {
+		return false
+	}
+	roles := map[string]bool{}
+	for _, role := range p.Roles[user] {
+		roles[role] = true
+	}
+	for _, rule := range p.Rules {
+		if !rule.appliesTo(user, roles) {
+			continue
+		}
+		if matchesMethod(rule.Deny, name) {
+			return false
+		}
+		if matchesMethod(rule.Allow, name) {
+			return true
+		}
+	}
+	return !p.DenyByDefault
+}
+
+func (r *AuthzRule) appliesTo(user string, roles map[string]bool) bool {
+	if len(r.Users) == 0 && len(r.Roles) == 0 {
+		return true
+	}
+	for _, u := range r.Users {
+		if u == user {
+			return true
+		}
+	}
+	for _, role := range r.Roles {
+		if roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMethod returns true if any of the given patterns matches the given
+// method name, which has the form "package.Service/Method".
+func matchesMethod(patterns []string, name string) bool {
+	service := name[:strings.IndexByte(name, '/')]
+	for _, pattern := range patterns {
+		subject := name
+		if !strings.Contains(pattern, "/") {
+			subject = service
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNoSideEffects returns true if the given method is marked with the option
+// "idempotency_level = NO_SIDE_EFFECTS".
+func hasNoSideEffects(md *desc.MethodDescriptor) bool {
+	return md.GetMethodOptions().GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+}
+
+// allowedMethods returns the subset of the given methods that the given user
+// may invoke.
+func (p *AuthzPolicy) allowedMethods(user string, methods []*desc.MethodDescriptor) []*desc.MethodDescriptor {
+	var allowed []*desc.MethodDescriptor
+	for _, md := range methods {
+		if p.Allowed(user, md) {
+			allowed = append(allowed, md)
+		}
+	}
+	return allowed
+}
+
+// authzHandler wraps the given handler, which invokes RPCs, so that requests
+// to invoke methods the user may not invoke are rejected.
+func authzHandler(h http.Handler, policy *AuthzPolicy, methods []*desc.MethodDescriptor, user func(*http.Request) string) http.Handler {
+	byName := make(map[string]*desc.MethodDescriptor, len(methods))
+	for _, md := range methods {
+		byName[md.GetFullyQualifiedName()] = md
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		md := byName[strings.TrimPrefix(r.URL.Path, "/")]
+		if md != nil && !policy.Allowed(user(r), md) {
+			http.Error(w, fmt.Sprintf("Forbidden: you may not invoke %s", md.GetFullyQualifiedName()), http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
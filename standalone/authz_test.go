@@ -0,0 +1,201 @@
+package standalone
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+
+	"github.com/fullstorydev/grpcui"
+)
+
+func loadOrderMethods(t *testing.T) []*desc.MethodDescriptor {
+	t.Helper()
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"orders.proto": `
+				syntax = "proto3";
+				package acme;
+				message Order {
+					string id = 1;
+				}
+				service Orders {
+					rpc GetOrder(Order) returns (Order) {
+						option idempotency_level = NO_SIDE_EFFECTS;
+					}
+					rpc ListOrders(Order) returns (stream Order);
+					rpc DeleteOrder(Order) returns (Order) {
+						option idempotency_level = IDEMPOTENT;
+					}
+				}
+				service Admin {
+					rpc Reset(Order) returns (Order);
+				}`,
+		}),
+	}
+	fds, err := p.ParseFiles("orders.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	return grpcui.AllMethodsForServices(fds[0].GetServices())
+}
+
+func allowedNames(p *AuthzPolicy, user string, methods []*desc.MethodDescriptor) string {
+	var names []string
+	for _, md := range p.allowedMethods(user, methods) {
+		names = append(names, md.GetName())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestAuthzPolicy(t *testing.T) {
+	methods := loadOrderMethods(t)
+	policy := &AuthzPolicy{
+		Roles: map[string][]string{"alice": {"support"}, "root": {"admin"}},
+		Rules: []AuthzRule{
+			{Roles: []string{"admin"}, Allow: []string{"*"}},
+			{Users: []string{"bob"}, Deny: []string{"acme.Orders/Delete*"}, Allow: []string{"acme.Orders"}},
+			{Roles: []string{"support"}, Allow: []string{"acme.Orders/Get*", "acme.Orders/List*"}},
+			{Deny: []string{"acme.Admin"}},
+		},
+		DenyByDefault: true,
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for user, expected := range map[string]string{
+		"root":  "GetOrder,ListOrders,DeleteOrder,Reset",
+		"bob":   "GetOrder,ListOrders",
+		"alice": "GetOrder,ListOrders",
+		"":      "",
+	} {
+		if got := allowedNames(policy, user, methods); got != expected {
+			t.Errorf("unexpected methods for %q: %s", user, got)
+		}
+	}
+	policy.DenyByDefault = false
+	if got := allowedNames(policy, "", methods); got != "GetOrder,ListOrders,DeleteOrder" {
+		t.Errorf("unexpected methods when allowed by default: %s", got)
+	}
+
+	policy.ReadOnly = true
+	policy.ReadOnlyAllow = []string{"acme.Orders/List*"}
+	if got := allowedNames(policy, "root", methods); got != "GetOrder,ListOrders" {
+		t.Errorf("unexpected methods in read-only mode: %s", got)
+	}
+
+	for _, bad := range []*AuthzPolicy{
+		{Rules: []AuthzRule{{Users: []string{"bob"}}}},
+		{Rules: []AuthzRule{{Allow: []string{"acme.[Orders"}}}},
+		{ReadOnlyAllow: []string{"["}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expecting error for policy %+v", bad)
+		}
+	}
+}
+
+func TestLoadAuthzPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return file
+	}
+	policy, err := LoadAuthzPolicy(write("policy.yaml", `
+roles:
+  alice: [support]
+deny_by_default: true
+rules:
+  - roles: [support]
+    allow: ["acme.Orders/Get*"]
+`))
+	if err != nil {
+		t.Fatalf("failed to load YAML policy: %v", err)
+	}
+	if got := allowedNames(policy, "alice", loadOrderMethods(t)); got != "GetOrder" {
+		t.Errorf("unexpected methods: %s", got)
+	}
+	if _, err := LoadAuthzPolicy(write("policy.json", `{"read_only": true, "read_only_allow": ["acme.Admin"]}`)); err != nil {
+		t.Errorf("failed to load JSON policy: %v", err)
+	}
+	if _, err := LoadAuthzPolicy(write("typo.json", `{"rules": [{"alow": ["*"]}]}`)); err == nil {
+		t.Errorf("expecting error for unknown field")
+	}
+	if _, err := LoadAuthzPolicy(write("typo.yml", "readonly: true\n")); err == nil {
+		t.Errorf("expecting error for unknown field in YAML")
+	}
+}
+
+func TestAuthzHandler(t *testing.T) {
+	methods := loadOrderMethods(t)
+	policy := &AuthzPolicy{ReadOnly: true}
+	invoked := false
+	h := authzHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked = true
+	}), policy, methods, func(*http.Request) string { return "" })
+
+	for _, tc := range []struct {
+		method string
+		code   int
+	}{
+		{"acme.Orders.GetOrder", http.StatusOK},
+		{"acme.Orders.DeleteOrder", http.StatusForbidden},
+		// unknown methods are left to the wrapped handler
+		{"acme.Orders.Nope", http.StatusOK},
+	} {
+		invoked = false
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/"+tc.method, nil))
+		if w.Code != tc.code || invoked != (tc.code == http.StatusOK) {
+			t.Errorf("unexpected result for %s: %d, invoked=%v", tc.method, w.Code, invoked)
+		}
+	}
+}
+
+func TestHandlerHidesForbiddenMethods(t *testing.T) {
+	methods := loadOrderMethods(t)
+	policy := &AuthzPolicy{Rules: []AuthzRule{{Users: []string{"bob"}, Deny: []string{"acme.Admin"}}}}
+	h := Handler(nil, "target", methods, nil, WithAuthzPolicy(policy), WithUserHeader("x-user"))
+	page := func(user string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("x-user", user)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	if body := page("alice"); !strings.Contains(body, "acme.Admin") {
+		t.Errorf("expecting acme.Admin in form for alice")
+	}
+	if body := page("bob"); strings.Contains(body, "acme.Admin") || !strings.Contains(body, "acme.Orders") {
+		t.Errorf("expecting only acme.Orders in form for bob")
+	}
+}
+
+func TestWithProtectedTarget(t *testing.T) {
+	opt, err := WithProtectedTarget([]string{"acme.Orders/DeleteOrder"}, []string{"acme.Admin/Reset"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var opts handlerOptions
+	opt.apply(&opts)
+	var names []string
+	for _, md := range loadOrderMethods(t) {
+		if opts.confirmMethod(md) {
+			names = append(names, md.GetName())
+		}
+	}
+	if got := strings.Join(names, ","); got != "ListOrders,DeleteOrder" {
+		t.Errorf("unexpected methods requiring confirmation: %s", got)
+	}
+	if _, err := WithProtectedTarget(nil, []string{"acme.[Admin"}); err == nil {
+		t.Errorf("expecting error for invalid pattern")
+	}
+}
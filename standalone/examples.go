@@ -15,6 +15,9 @@ type Example struct {
 	Service     string         `json:"service"`
 	Method      string         `json:"method"`
 	Request     ExampleRequest `json:"request"`
+	// The expected outcome of the request, used when running examples as
+	// tests. See RunExample.
+	Expect *ExampleExpectation `json:"expect,omitempty"`
 }
 
 // ExampleMetadataPair (name, value) pair
@@ -49,6 +52,25 @@ func (r *ExampleRequest) MarshalJSON() ([]byte, error) {
 	return json.Marshal(jsonRequest)
 }
 
+func (r *ExampleRequest) UnmarshalJSON(data []byte) error {
+	// history saved from the web form uses "timeout_seconds" instead of
+	// "timeout_secs", so we accept both
+	type jsReq ExampleRequest
+	jsonRequest := struct {
+		*jsReq
+		TimeoutSecondsAlias *float64 `json:"timeout_seconds"`
+	}{
+		jsReq: (*jsReq)(r),
+	}
+	if err := json.Unmarshal(data, &jsonRequest); err != nil {
+		return err
+	}
+	if jsonRequest.TimeoutSecondsAlias != nil && r.TimeoutSeconds == 0 {
+		r.TimeoutSeconds = *jsonRequest.TimeoutSecondsAlias
+	}
+	return nil
+}
+
 func marshalData(data interface{}) (json.RawMessage, error) {
 	switch data := data.(type) {
 	case protov2.Message:
@@ -0,0 +1,234 @@
+package standalone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// ErrExampleExists is returned by ExampleStore.SaveExample when the store
+// already has an example with the same name for the same method.
+var ErrExampleExists = errors.New("an example with that name already exists for the method")
+
+// ExampleStore is a writable collection of examples. When a Handler is
+// configured with a store, users can save the request in the web form as a
+// new example, which is then shown to all users of the UI.
+type ExampleStore interface {
+	// Examples returns all examples in the store, grouped by service and
+	// method.
+	Examples() ([]Example, error)
+	// SaveExample adds the given example to the store. If the store already
+	// has an example with the same name for the same method, it returns
+	// ErrExampleExists.
+	SaveExample(ex Example) error
+}
+
+// NewFileExampleStore returns an ExampleStore that reads and writes examples
+// at the given path, which may be a JSON file or a directory. Examples are
+// read from the path using LoadExamples, so the path may be the same one used
+// with the "-examples" flag of grpcui.
+//
+// When the path is a directory, saved examples are written to a JSON file
+// named after the method, in a sub-directory named after the service (e.g.
+// "<path>/my.package.Service/Method.json"). Otherwise, saved examples are
+// appended to the file. Since files are re-written when an example is saved,
+// YAML files (which could lose comments and formatting) are never written,
+// and it is an error to use a YAML file as the path.
+//
+// If the path does not exist, it is created when the first example is saved:
+// as a file if it has a ".json" extension, otherwise as a directory.
+func NewFileExampleStore(path string) (ExampleStore, error) {
+	var isDir bool
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		isDir = info.IsDir()
+	case os.IsNotExist(err):
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json", ".yaml", ".yml":
+		default:
+			isDir = true
+		}
+	default:
+		return nil, err
+	}
+	if !isDir {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			return nil, fmt.Errorf("%s: cannot save examples to a YAML file; use a JSON file or a directory", path)
+		}
+	}
+	return &fileExampleStore{path: path, isDir: isDir}, nil
+}
+
+type fileExampleStore struct {
+	path  string
+	isDir bool
+	mu    sync.Mutex
+}
+
+func (s *fileExampleStore) Examples() ([]Example, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	examples, err := LoadExamples(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return examples, err
+}
+
+func (s *fileExampleStore) SaveExample(ex Example) error {
+	if ex.Service == "" || ex.Method == "" {
+		return errors.New("example must indicate service and method")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// check all examples, not just those in the file we write, since the
+	// directory may have other files with examples for the same method
+	existing, err := LoadExamples(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, other := range existing {
+		if other.Service == ex.Service && other.Method == ex.Method && other.Name == ex.Name {
+			return ErrExampleExists
+		}
+	}
+
+	file := s.path
+	if s.isDir {
+		// service and method names are identifiers, but since they come from
+		// users we make sure they cannot be used to escape the directory
+		for _, name := range []string{ex.Service, ex.Method} {
+			if strings.ContainsAny(name, `/\`) || strings.Trim(name, ".") == "" {
+				return fmt.Errorf("invalid service or method name: %q", name)
+			}
+		}
+		file = filepath.Join(s.path, ex.Service, ex.Method+".json")
+	}
+
+	var examples []Example
+	if _, err := os.Stat(file); err == nil {
+		if examples, err = loadExampleFile(file); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	examples = append(examples, ex)
+
+	data, err := json.MarshalIndent(examples, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode examples to json: %v", err)
+	}
+	return writeFileAtomically(file, append(data, '\n'), 0644)
+}
+
+// examplesHandler returns a handler that serves the given static examples,
+// followed by those in the given store, in response to GET requests. POST
+// requests save the example in the request body to the store, after checking
+// it against the given methods. Examples are seen by every user, so values of
+// metadata that likely holds credentials are masked before they are saved.
+func examplesHandler(static []byte, store ExampleStore, methods []*desc.MethodDescriptor) http.Handler {
+	var staticExamples []Example
+	if len(static) > 0 {
+		// WithExampleData and WithExamples already verified this is valid
+		_ = json.Unmarshal(static, &staticExamples)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			stored, err := store.Examples()
+			if err != nil {
+				http.Error(w, "Failed to load examples: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			examples := append(append([]Example{}, staticExamples...), stored...)
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(examples)
+		case "POST":
+			if !checkCSRFToken(w, r) {
+				return
+			}
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				http.Error(w, "Request must be JSON", http.StatusUnsupportedMediaType)
+				return
+			}
+			var ex Example
+			if err := json.NewDecoder(r.Body).Decode(&ex); err != nil {
+				http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			ex.Name = strings.TrimSpace(ex.Name)
+			if ex.Name == "" {
+				http.Error(w, "Example must have a name", http.StatusBadRequest)
+				return
+			}
+			if errs := ValidateExamples([]Example{ex}, methods); len(errs) > 0 {
+				http.Error(w, errs[0].Err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			for i, pair := range ex.Request.Metadata {
+				if isSecretMetadata(pair.Name) {
+					ex.Request.Metadata[i].Value = "REDACTED"
+				}
+			}
+			if err := store.SaveExample(ex); err != nil {
+				if errors.Is(err, ErrExampleExists) {
+					http.Error(w, err.Error(), http.StatusConflict)
+				} else {
+					http.Error(w, "Failed to save example: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(&ex)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// writeFileAtomically writes data to a temporary file and then renames it, so
+// that readers never see a partially written file. The file gets the given
+// permissions. Missing parent directories are created, with search
+// permission added wherever the file's permissions allow reading, so a file
+// only its owner may read ends up in directories only its owner may enter.
+func writeFileAtomically(file string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, perm|(perm&0444)>>2); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
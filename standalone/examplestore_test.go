@@ -0,0 +1,170 @@
+package standalone
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileExampleStore_Directory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"greet/hello.yaml": `
+service: greet.Greeter
+method: SayHello
+examples:
+  - name: bob
+    request:
+      data: {name: bob}
+`,
+	})
+	store, err := NewFileExampleStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	ex := Example{
+		Name:    "alice",
+		Service: "greet.Greeter",
+		Method:  "SayHello",
+		Request: ExampleRequest{Data: map[string]interface{}{"name": "alice"}},
+	}
+	if err := store.SaveExample(ex); err != nil {
+		t.Fatalf("failed to save example: %v", err)
+	}
+	ex.Request.TimeoutSeconds = 3
+	if err := store.SaveExample(ex); !errors.Is(err, ErrExampleExists) {
+		t.Errorf("expecting ErrExampleExists for duplicate name; got %v", err)
+	}
+	ex.Name = "bob"
+	if err := store.SaveExample(ex); !errors.Is(err, ErrExampleExists) {
+		t.Errorf("expecting ErrExampleExists for name in another file; got %v", err)
+	}
+	ex.Name = "carol"
+	if err := store.SaveExample(ex); err != nil {
+		t.Fatalf("failed to save example: %v", err)
+	}
+
+	saved, err := LoadExamples(filepath.Join(dir, "greet.Greeter", "SayHello.json"))
+	if err != nil {
+		t.Fatalf("failed to load saved examples: %v", err)
+	}
+	if len(saved) != 2 || saved[0].Name != "alice" || saved[1].Name != "carol" || saved[1].Request.TimeoutSeconds != 3 {
+		t.Errorf("unexpected saved examples: %+v", saved)
+	}
+
+	all, err := store.Examples()
+	if err != nil {
+		t.Fatalf("failed to load examples: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expecting 3 examples; got %d", len(all))
+	}
+
+	ex.Service = "../greet.Greeter"
+	if err := store.SaveExample(ex); err == nil {
+		t.Errorf("expecting error for service name that is a path")
+	}
+}
+
+func TestFileExampleStore_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "examples.json")
+	store, err := NewFileExampleStore(file)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if examples, err := store.Examples(); err != nil || len(examples) != 0 {
+		t.Errorf("expecting no examples before file exists; got %v, %v", examples, err)
+	}
+	for _, name := range []string{"one", "two"} {
+		ex := Example{Name: name, Service: "greet.Greeter", Method: "SayHello", Request: ExampleRequest{Data: map[string]interface{}{}}}
+		if err := store.SaveExample(ex); err != nil {
+			t.Fatalf("failed to save example: %v", err)
+		}
+	}
+	examples, err := LoadExamples(file)
+	if err != nil {
+		t.Fatalf("failed to load saved examples: %v", err)
+	}
+	if len(examples) != 2 {
+		t.Errorf("expecting 2 examples; got %d", len(examples))
+	}
+
+	if _, err := NewFileExampleStore(filepath.Join(t.TempDir(), "examples.yaml")); err == nil {
+		t.Errorf("expecting error for YAML file")
+	}
+}
+
+func TestExamplesHandler(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileExampleStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	static := []byte(`[{"name": "static", "service": "greet.Greeter", "method": "SayHello", "request": {"data": {}}}]`)
+	h := examplesHandler(static, store, loadGreeterMethods(t))
+
+	post := func(body string, csrf bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/examples", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if csrf {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "token"})
+			req.Header.Set(csrfHeaderName, "token")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	ok := `{"name": "saved", "description": "from the UI", "service": "greet.Greeter", "method": "SayHellos",
+		"request": {"timeout_seconds": 2, "metadata": [{"name": "a", "value": "b"}, {"name": "Authorization", "value": "Bearer s3cret"}], "data": [{"name": "x"}]}}`
+	testCases := []struct {
+		body   string
+		csrf   bool
+		status int
+	}{
+		{ok, false, http.StatusUnauthorized},
+		{`{"name": `, true, http.StatusBadRequest},
+		{`{"service": "greet.Greeter", "method": "SayHello", "request": {"data": {}}}`, true, http.StatusBadRequest},
+		{`{"name": "x", "service": "greet.Greeter", "method": "SayGoodbye", "request": {"data": {}}}`, true, http.StatusUnprocessableEntity},
+		{`{"name": "x", "service": "greet.Greeter", "method": "SayHello", "request": {"data": {"nope": 1}}}`, true, http.StatusUnprocessableEntity},
+		{ok, true, http.StatusOK},
+		{ok, true, http.StatusConflict},
+	}
+	for i, tc := range testCases {
+		if w := post(tc.body, tc.csrf); w.Code != tc.status {
+			t.Errorf("case %d: expecting status %d; got %d: %s", i, tc.status, w.Code, w.Body.String())
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "greet.Greeter", "SayHellos.json")); err != nil {
+		t.Errorf("example was not written to expected file: %v", err)
+	} else if strings.Contains(string(data), "s3cret") {
+		t.Errorf("expecting credentials to be masked in saved example: %s", data)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/examples", nil))
+	var examples []Example
+	if err := json.Unmarshal(w.Body.Bytes(), &examples); err != nil {
+		t.Fatalf("failed to parse examples: %v", err)
+	}
+	if len(examples) != 2 || examples[0].Name != "static" || examples[1].Name != "saved" {
+		t.Fatalf("unexpected examples: %+v", examples)
+	}
+	saved := examples[1]
+	if saved.Description != "from the UI" || saved.Request.TimeoutSeconds != 2 || len(saved.Request.Metadata) != 2 {
+		t.Errorf("unexpected saved example: %+v", saved)
+	} else if md := saved.Request.Metadata; md[0].Value != "b" || md[1].Value != "REDACTED" {
+		t.Errorf("expecting only credentials to be masked in saved example; got %+v", md)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/examples", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expecting status 405 for DELETE; got %d", w.Code)
+	}
+}
@@ -0,0 +1,368 @@
+package standalone
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+
+	"github.com/fullstorydev/grpcui"
+)
+
+// ErrHistoryNotFound is returned by HistoryStore.DeleteHistory when the store
+// has no item with the given ID for the given user.
+var ErrHistoryNotFound = errors.New("history item not found")
+
+// HistoryItem is a past invocation from the web UI. Its JSON form is the same
+// as the items the web form keeps in the browser's local storage when there is
+// no store.
+type HistoryItem struct {
+	// A unique ID, assigned by the store.
+	ID string `json:"id"`
+	// The user that made the invocation, if known. See WithUserHeader.
+	User       string    `json:"user,omitempty"`
+	Service    string    `json:"service"`
+	Method     string    `json:"method"`
+	StartTime  time.Time `json:"startTime"`
+	DurationMS float64   `json:"durationMS"`
+	// The request, in the same form as the request of an Example. It is kept
+	// as JSON since the store does not interpret it, other than for searches.
+	Request json.RawMessage `json:"request"`
+	// A summary of the response, if one was received.
+	ResponseData json.RawMessage `json:"responseData,omitempty"`
+	// A description of the failure if the web form could not get a response
+	// from the server that hosts the UI.
+	FailureStatus string `json:"failureStatus,omitempty"`
+}
+
+// HistoryQuery selects items from a HistoryStore.
+type HistoryQuery struct {
+	// Only items for this user are returned. If empty, only items not
+	// associated with any user are returned.
+	User string
+	// If non-empty, only items whose method name or request contain this text
+	// (ignoring case) are returned.
+	Search string
+	// The maximum number of items to return. If zero, all matching items are
+	// returned.
+	Limit int
+}
+
+// HistoryStore keeps the history of invocations made from the web UI on the
+// server, so that it is available across browsers. When a Handler is
+// configured with a store, the web form uses it instead of local storage.
+type HistoryStore interface {
+	// History returns the items that match the given query, most recent
+	// first.
+	History(q HistoryQuery) ([]HistoryItem, error)
+	// AddHistory adds the given item to the store and returns it with its ID
+	// assigned. The store may evict older items to make room.
+	AddHistory(item HistoryItem) (HistoryItem, error)
+	// DeleteHistory removes the item with the given ID for the given user. If
+	// there is no such item, it returns ErrHistoryNotFound.
+	DeleteHistory(user, id string) error
+	// ClearHistory removes all items for the given user.
+	ClearHistory(user string) error
+}
+
+// NewFileHistoryStore returns a HistoryStore that keeps history in the given
+// JSON file. The file is read when the store is created and re-written every
+// time the history changes. At most maxItems items are kept for each user,
+// evicting the oldest ones; if maxItems is zero, 1000 items are kept.
+//
+// If the file does not exist, it is created when the first item is added.
+// Since history holds users' requests, including their metadata, the file and
+// any directories created for it are only accessible to their owner.
+func NewFileHistoryStore(path string, maxItems int) (HistoryStore, error) {
+	if maxItems <= 0 {
+		maxItems = 1000
+	}
+	s := &fileHistoryStore{path: path, maxItems: maxItems}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.items); err != nil {
+			return nil, fmt.Errorf("%s: failed to parse history: %v", path, err)
+		}
+	}
+	return s, nil
+}
+
+type fileHistoryStore struct {
+	path     string
+	maxItems int
+	mu       sync.Mutex
+	// most recent first
+	items []HistoryItem
+}
+
+func (s *fileHistoryStore) History(q HistoryQuery) ([]HistoryItem, error) {
+	search := strings.ToLower(q.Search)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []HistoryItem
+	for _, item := range s.items {
+		if item.User != q.User {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(item.Service+"."+item.Method), search) &&
+			!strings.Contains(strings.ToLower(string(item.Request)), search) {
+			continue
+		}
+		result = append(result, item)
+		if len(result) == q.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *fileHistoryStore) AddHistory(item HistoryItem) (HistoryItem, error) {
+	if item.Service == "" || item.Method == "" {
+		return item, errors.New("history item must indicate service and method")
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return item, err
+	}
+	item.ID = hex.EncodeToString(id[:])
+	if item.StartTime.IsZero() {
+		item.StartTime = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]HistoryItem, 0, len(s.items)+1)
+	items = append(items, item)
+	count := 1
+	for _, other := range s.items {
+		if other.User == item.User {
+			if count == s.maxItems {
+				// evict the oldest item for this user
+				continue
+			}
+			count++
+		}
+		items = append(items, other)
+	}
+	if err := s.save(items); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+func (s *fileHistoryStore) DeleteHistory(user, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, item := range s.items {
+		if item.User == user && item.ID == id {
+			items := append(append([]HistoryItem{}, s.items[:i]...), s.items[i+1:]...)
+			return s.save(items)
+		}
+	}
+	return ErrHistoryNotFound
+}
+
+func (s *fileHistoryStore) ClearHistory(user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []HistoryItem
+	for _, item := range s.items {
+		if item.User != user {
+			items = append(items, item)
+		}
+	}
+	return s.save(items)
+}
+
+// save writes the given items to the file and, if successful, makes them the
+// store's items. Must be called with s.mu held.
+func (s *fileHistoryStore) save(items []HistoryItem) error {
+	if items == nil {
+		items = []HistoryItem{}
+	}
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history to json: %v", err)
+	}
+	if err := writeFileAtomically(s.path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	s.items = items
+	return nil
+}
+
+// historyRedactor returns a function that masks sensitive metadata and fields
+// in the requests of history items for the given methods. Responses need no
+// masking, since the web form records them as returned by the invocation
+// handler, which masks them with the same redactor.
+func historyRedactor(redactor *grpcui.Redactor, methods []*desc.MethodDescriptor) func(*HistoryItem) {
+	byName := make(map[string]*desc.MethodDescriptor, len(methods))
+	for _, md := range methods {
+		byName[md.GetFullyQualifiedName()] = md
+	}
+	return func(item *HistoryItem) {
+		if md := byName[item.Service+"."+item.Method]; md != nil && len(item.Request) > 0 {
+			item.Request = redactor.RedactRequest(md, item.Request)
+		}
+	}
+}
+
+// maskSecretMetadata masks the values of metadata that likely holds
+// credentials in the given request, in the same form as the request of an
+// Example. If the request can't be parsed, it is returned unchanged.
+func maskSecretMetadata(request json.RawMessage) json.RawMessage {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(request, &req); err != nil {
+		return request
+	}
+	var md []ExampleMetadataPair
+	if err := json.Unmarshal(req["metadata"], &md); err != nil {
+		return request
+	}
+	changed := false
+	for i := range md {
+		if isSecretMetadata(md[i].Name) {
+			md[i].Value, changed = "REDACTED", true
+		}
+	}
+	if !changed {
+		return request
+	}
+	req["metadata"], _ = json.Marshal(md)
+	masked, err := json.Marshal(req)
+	if err != nil {
+		return request
+	}
+	return masked
+}
+
+// sharedHistoryForbidden is the error message for attempts to delete history
+// that is shared by all users.
+const sharedHistoryForbidden = "History is shared by all users, so it can't be deleted"
+
+// historyHandler returns a handler for the history in the given store. It
+// expects to serve both "/history" and "/history/". GET requests to the
+// former list the history, POST requests add an item, and DELETE requests
+// clear the history. DELETE requests to "/history/<id>" remove a single item.
+// All operations are scoped to the user returned by the given function. If
+// redact is non-nil, it is called to mask sensitive values in items before
+// they are added. Items without a user are seen by everyone, so metadata in
+// them that likely holds credentials is masked, too. For the same reason,
+// DELETE requests without a user are refused, so that one visitor can't wipe
+// out the history shared by all of them.
+func historyHandler(store HistoryStore, user func(*http.Request) string, redact func(*HistoryItem)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/history"), "/")
+		if id != "" {
+			if r.Method != "DELETE" {
+				w.Header().Set("Allow", "DELETE")
+				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if !checkCSRFToken(w, r) {
+				return
+			}
+			if user(r) == "" {
+				http.Error(w, sharedHistoryForbidden, http.StatusForbidden)
+				return
+			}
+			if err := store.DeleteHistory(user(r), id); err != nil {
+				if errors.Is(err, ErrHistoryNotFound) {
+					http.NotFound(w, r)
+				} else {
+					http.Error(w, "Failed to delete history: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			q := HistoryQuery{User: user(r), Search: r.URL.Query().Get("q")}
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				var err error
+				if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+					http.Error(w, "Invalid limit: "+limit, http.StatusBadRequest)
+					return
+				}
+			}
+			items, err := store.History(q)
+			if err != nil {
+				http.Error(w, "Failed to load history: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if items == nil {
+				items = []HistoryItem{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(items)
+		case "POST":
+			if !checkCSRFToken(w, r) {
+				return
+			}
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				http.Error(w, "Request must be JSON", http.StatusUnsupportedMediaType)
+				return
+			}
+			var item HistoryItem
+			if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+				http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if item.Service == "" || item.Method == "" {
+				http.Error(w, "History item must indicate service and method", http.StatusBadRequest)
+				return
+			}
+			item.User = user(r)
+			if redact != nil {
+				redact(&item)
+			}
+			if item.User == "" {
+				item.Request = maskSecretMetadata(item.Request)
+			}
+			item, err := store.AddHistory(item)
+			if err != nil {
+				http.Error(w, "Failed to save history: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(&item)
+		case "DELETE":
+			if !checkCSRFToken(w, r) {
+				return
+			}
+			if user(r) == "" {
+				http.Error(w, sharedHistoryForbidden, http.StatusForbidden)
+				return
+			}
+			if err := store.ClearHistory(user(r)); err != nil {
+				http.Error(w, "Failed to clear history: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
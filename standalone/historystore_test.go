@@ -0,0 +1,232 @@
+package standalone
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcui"
+)
+
+func TestFileHistoryStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "grpcui")
+	file := filepath.Join(dir, "history.json")
+	store, err := NewFileHistoryStore(file, 3)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	add := func(user, method, request string) HistoryItem {
+		item, err := store.AddHistory(HistoryItem{User: user, Service: "greet.Greeter", Method: method, Request: json.RawMessage(request)})
+		if err != nil {
+			t.Fatalf("failed to add history: %v", err)
+		}
+		if item.ID == "" || item.StartTime.IsZero() {
+			t.Fatalf("added item is missing ID or start time: %+v", item)
+		}
+		return item
+	}
+	names := func(q HistoryQuery) string {
+		items, err := store.History(q)
+		if err != nil {
+			t.Fatalf("failed to query history: %v", err)
+		}
+		var names []string
+		for _, item := range items {
+			var req struct {
+				Data struct{ Name string }
+			}
+			_ = json.Unmarshal(item.Request, &req)
+			names = append(names, req.Data.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		add("", "SayHello", `{"data": {"name": "`+name+`"}}`)
+	}
+	bobItem := add("bob", "SayHellos", `{"data": {"name": "bob"}}`)
+
+	// oldest item for the anonymous user was evicted, but bob's item did not
+	// cause another eviction
+	if got := names(HistoryQuery{}); got != "d,c,b" {
+		t.Errorf("unexpected history: %s", got)
+	}
+	if got := names(HistoryQuery{User: "bob"}); got != "bob" {
+		t.Errorf("unexpected history for bob: %s", got)
+	}
+	if got := names(HistoryQuery{Limit: 2}); got != "d,c" {
+		t.Errorf("unexpected history with limit: %s", got)
+	}
+	if got := names(HistoryQuery{Search: `"C"`}); got != "c" {
+		t.Errorf("unexpected history for search of request: %s", got)
+	}
+	if got := names(HistoryQuery{User: "bob", Search: "greeter.sayhellos"}); got != "bob" {
+		t.Errorf("unexpected history for search of method: %s", got)
+	}
+
+	if err := store.DeleteHistory("", bobItem.ID); !errors.Is(err, ErrHistoryNotFound) {
+		t.Errorf("expecting ErrHistoryNotFound for item of another user; got %v", err)
+	}
+	if err := store.DeleteHistory("bob", bobItem.ID); err != nil {
+		t.Errorf("failed to delete history: %v", err)
+	}
+	add("bob", "SayHello", `{"data": {"name": "bobby"}}`)
+	if err := store.ClearHistory(""); err != nil {
+		t.Errorf("failed to clear history: %v", err)
+	}
+
+	// other local users can't read anyone's history
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expecting history file with mode 0600; got %v, %v", fi, err)
+	}
+	if fi, err := os.Stat(dir); err != nil || fi.Mode().Perm() != 0700 {
+		t.Errorf("expecting history directory with mode 0700; got %v, %v", fi, err)
+	}
+
+	// history survives a restart
+	store, err = NewFileHistoryStore(file, 3)
+	if err != nil {
+		t.Fatalf("failed to re-open store: %v", err)
+	}
+	if got := names(HistoryQuery{}); got != "" {
+		t.Errorf("expecting empty history after clear; got %s", got)
+	}
+	if got := names(HistoryQuery{User: "bob"}); got != "bobby" {
+		t.Errorf("unexpected history for bob after re-opening: %s", got)
+	}
+}
+
+func TestHistoryHandler(t *testing.T) {
+	store, err := NewFileHistoryStore(filepath.Join(t.TempDir(), "history.json"), 0)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	h := historyHandler(store, func(r *http.Request) string {
+		return r.Header.Get("x-user")
+	}, nil)
+
+	serve := func(method, uri, user, body string, csrf bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, uri, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("x-user", user)
+		}
+		if csrf {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "token"})
+			req.Header.Set(csrfHeaderName, "token")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	list := func(uri, user string) []HistoryItem {
+		w := serve("GET", uri, user, "", false)
+		if w.Code != http.StatusOK {
+			t.Fatalf("failed to list history: %d %s", w.Code, w.Body.String())
+		}
+		var items []HistoryItem
+		if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+			t.Fatalf("failed to parse history: %v", err)
+		}
+		return items
+	}
+
+	item := `{"service": "greet.Greeter", "method": "SayHello", "startTime": "2024-01-02T03:04:05Z", "durationMS": 1.5,
+		"request": {"timeout_seconds": 2, "metadata": [{"name": "Authorization", "value": "Bearer s3cret"}, {"name": "x-trace", "value": "1"}], "data": {"name": "x"}}, "responseData": {"responseMsgCount": 1}}`
+	if w := serve("POST", "/history", "alice", item, false); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 without CSRF token; got %d", w.Code)
+	}
+	if w := serve("POST", "/history", "alice", `{"service": "greet.Greeter"}`, true); w.Code != http.StatusBadRequest {
+		t.Errorf("expecting status 400 without method; got %d", w.Code)
+	}
+	w := serve("POST", "/history", "alice", item, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to add history: %d %s", w.Code, w.Body.String())
+	}
+	var added HistoryItem
+	if err := json.Unmarshal(w.Body.Bytes(), &added); err != nil {
+		t.Fatalf("failed to parse added item: %v", err)
+	}
+	if added.ID == "" || added.User != "alice" || added.DurationMS != 1.5 {
+		t.Errorf("unexpected added item: %+v", added)
+	}
+	serve("POST", "/history", "", item, true)
+
+	if items := list("/history", "alice"); len(items) != 1 || items[0].ID != added.ID || !strings.Contains(string(items[0].ResponseData), `"responseMsgCount": 1`) {
+		t.Errorf("unexpected history for alice: %+v", items)
+	}
+	if items := list("/history?q=nothing", "alice"); len(items) != 0 {
+		t.Errorf("expecting no history for search; got %+v", items)
+	}
+	if items := list("/history", ""); len(items) != 1 || items[0].ID == added.ID {
+		t.Errorf("unexpected shared history: %+v", items)
+	} else {
+		var req struct {
+			Metadata []ExampleMetadataPair `json:"metadata"`
+		}
+		_ = json.Unmarshal(items[0].Request, &req)
+		if len(req.Metadata) != 2 || req.Metadata[0].Value != "REDACTED" || req.Metadata[1].Value != "1" {
+			t.Errorf("expecting credentials to be masked in shared history; got %s", items[0].Request)
+		}
+	}
+	if items := list("/history", "alice"); len(items) != 1 || !strings.Contains(string(items[0].Request), "s3cret") {
+		t.Errorf("expecting metadata to be kept in a user's own history; got %+v", items)
+	}
+	if w := serve("GET", "/history?limit=x", "", "", false); w.Code != http.StatusBadRequest {
+		t.Errorf("expecting status 400 for invalid limit; got %d", w.Code)
+	}
+
+	if w := serve("DELETE", "/history/"+added.ID, "bob", "", true); w.Code != http.StatusNotFound {
+		t.Errorf("expecting status 404 when deleting another user's item; got %d", w.Code)
+	}
+	// shared history can't be deleted, since everyone shares it
+	shared := list("/history", "")
+	if w := serve("DELETE", "/history/"+shared[0].ID, "", "", true); w.Code != http.StatusForbidden {
+		t.Errorf("expecting status 403 when deleting shared item; got %d", w.Code)
+	}
+	if w := serve("DELETE", "/history", "", "", true); w.Code != http.StatusForbidden {
+		t.Errorf("expecting status 403 when clearing shared history; got %d", w.Code)
+	}
+	if items := list("/history", ""); len(items) != 1 {
+		t.Errorf("expecting shared history to be kept; got %+v", items)
+	}
+	if w := serve("DELETE", "/history/"+added.ID, "alice", "", true); w.Code != http.StatusNoContent {
+		t.Errorf("expecting status 204 when deleting item; got %d", w.Code)
+	}
+	if w := serve("POST", "/history/"+added.ID, "alice", "", true); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expecting status 405 for POST to item; got %d", w.Code)
+	}
+	serve("POST", "/history", "alice", item, true)
+	if w := serve("DELETE", "/history", "alice", "", true); w.Code != http.StatusNoContent {
+		t.Errorf("expecting status 204 when clearing history; got %d", w.Code)
+	}
+	if items := list("/history", "alice"); len(items) != 0 {
+		t.Errorf("expecting no history after clear; got %+v", items)
+	}
+}
+
+func TestHistoryRedactor(t *testing.T) {
+	redact := historyRedactor(grpcui.NewRedactor([]string{"acme.Order.id"}, []string{"authorization"}), loadOrderMethods(t))
+	item := HistoryItem{
+		Service: "acme.Orders",
+		Method:  "GetOrder",
+		Request: json.RawMessage(`{"metadata":[{"name":"Authorization","value":"Bearer abc"}],"data":{"id":"123"}}`),
+	}
+	redact(&item)
+	if strings.Contains(string(item.Request), "abc") || strings.Contains(string(item.Request), "123") {
+		t.Errorf("expecting request to be redacted; got %s", item.Request)
+	}
+
+	// items for unknown methods are left alone
+	item = HistoryItem{Service: "acme.Nope", Method: "Nope", Request: json.RawMessage(`{"data":{"id":"123"}}`)}
+	redact(&item)
+	if string(item.Request) != `{"data":{"id":"123"}}` {
+		t.Errorf("expecting request to be unchanged; got %s", item.Request)
+	}
+}
@@ -0,0 +1,350 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadExamples loads examples from the given path, which may be a file or a
+// directory. If it is a directory, all files therein with a ".json", ".yaml",
+// or ".yml" extension are loaded, including files in sub-directories. Files
+// with a ".yaml" or ".yml" extension are parsed as YAML; all others as JSON.
+//
+// Each file may contain an array of examples (the same format as used when
+// saving history from the gRPC UI "History" tab), a single example, or an
+// object with "service", "method", and "examples" properties. The latter is
+// convenient for keeping one file per method: the service and method apply to
+// all examples in the file that do not specify their own.
+//
+// The returned examples are grouped by service and method, and are otherwise
+// in the order in which they were found. If any file cannot be parsed, or if
+// any example does not indicate a service and method, an error is returned
+// that indicates the file and position of the problem.
+func LoadExamples(path string) ([]Example, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	if info.IsDir() {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != path && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(p)) {
+			case ".json", ".yaml", ".yml":
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		files = []string{path}
+	}
+
+	var examples []Example
+	var errs []error
+	for _, file := range files {
+		exs, err := loadExampleFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		examples = append(examples, exs...)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	sort.SliceStable(examples, func(i, j int) bool {
+		if examples[i].Service != examples[j].Service {
+			return examples[i].Service < examples[j].Service
+		}
+		return examples[i].Method < examples[j].Method
+	})
+	return examples, nil
+}
+
+// exampleSource is the JSON for a single example and its position in a file.
+type exampleSource struct {
+	data      []byte
+	line, col int
+}
+
+// exampleFile is the contents of a file of examples. If the file contains a
+// single example or an array of examples then service and method are empty.
+type exampleFile struct {
+	service, method string
+	examples        []exampleSource
+}
+
+func loadExampleFile(file string) ([]Example, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var contents *exampleFile
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		contents, err = parseYAMLExamples(data)
+	default:
+		contents, err = parseJSONExamples(data)
+	}
+	if err != nil {
+		var posErr exampleFileError
+		if errors.As(err, &posErr) {
+			return nil, fmt.Errorf("%s:%d:%d: %v", file, posErr.line, posErr.col, posErr.err)
+		}
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	examples := make([]Example, 0, len(contents.examples))
+	for i, src := range contents.examples {
+		var ex Example
+		if err := json.Unmarshal(src.data, &ex); err != nil {
+			return nil, fmt.Errorf("%s:%d:%d: invalid example: %v", file, src.line, src.col, err)
+		}
+		if ex.Service == "" {
+			ex.Service = contents.service
+		}
+		if ex.Method == "" {
+			ex.Method = contents.method
+		}
+		if ex.Service == "" || ex.Method == "" {
+			return nil, fmt.Errorf("%s:%d:%d: example must indicate service and method", file, src.line, src.col)
+		}
+		if ex.Name == "" {
+			ex.Name = base
+			if len(contents.examples) > 1 {
+				ex.Name = fmt.Sprintf("%s #%d", base, i+1)
+			}
+		}
+		examples = append(examples, ex)
+	}
+	return examples, nil
+}
+
+// exampleFileError is an error at a particular position in a file.
+type exampleFileError struct {
+	line, col int
+	err       error
+}
+
+func (e exampleFileError) Error() string {
+	return fmt.Sprintf("%d:%d: %v", e.line, e.col, e.err)
+}
+
+func parseJSONExamples(data []byte) (*exampleFile, error) {
+	if !json.Valid(data) {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// the offset is just past the offending character
+			line, col := offsetToLineCol(data, syntaxErr.Offset-1)
+			return nil, exampleFileError{line: line, col: col, err: err}
+		}
+		return nil, err
+	}
+
+	var result exampleFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	element := func() error {
+		start := nextTokenOffset(data, dec.InputOffset())
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		line, col := offsetToLineCol(data, start)
+		result.examples = append(result.examples, exampleSource{data: raw, line: line, col: col})
+		return nil
+	}
+
+	switch data[nextTokenOffset(data, 0)] {
+	case '[':
+		_, _ = dec.Token()
+		for dec.More() {
+			if err := element(); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		if _, ok := fields["examples"]; !ok {
+			// a single example
+			if err := element(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		_, _ = dec.Token()
+		for dec.More() {
+			tok, _ := dec.Token()
+			switch tok {
+			case "service":
+				if err := dec.Decode(&result.service); err != nil {
+					return nil, jsonErrorAt(data, dec, err)
+				}
+			case "method":
+				if err := dec.Decode(&result.method); err != nil {
+					return nil, jsonErrorAt(data, dec, err)
+				}
+			case "examples":
+				if tok, _ := dec.Token(); tok != json.Delim('[') {
+					line, col := offsetToLineCol(data, dec.InputOffset())
+					return nil, exampleFileError{line: line, col: col, err: errors.New("examples must be an array")}
+				}
+				for dec.More() {
+					if err := element(); err != nil {
+						return nil, err
+					}
+				}
+				_, _ = dec.Token()
+			default:
+				var ignored json.RawMessage
+				_ = dec.Decode(&ignored)
+			}
+		}
+	default:
+		return nil, errors.New("expecting an array or object of examples")
+	}
+	return &result, nil
+}
+
+func jsonErrorAt(data []byte, dec *json.Decoder, err error) error {
+	line, col := offsetToLineCol(data, dec.InputOffset())
+	return exampleFileError{line: line, col: col, err: err}
+}
+
+// nextTokenOffset returns the offset of the first non-whitespace character in
+// data at or after the given offset. This skips separators between array
+// elements, too.
+func nextTokenOffset(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func offsetToLineCol(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	} else if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - (bytes.LastIndexByte(before, '\n') + 1) + 1
+	return line, col
+}
+
+func parseYAMLExamples(data []byte) (*exampleFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// YAML errors already indicate the line
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &exampleFile{}, nil
+	}
+	root := doc.Content[0]
+
+	var result exampleFile
+	addElements := func(nodes []*yaml.Node) error {
+		for _, n := range nodes {
+			var v interface{}
+			if err := n.Decode(&v); err != nil {
+				return exampleFileError{line: n.Line, col: n.Column, err: err}
+			}
+			js, err := json.Marshal(yamlToJSONValue(v))
+			if err != nil {
+				return exampleFileError{line: n.Line, col: n.Column, err: err}
+			}
+			result.examples = append(result.examples, exampleSource{data: js, line: n.Line, col: n.Column})
+		}
+		return nil
+	}
+
+	switch root.Kind {
+	case yaml.SequenceNode:
+		if err := addElements(root.Content); err != nil {
+			return nil, err
+		}
+	case yaml.MappingNode:
+		var examples *yaml.Node
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			switch root.Content[i].Value {
+			case "service":
+				result.service = root.Content[i+1].Value
+			case "method":
+				result.method = root.Content[i+1].Value
+			case "examples":
+				examples = root.Content[i+1]
+			}
+		}
+		if examples == nil {
+			// a single example
+			result.service, result.method = "", ""
+			if err := addElements([]*yaml.Node{root}); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if examples.Kind != yaml.SequenceNode {
+			return nil, exampleFileError{line: examples.Line, col: examples.Column, err: errors.New("examples must be a list")}
+		}
+		if err := addElements(examples.Content); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, exampleFileError{line: root.Line, col: root.Column, err: errors.New("expecting a list or map of examples")}
+	}
+	return &result, nil
+}
+
+// yamlToJSONValue converts maps with non-string keys, which YAML allows but
+// JSON does not, into maps with string keys. This allows the keys of protobuf
+// map fields to be written naturally, even when they are numbers or booleans.
+func yamlToJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = yamlToJSONValue(val)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = yamlToJSONValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = yamlToJSONValue(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,119 @@
+package standalone
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLoadExamples(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"history.json": `[
+			{"name": "b1", "service": "svc.B", "method": "Do", "request": {"data": {}}},
+			{"name": "a2", "service": "svc.A", "method": "Get", "request": {"timeout_seconds": 2, "data": {}}}
+		]`,
+		"a/get.yaml": `
+service: svc.A
+method: Get
+examples:
+  - name: a1
+    description: the first
+    request:
+      metadata:
+        - name: auth
+          value: abc
+      data:
+        id: 123
+        labels:
+          1: one
+  - request:
+      data: {}
+`,
+		"a/single.yml": `
+name: single
+service: svc.A
+method: Create
+request:
+  data: {}
+`,
+		".hidden/bad.json": `not even JSON`,
+		"README.txt":       `ignored`,
+	})
+
+	examples, err := LoadExamples(dir)
+	if err != nil {
+		t.Fatalf("failed to load examples: %v", err)
+	}
+	var names []string
+	for _, ex := range examples {
+		names = append(names, ex.Service+"."+ex.Method+":"+ex.Name)
+	}
+	expected := []string{
+		"svc.A.Create:single",
+		"svc.A.Get:a1",
+		"svc.A.Get:get #2",
+		"svc.A.Get:a2",
+		"svc.B.Do:b1",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected examples:\nwant %v\ngot  %v", expected, names)
+	}
+
+	a1 := examples[1]
+	if a1.Description != "the first" || len(a1.Request.Metadata) != 1 || a1.Request.Metadata[0].Value != "abc" {
+		t.Errorf("unexpected example: %+v", a1)
+	}
+	data, _ := json.Marshal(a1.Request.Data)
+	if string(data) != `{"id":123,"labels":{"1":"one"}}` {
+		t.Errorf("unexpected request data: %s", data)
+	}
+	if examples[3].Request.TimeoutSeconds != 2 {
+		t.Errorf("unexpected timeout: %v", examples[3].Request.TimeoutSeconds)
+	}
+
+	// a single file can also be loaded
+	examples, err = LoadExamples(filepath.Join(dir, "history.json"))
+	if err != nil {
+		t.Fatalf("failed to load examples: %v", err)
+	}
+	if len(examples) != 2 {
+		t.Errorf("expecting 2 examples; got %d", len(examples))
+	}
+}
+
+func TestLoadExamples_Errors(t *testing.T) {
+	testCases := []struct {
+		name, contents, err string
+	}{
+		{"syntax.json", "[\n  {\"name\": \"x\",,}\n]", "syntax.json:2:16: invalid character ','"},
+		{"missing.json", "[\n  {\"name\": \"x\", \"request\": {}}\n]", "missing.json:2:3: example must indicate service and method"},
+		{"type.json", `{"service": "a", "method": "b", "examples": [{"name": 1}]}`, "type.json:1:46: invalid example"},
+		{"syntax.yaml", "examples:\n  - name: [\n", "syntax.yaml: yaml: line 2"},
+		{"missing.yaml", "- name: x\n  service: svc.A\n- name: y\n  method: Get\n", "missing.yaml:1:3: example must indicate service and method"},
+		{"scalar.yaml", "hello", "scalar.yaml:1:1: expecting a list or map of examples"},
+	}
+	for _, tc := range testCases {
+		dir := writeFiles(t, map[string]string{tc.name: tc.contents})
+		_, err := LoadExamples(filepath.Join(dir, tc.name))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
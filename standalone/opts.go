@@ -5,7 +5,13 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"net/http"
 	"path"
+
+	"github.com/jhump/protoreflect/desc"
+	"golang.org/x/oauth2"
+
+	"github.com/fullstorydev/grpcui"
 )
 
 // WebFormContainerTemplateData is the param type for templates that embed the webform HTML.
@@ -21,6 +27,11 @@ type WebFormContainerTemplateData struct {
 	// AddlResources are additional CSS and JS files, in the form of <link> and <script>
 	// tags, that we want to append to the HEAD of the index template.
 	AddlResources []template.HTML
+
+	// LogoutURI is the relative URI to which a POST request ends the user's session
+	// and which then shows the login page. It is empty unless users log in via the
+	// login page (see WithAuthentication).
+	LogoutURI string
 }
 
 // HandlerOption instances allow for configuration of the standalone Handler.
@@ -159,8 +170,167 @@ func WithExampleData(data []byte) (HandlerOption, error) {
 	}), nil
 }
 
+// WithExampleStore allows users of the UI to save the request in the web form
+// as a named example. Saved examples are written to the given store, and the
+// examples in the store are shown in the UI, after any examples provided via
+// WithExamples or WithExampleData. Examples are served at "/examples", where
+// POST requests save a new one, with values of metadata that likely holds
+// credentials masked.
+func WithExampleStore(store ExampleStore) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.exampleStore = store
+	})
+}
+
+// WithHistoryStore keeps the history of invocations made from the web UI in
+// the given store, instead of in the browser's local storage. This makes the
+// history available across browsers and lets users search it. If the handler
+// is also configured with WithUserHeader, each user has their own history.
+// Otherwise, the history is shared by all users of the UI: metadata in it that
+// likely holds credentials, like an "authorization" header, is masked, and it
+// can't be deleted. The history is served at "/history", where POST requests
+// add to it and DELETE requests clear it, and DELETE requests to
+// "/history/<id>" remove a single item.
+func WithHistoryStore(store HistoryStore) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.historyStore = store
+	})
+}
+
+// WithShareStore allows users of the UI to share the request in the web form
+// (and, optionally, its response) via a link that opens the UI with the
+// request pre-filled. Shared requests are kept in the given store. Unless the
+// user chooses otherwise, metadata that likely holds credentials, such as an
+// "authorization" header, is not shared. POST requests to "/share" share the
+// request in the body, and GET requests to "/share/<id>" return it.
+func WithShareStore(store ShareStore) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.shareStore = store
+	})
+}
+
+// WithWorkspaceStore gives each user of the UI a workspace, kept in the given
+// store, where they can save requests in folders, along with notes and
+// variables, and run them later. Workspaces can also be exported and
+// imported as JSON. Unless the handler is also configured with
+// WithUserHeader, all users share one workspace. The user's workspace is
+// served at "/workspace", where PUT requests replace it.
+func WithWorkspaceStore(store WorkspaceStore) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.workspaceStore = store
+	})
+}
+
+// WithAuditSink records every RPC invocation made from the web UI in the
+// given sink, including who made it (see WithUserHeader), the method, and the
+// resulting status. The given options control whether the request and
+// response messages are included. Their sensitive fields are masked by the
+// redactor given via WithRedactor.
+func WithAuditSink(sink AuditSink, auditOpts AuditOptions) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.auditSink = sink
+		opts.auditOptions = auditOpts
+	})
+}
+
+// WithAuthentication requires every request to the handler to be
+// authenticated by one of the given authenticators. If any of them is a
+// PasswordAuthenticator, the handler also serves a login page at "/login",
+// which starts a session that lasts 12 hours, and ends sessions via POST
+// requests to "/logout". The authenticated user takes precedence over the
+// header given to WithUserHeader. When the handler is served over TLS, the
+// session cookie, like the CSRF cookie, is marked Secure.
+func WithAuthentication(authenticators ...Authenticator) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.authenticators = authenticators
+	})
+}
+
+// WithAuthzPolicy restricts the methods that each user may invoke, as decided
+// by the given policy. Users are identified by WithAuthentication or
+// WithUserHeader; without either, every user has an empty name, so only
+// rules that apply to all users have any effect. Methods that a user may not
+// invoke are hidden from their web form and rejected if invoked anyway.
+func WithAuthzPolicy(policy *AuthzPolicy) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.authzPolicy = policy
+	})
+}
+
+// WithProtectedTarget marks the target as protected, so that invoking methods
+// that may have side effects must be confirmed. This is enforced by the
+// handler that invokes RPCs, not only by the web form, so replaying a request
+// from history or sending one from a script needs confirmation, too.
+//
+// A method must be confirmed if it matches any of the confirm patterns, or if
+// its idempotency_level option is neither NO_SIDE_EFFECTS nor IDEMPOTENT and
+// it matches none of the exempt patterns. Patterns are matched against names
+// of the form "package.Service/Method", as in AuthzPolicy. This function
+// returns an error if any pattern is malformed.
+func WithProtectedTarget(confirm, exempt []string) (HandlerOption, error) {
+	for _, pattern := range append(append([]string{}, confirm...), exempt...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return optFunc(func(opts *handlerOptions) {
+		opts.confirmMethod = func(md *desc.MethodDescriptor) bool {
+			name := md.GetService().GetFullyQualifiedName() + "/" + md.GetName()
+			if matchesMethod(confirm, name) {
+				return true
+			}
+			return grpcui.MayHaveSideEffects(md) && !matchesMethod(exempt, name)
+		}
+	}), nil
+}
+
+// WithRedactor masks the sensitive fields and metadata identified by the given
+// redactor. They are masked in responses shown in the web form, in requests
+// saved to the HistoryStore, in records given to the AuditSink, and in the
+// grpcurl command shown for the current request.
+func WithRedactor(redactor *grpcui.Redactor) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.redactor = redactor
+	})
+}
+
+// WithTokenSource sends a token from the given source as "authorization"
+// metadata with every RPC invoked from the web form. Like the metadata given
+// to WithMetadata, the web form lists it by name only. See
+// grpcui.InvokeOptions.TokenSource.
+func WithTokenSource(ts oauth2.TokenSource) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.tokenSource = ts
+	})
+}
+
+// WithInjectedMetadata names metadata that the channel adds to every RPC on
+// its own, such as "authorization" metadata added by per-RPC credentials.
+// Like the metadata given to WithMetadata, the web form lists it by name only.
+// Values for it entered in the web form are not sent. See
+// grpcui.InvokeOptions.InjectedMetadata.
+func WithInjectedMetadata(names ...string) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.injectedMetadata = append(opts.injectedMetadata, names...)
+	})
+}
+
+// WithUserHeader names an HTTP header that identifies the user of the web UI,
+// such as one set by an authenticating reverse proxy. When present in a
+// request, data kept on the server for users, like the history in a
+// HistoryStore, is scoped to the header's value. Since clients can set any
+// header they like, this should only be used when the handler is behind a
+// proxy that always sets (or removes) the header.
+func WithUserHeader(name string) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.userHeader = name
+	})
+}
+
 // WithDefaultMetadata sets the default metadata in the web form to the given
-// values. Each string should be in the form "name: value".
+// values. Each string should be in the form "name: value". The values are
+// visible to every user of the web form, except for those of metadata masked
+// by the redactor given via WithRedactor, which is listed by name only.
 func WithDefaultMetadata(headers []string) HandlerOption {
 	return optFunc(func(opts *handlerOptions) {
 		opts.defaultMetadata = headers
@@ -171,6 +341,8 @@ func WithDefaultMetadata(headers []string) HandlerOption {
 // in invoked. Each string should be in the form "name: value". If the web
 // form includes conflicting metadata, the web form input will be ignored and
 // the metadata supplied to this option will be sent instead.
+// The web form lists the names of this metadata, with an "(injected)"
+// placeholder instead of the values, which are never sent to the browser.
 func WithMetadata(headers []string) HandlerOption {
 	return optFunc(func(opts *handlerOptions) {
 		opts.extraMetadata = headers
@@ -225,6 +397,15 @@ func WithGRPCOptions(options []string) HandlerOption {
 	})
 }
 
+// WithSnippetOptions configures the client code that the web UI generates for
+// the current request. In particular, it indicates whether the generated code
+// should use TLS to connect to the target.
+func WithSnippetOptions(options grpcui.SnippetOptions) HandlerOption {
+	return optFunc(func(opts *handlerOptions) {
+		opts.snippetOptions = options
+	})
+}
+
 // optFunc implements HandlerOption
 type optFunc func(opts *handlerOptions)
 
@@ -237,6 +418,19 @@ type handlerOptions struct {
 	css                 []byte
 	cssPublic           bool
 	examples            []byte
+	exampleStore        ExampleStore
+	historyStore        HistoryStore
+	shareStore          ShareStore
+	workspaceStore      WorkspaceStore
+	auditSink           AuditSink
+	auditOptions        AuditOptions
+	authenticators      []Authenticator
+	authzPolicy         *AuthzPolicy
+	confirmMethod       func(*desc.MethodDescriptor) bool
+	redactor            *grpcui.Redactor
+	tokenSource         oauth2.TokenSource
+	injectedMetadata    []string
+	userHeader          string
 	tmplResources       []*resource
 	servedOnlyResources []*resource
 	defaultMetadata     []string
@@ -246,6 +440,19 @@ type handlerOptions struct {
 	invokeVerbosity     int
 	debug               *bool
 	gRPCurlOptions      []string
+	snippetOptions      grpcui.SnippetOptions
+}
+
+// user returns the user of the web UI that sent the given request, or the
+// empty string if unknown.
+func (opts *handlerOptions) user(r *http.Request) string {
+	if user, ok := authenticatedUser(r); ok {
+		return user
+	}
+	if opts.userHeader == "" {
+		return ""
+	}
+	return r.Header.Get(opts.userHeader)
 }
 
 func (opts *handlerOptions) addlServedResources() []*resource {
@@ -0,0 +1,349 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// The subset of the Postman collection format that describes gRPC requests.
+// Items may be folders, in which case they have nested items instead of a
+// request.
+type postmanCollection struct {
+	Info postmanInfo   `json:"info"`
+	Item []postmanItem `json:"item"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Item        []postmanItem   `json:"item,omitempty"`
+	Request     *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanRequest struct {
+	URL        postmanURL        `json:"url"`
+	MethodPath string            `json:"methodPath"`
+	Metadata   []postmanKeyValue `json:"metadata,omitempty"`
+	Message    postmanMessage    `json:"message,omitempty"`
+	// Postman only stores a single message per request, so the full stream
+	// for client-streaming methods is stored here.
+	Messages []postmanMessage `json:"messages,omitempty"`
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	// HTTP requests in a collection may be just a URL string; these are
+	// ignored, so we leave the request empty
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil
+	}
+	type jsReq postmanRequest
+	return json.Unmarshal(data, (*jsReq)(r))
+}
+
+// postmanURL is the URL of a request. For gRPC requests, it is the target
+// address. HTTP requests may instead have an object that describes the URL,
+// which is ignored.
+type postmanURL string
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*u = postmanURL(s)
+	}
+	return nil
+}
+
+// postmanMessage is the text of a JSON request message. Postman stores it as
+// a string, but we also accept an object with a "content" property or the
+// message itself when importing.
+type postmanMessage string
+
+func (m *postmanMessage) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = postmanMessage(s)
+		return nil
+	}
+	var obj struct {
+		Content *string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &obj); err == nil && obj.Content != nil {
+		*m = postmanMessage(*obj.Content)
+		return nil
+	}
+	*m = postmanMessage(data)
+	return nil
+}
+
+// PostmanImportProblem describes an item in a Postman collection that could
+// not be converted into an example.
+type PostmanImportProblem struct {
+	// The name of the item, including the names of enclosing folders.
+	Item string `json:"item"`
+	// The method referenced by the item, in "service/method" form.
+	MethodPath string `json:"methodPath"`
+	// Why the item could not be imported.
+	Reason string `json:"reason"`
+}
+
+// ExportPostmanCollection writes the given examples to w as a Postman
+// collection with the given name. The collection has a folder for each
+// service, which contains one gRPC request for each example. The requests
+// include the example's metadata and request messages and use the given
+// target as their URL. Handler serves this via POST requests to
+// "/postman/export".
+//
+// The given methods are used to determine which examples are for
+// client-streaming methods. The request data for such examples is a stream
+// of messages instead of a single message.
+func ExportPostmanCollection(w io.Writer, name, target string, methods []*desc.MethodDescriptor, examples []Example) error {
+	clientStreaming := map[string]bool{}
+	for _, md := range methods {
+		clientStreaming[md.GetFullyQualifiedName()] = md.IsClientStreaming()
+	}
+
+	folders := map[string]*postmanItem{}
+	var services []string
+	for _, ex := range examples {
+		data, err := marshalData(ex.Request.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode request data for example %q: %v", ex.Name, err)
+		}
+		var msgs []json.RawMessage
+		if clientStreaming[ex.Service+"."+ex.Method] {
+			if err := json.Unmarshal(data, &msgs); err != nil {
+				return fmt.Errorf("request data for example %q should be an array of messages: %v", ex.Name, err)
+			}
+		} else {
+			msgs = []json.RawMessage{data}
+		}
+
+		req := &postmanRequest{
+			URL:        postmanURL(target),
+			MethodPath: ex.Service + "/" + ex.Method,
+		}
+		for _, md := range ex.Request.Metadata {
+			req.Metadata = append(req.Metadata, postmanKeyValue{Key: md.Name, Value: md.Value})
+		}
+		for i, msg := range msgs {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, msg, "", "  "); err != nil {
+				return fmt.Errorf("failed to encode request data for example %q: %v", ex.Name, err)
+			}
+			if i == 0 {
+				req.Message = postmanMessage(buf.String())
+			}
+			if len(msgs) > 1 {
+				req.Messages = append(req.Messages, postmanMessage(buf.String()))
+			}
+		}
+
+		folder := folders[ex.Service]
+		if folder == nil {
+			folder = &postmanItem{Name: ex.Service}
+			folders[ex.Service] = folder
+			services = append(services, ex.Service)
+		}
+		folder.Item = append(folder.Item, postmanItem{
+			Name:        ex.Name,
+			Description: ex.Description,
+			Request:     req,
+		})
+	}
+
+	sort.Strings(services)
+	coll := postmanCollection{
+		Info: postmanInfo{Name: name, Schema: postmanSchema},
+		Item: []postmanItem{},
+	}
+	for _, svc := range services {
+		coll.Item = append(coll.Item, *folders[svc])
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(coll)
+}
+
+// ImportPostmanCollection reads a Postman collection from r and converts its
+// gRPC requests into examples. Items in nested folders are included, and their
+// names are qualified with the names of the enclosing folders. Handler serves
+// this via POST requests to "/postman/import".
+//
+// Requests that refer to methods that are not among the given methods, or
+// whose messages are not valid JSON, are not converted. Instead, they are
+// described in the returned problems. Other items in the collection, such as
+// HTTP requests, are ignored. An error is returned only if the data is not a
+// valid Postman collection.
+func ImportPostmanCollection(r io.Reader, methods []*desc.MethodDescriptor) ([]Example, []PostmanImportProblem, error) {
+	var coll postmanCollection
+	if err := json.NewDecoder(r).Decode(&coll); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Postman collection: %v", err)
+	}
+	if coll.Item == nil {
+		return nil, nil, fmt.Errorf("data is not a Postman collection: no items")
+	}
+
+	methodsByName := map[string]*desc.MethodDescriptor{}
+	for _, md := range methods {
+		methodsByName[md.GetFullyQualifiedName()] = md
+	}
+
+	var examples []Example
+	var problems []PostmanImportProblem
+	var importItems func(prefix string, items []postmanItem)
+	importItems = func(prefix string, items []postmanItem) {
+		for _, item := range items {
+			name := prefix + item.Name
+			if item.Request == nil {
+				importItems(name+" / ", item.Item)
+				continue
+			}
+			if item.Request.MethodPath == "" {
+				// not a gRPC request
+				continue
+			}
+			ex, reason := postmanItemToExample(item, methodsByName)
+			if reason != "" {
+				problems = append(problems, PostmanImportProblem{
+					Item:       name,
+					MethodPath: item.Request.MethodPath,
+					Reason:     reason,
+				})
+				continue
+			}
+			ex.Name = name
+			examples = append(examples, ex)
+		}
+	}
+	importItems("", coll.Item)
+	return examples, problems, nil
+}
+
+func postmanItemToExample(item postmanItem, methods map[string]*desc.MethodDescriptor) (Example, string) {
+	methodPath := strings.TrimPrefix(item.Request.MethodPath, "/")
+	sep := strings.LastIndexByte(methodPath, '/')
+	if sep < 0 {
+		sep = strings.LastIndexByte(methodPath, '.')
+	}
+	if sep < 0 {
+		return Example{}, "invalid method path"
+	}
+	md := methods[methodPath[:sep]+"."+methodPath[sep+1:]]
+	if md == nil {
+		return Example{}, "method is not exposed by this server"
+	}
+
+	msgs := item.Request.Messages
+	if len(msgs) == 0 && strings.TrimSpace(string(item.Request.Message)) != "" {
+		msgs = []postmanMessage{item.Request.Message}
+	}
+	var data []json.RawMessage
+	for _, msg := range msgs {
+		if !json.Valid([]byte(msg)) {
+			return Example{}, "request message is not valid JSON"
+		}
+		data = append(data, json.RawMessage(msg))
+	}
+
+	ex := Example{
+		Description: item.Description,
+		Service:     md.GetService().GetFullyQualifiedName(),
+		Method:      md.GetName(),
+	}
+	switch {
+	case md.IsClientStreaming():
+		if data == nil {
+			data = []json.RawMessage{}
+		}
+		ex.Request.Data = data
+	case len(data) > 1:
+		return Example{}, fmt.Sprintf("method does not accept a stream of %d request messages", len(data))
+	case len(data) == 1:
+		ex.Request.Data = data[0]
+	default:
+		ex.Request.Data = json.RawMessage("{}")
+	}
+	ex.Request.Metadata = []ExampleMetadataPair{}
+	for _, kv := range item.Request.Metadata {
+		if !kv.Disabled {
+			ex.Request.Metadata = append(ex.Request.Metadata, ExampleMetadataPair{Name: kv.Key, Value: kv.Value})
+		}
+	}
+	return ex, ""
+}
+
+// postmanHandler returns a handler that converts between the examples format,
+// which is also the format of saved history in the web form, and Postman
+// collections. It serves two endpoints: "/export", which accepts a JSON array
+// of examples and returns a collection, and "/import", which accepts a
+// collection and returns the converted examples and any problems.
+func postmanHandler(target string, methods []*desc.MethodDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/export":
+			var examples []Example
+			if err := json.NewDecoder(r.Body).Decode(&examples); err != nil {
+				http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			var buf bytes.Buffer
+			if err := ExportPostmanCollection(&buf, "gRPC UI: "+target, target, methods, examples); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="grpcui.postman_collection.json"`)
+			_, _ = w.Write(buf.Bytes())
+		case "/import":
+			examples, problems, err := ImportPostmanCollection(r.Body, methods)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result := struct {
+				Examples []Example              `json:"examples"`
+				Problems []PostmanImportProblem `json:"problems"`
+			}{
+				Examples: examples,
+				Problems: problems,
+			}
+			if result.Examples == nil {
+				result.Examples = []Example{}
+			}
+			if result.Problems == nil {
+				result.Problems = []PostmanImportProblem{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(result)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse" //lint:ignore SA1019 only used to load test fixtures
+
+	"github.com/fullstorydev/grpcui"
+)
+
+func loadGreeterMethods(t *testing.T) []*desc.MethodDescriptor {
+	t.Helper()
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"greeter.proto": `
+				syntax = "proto3";
+				package greet;
+				message Hello {
+					string name = 1;
+				}
+				service Greeter {
+					rpc SayHello(Hello) returns (Hello);
+					rpc SayHellos(stream Hello) returns (Hello);
+				}`,
+		}),
+	}
+	fds, err := p.ParseFiles("greeter.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto: %v", err)
+	}
+	return grpcui.AllMethodsForServices(fds[0].GetServices())
+}
+
+func TestPostmanRoundTrip(t *testing.T) {
+	methods := loadGreeterMethods(t)
+	examples := []Example{
+		{
+			Name:        "hello",
+			Description: "says hello",
+			Service:     "greet.Greeter",
+			Method:      "SayHello",
+			Request: ExampleRequest{
+				Metadata: []ExampleMetadataPair{{Name: "authorization", Value: "Bearer abc"}},
+				Data:     map[string]interface{}{"name": "bob"},
+			},
+		},
+		{
+			Name:    "hellos",
+			Service: "greet.Greeter",
+			Method:  "SayHellos",
+			Request: ExampleRequest{
+				Metadata: []ExampleMetadataPair{},
+				Data:     []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportPostmanCollection(&buf, "test", "localhost:8080", methods, examples); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	var coll map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &coll); err != nil {
+		t.Fatalf("exported invalid JSON: %v", err)
+	}
+	folder := coll["item"].([]interface{})[0].(map[string]interface{})
+	if folder["name"] != "greet.Greeter" {
+		t.Errorf("expecting a folder for the service; got %v", folder["name"])
+	}
+	req := folder["item"].([]interface{})[0].(map[string]interface{})["request"].(map[string]interface{})
+	if req["methodPath"] != "greet.Greeter/SayHello" || req["url"] != "localhost:8080" {
+		t.Errorf("unexpected request: %v", req)
+	}
+
+	imported, problems, err := ImportPostmanCollection(&buf, methods)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("expecting 2 examples; got %d", len(imported))
+	}
+	for i := range imported {
+		if imported[i].Name != "greet.Greeter / "+examples[i].Name {
+			t.Errorf("unexpected name: %q", imported[i].Name)
+		}
+		if imported[i].Method != examples[i].Method || imported[i].Description != examples[i].Description {
+			t.Errorf("round trip failure: want %+v, got %+v", examples[i], imported[i])
+		}
+		if !reflect.DeepEqual(imported[i].Request.Metadata, examples[i].Request.Metadata) {
+			t.Errorf("metadata round trip failure: want %v, got %v", examples[i].Request.Metadata, imported[i].Request.Metadata)
+		}
+		want, _ := json.Marshal(examples[i].Request.Data)
+		got, _ := json.Marshal(imported[i].Request.Data)
+		if !bytes.Equal(want, got) {
+			t.Errorf("data round trip failure: want %s, got %s", want, got)
+		}
+	}
+}
+
+func TestImportPostmanCollection_Problems(t *testing.T) {
+	coll := `{
+		"info": {"name": "other team", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+		"item": [
+			{"name": "rest call", "request": {"method": "GET", "url": {"raw": "http://example.com", "host": ["example", "com"]}}},
+			{"name": "short rest call", "request": "http://example.com"},
+			{"name": "folder", "item": [
+				{"name": "ok", "request": {"url": "x:1", "methodPath": "greet.Greeter/SayHello",
+					"metadata": [{"key": "a", "value": "b"}, {"key": "c", "value": "d", "disabled": true}],
+					"message": {"content": "{\"name\": \"x\"}"}}},
+				{"name": "gone", "request": {"url": "x:1", "methodPath": "greet.Greeter/SayGoodbye", "message": "{}"}},
+				{"name": "templated", "request": {"url": "x:1", "methodPath": "greet.Greeter/SayHello", "message": "{\"name\": {{name}}}"}}
+			]}
+		]
+	}`
+	examples, problems, err := ImportPostmanCollection(strings.NewReader(coll), loadGreeterMethods(t))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(examples) != 1 || examples[0].Name != "folder / ok" {
+		t.Fatalf("unexpected examples: %+v", examples)
+	}
+	if !reflect.DeepEqual(examples[0].Request.Metadata, []ExampleMetadataPair{{Name: "a", Value: "b"}}) {
+		t.Errorf("disabled metadata should be skipped; got %v", examples[0].Request.Metadata)
+	}
+	expected := []PostmanImportProblem{
+		{Item: "folder / gone", MethodPath: "greet.Greeter/SayGoodbye", Reason: "method is not exposed by this server"},
+		{Item: "folder / templated", MethodPath: "greet.Greeter/SayHello", Reason: "request message is not valid JSON"},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("unexpected problems: %+v", problems)
+	}
+
+	if _, _, err := ImportPostmanCollection(strings.NewReader(`{"name": "not a collection"}`), nil); err == nil {
+		t.Errorf("expecting error for data that is not a collection")
+	}
+}
+
+func TestExampleRequest_TimeoutSecondsAlias(t *testing.T) {
+	var req ExampleRequest
+	if err := json.Unmarshal([]byte(`{"timeout_seconds": 1.5, "data": {}}`), &req); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if req.TimeoutSeconds != 1.5 {
+		t.Errorf("expecting timeout_seconds to be accepted; got %v", req.TimeoutSeconds)
+	}
+}
@@ -0,0 +1,557 @@
+package standalone
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/golang/protobuf/proto"  //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ExampleExpectation describes the expected outcome of an example's request.
+// Examples with expectations can be run as tests using RunExample.
+type ExampleExpectation struct {
+	// The name of the expected status code, such as "OK" or "NOT_FOUND". The
+	// numeric value of the code may also be used. Defaults to "OK".
+	Code string `json:"code,omitempty"`
+	// If non-empty, the status message must contain this text.
+	Message string `json:"message,omitempty"`
+	// If non-empty, the RPC must return exactly this many response messages,
+	// and each must match the corresponding matcher.
+	Responses []ResponseMatcher `json:"responses,omitempty"`
+	// Response headers that must be present, with the given values.
+	Headers []ExampleMetadataPair `json:"headers,omitempty"`
+	// Response trailers that must be present, with the given values.
+	Trailers []ExampleMetadataPair `json:"trailers,omitempty"`
+}
+
+// ResponseMatcher describes the expected contents of a response message. The
+// message is compared in its JSON form, using the field names from the proto
+// source (the same form shown in the web UI). A matcher may use any
+// combination of its fields; the message must satisfy all of them.
+type ResponseMatcher struct {
+	// If present, the message must be exactly equal to this value. Fields
+	// with default values are omitted from the message, so they must be
+	// omitted here, too.
+	Exact interface{} `json:"exact,omitempty"`
+	// If present, the message must contain all of the fields in this value,
+	// with the same values. Nested messages are compared the same way, so
+	// they may also omit fields. Arrays must have the same length and their
+	// elements are compared the same way. Fields with default values can be
+	// matched, even though they are omitted from the UI.
+	Subset interface{} `json:"subset,omitempty"`
+	// Maps path expressions to the expected value at that path. A path is a
+	// sequence of field names separated by dots, and may use "[n]" to index
+	// into arrays, for example "items[0].id". The value at the path is
+	// compared the same way as Subset.
+	Paths map[string]interface{} `json:"paths,omitempty"`
+}
+
+// ExampleResult is the outcome of running an example with RunExample.
+type ExampleResult struct {
+	// The example that was run.
+	Example Example
+	// How long the RPC took.
+	Duration time.Duration
+	// Descriptions of how the outcome differed from the example's
+	// expectations. Empty if the example passed.
+	Failures []string
+}
+
+// Passed returns true if the outcome of the example met its expectations.
+func (r *ExampleResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// RunExample invokes the example's RPC using the given channel and checks the
+// outcome against the example's expectations. Examples without expectations
+// are expected to complete with an "OK" status. The given methods are used to
+// resolve the example's method, and the given metadata, with each string in
+// the form "name: value", is sent with the RPC in addition to the example's
+// request metadata.
+func RunExample(ctx context.Context, ch grpc.ClientConnInterface, methods []*desc.MethodDescriptor, ex Example, extraMetadata []string) *ExampleResult {
+	result := &ExampleResult{Example: ex}
+	if errs := ValidateExamples([]Example{ex}, methods); len(errs) > 0 {
+		result.Failures = append(result.Failures, errs[0].Err.Error())
+		return result
+	}
+	var md *desc.MethodDescriptor
+	for _, m := range methods {
+		if m.GetFullyQualifiedName() == ex.Service+"."+ex.Method {
+			md = m
+			break
+		}
+	}
+	expect := ex.Expect
+	if expect == nil {
+		expect = &ExampleExpectation{}
+	}
+	code, err := parseStatusCode(expect.Code)
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result
+	}
+
+	descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result
+	}
+	data, err := marshalData(ex.Request.Data)
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("failed to encode request data: %v", err))
+		return result
+	}
+	reqs := []json.RawMessage{data}
+	if md.IsClientStreaming() {
+		// already verified to be an array by ValidateExamples
+		_ = json.Unmarshal(data, &reqs)
+	}
+	unmarshaler := jsonpb.Unmarshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSource(descSource)}
+	requestFunc := func(m proto.Message) error {
+		if len(reqs) == 0 {
+			return io.EOF
+		}
+		req := reqs[0]
+		reqs = reqs[1:]
+		return unmarshaler.Unmarshal(bytes.NewReader(req), m)
+	}
+
+	// like the invoke handler, the given metadata overrides conflicting
+	// metadata in the request
+	reqMD := metadata.MD{}
+	for _, pair := range ex.Request.Metadata {
+		reqMD.Append(pair.Name, pair.Value)
+	}
+	for k, v := range grpcurl.MetadataFromHeaders(extraMetadata) {
+		reqMD[k] = v
+	}
+	var hdrs []string
+	for k, vs := range reqMD {
+		for _, v := range vs {
+			hdrs = append(hdrs, k+": "+v)
+		}
+	}
+	sort.Strings(hdrs)
+	if ex.Request.TimeoutSeconds > 0 {
+		timeout := time.Duration(ex.Request.TimeoutSeconds * float64(time.Second))
+		if timeout < 0 {
+			timeout = time.Duration(math.MaxInt64)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	run := exampleRun{descSource: descSource}
+	start := time.Now()
+	err = grpcurl.InvokeRPC(ctx, descSource, ch, md.GetFullyQualifiedName(), hdrs, &run, requestFunc)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("failed to invoke RPC: %v", err))
+		return result
+	}
+	if run.err != nil {
+		result.Failures = append(result.Failures, run.err.Error())
+		return result
+	}
+
+	if run.status.Code() != code {
+		msg := fmt.Sprintf("expected status %s, got %s", statusCodeName(code), statusCodeName(run.status.Code()))
+		if run.status.Message() != "" {
+			msg += ": " + run.status.Message()
+		}
+		result.Failures = append(result.Failures, msg)
+	} else if expect.Message != "" && !strings.Contains(run.status.Message(), expect.Message) {
+		result.Failures = append(result.Failures, fmt.Sprintf("expected status message to contain %q, got %q", expect.Message, run.status.Message()))
+	}
+	result.Failures = append(result.Failures, checkMetadata("header", expect.Headers, run.headers)...)
+	result.Failures = append(result.Failures, checkMetadata("trailer", expect.Trailers, run.trailers)...)
+
+	if len(expect.Responses) > 0 {
+		if len(expect.Responses) != len(run.responses) {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected %d response message(s), got %d", len(expect.Responses), len(run.responses)))
+		} else {
+			for i, matcher := range expect.Responses {
+				for _, failure := range matcher.check(run.responses[i]) {
+					result.Failures = append(result.Failures, fmt.Sprintf("response #%d: %s", i+1, failure))
+				}
+			}
+		}
+	}
+	return result
+}
+
+func parseStatusCode(name string) (codes.Code, error) {
+	if name == "" {
+		return codes.OK, nil
+	}
+	var code codes.Code
+	js := strconv.Quote(strings.ToUpper(name))
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		js = name
+	}
+	if err := code.UnmarshalJSON([]byte(js)); err != nil {
+		return 0, fmt.Errorf("invalid expected status code %q", name)
+	}
+	return code, nil
+}
+
+// statusCodeName returns the name of the given code in the same form used in
+// expectations, such as "NOT_FOUND".
+func statusCodeName(code codes.Code) string {
+	name := code.String()
+	var buf strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(name[i-1])) {
+			buf.WriteByte('_')
+		}
+		buf.WriteRune(unicode.ToUpper(r))
+	}
+	return buf.String()
+}
+
+func checkMetadata(kind string, expected []ExampleMetadataPair, actual metadata.MD) []string {
+	var failures []string
+	for _, pair := range expected {
+		vals := actual.Get(pair.Name)
+		found := false
+		for _, v := range vals {
+			if strings.HasSuffix(strings.ToLower(pair.Name), "-bin") {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			if v == pair.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if len(vals) == 0 {
+				failures = append(failures, fmt.Sprintf("expected %s %q, but it was not present", kind, pair.Name))
+			} else {
+				failures = append(failures, fmt.Sprintf("expected %s %q to be %q, got %q", kind, pair.Name, pair.Value, strings.Join(vals, ", ")))
+			}
+		}
+	}
+	return failures
+}
+
+// exampleRun records the outcome of an RPC invoked by RunExample.
+type exampleRun struct {
+	descSource grpcurl.DescriptorSource
+	headers    metadata.MD
+	trailers   metadata.MD
+	status     *status.Status
+	responses  []runResponse
+	err        error
+}
+
+// runResponse is a response message in JSON form, both with and without
+// fields that have default values.
+type runResponse struct {
+	compact, withDefaults interface{}
+}
+
+func (*exampleRun) OnResolveMethod(*desc.MethodDescriptor) {}
+
+func (*exampleRun) OnSendHeaders(metadata.MD) {}
+
+func (r *exampleRun) OnReceiveHeaders(md metadata.MD) {
+	r.headers = md
+}
+
+func (r *exampleRun) OnReceiveResponse(m proto.Message) {
+	var resp runResponse
+	for _, emitDefaults := range []bool{false, true} {
+		jsm := jsonpb.Marshaler{
+			EmitDefaults: emitDefaults,
+			OrigName:     true,
+			AnyResolver:  grpcurl.AnyResolverFromDescriptorSourceWithFallback(r.descSource),
+		}
+		var buf bytes.Buffer
+		var v interface{}
+		err := jsm.Marshal(&buf, m)
+		if err == nil {
+			v, err = decodeJSONValue(buf.Bytes())
+		}
+		if err != nil {
+			if r.err == nil {
+				r.err = fmt.Errorf("failed to convert response message to JSON: %v", err)
+			}
+			return
+		}
+		if emitDefaults {
+			resp.withDefaults = v
+		} else {
+			resp.compact = v
+		}
+	}
+	r.responses = append(r.responses, resp)
+}
+
+func (r *exampleRun) OnReceiveTrailers(stat *status.Status, md metadata.MD) {
+	r.status = stat
+	r.trailers = md
+}
+
+func (m *ResponseMatcher) check(resp runResponse) []string {
+	var failures []string
+	if m.Exact != nil {
+		want, err := normalizeJSONValue(m.Exact)
+		if err != nil {
+			return []string{fmt.Sprintf("invalid exact matcher: %v", err)}
+		}
+		if !jsonValuesEqual(want, resp.compact, false) {
+			failures = append(failures, "message does not match exactly:\n"+jsonDiff(want, resp.compact))
+		}
+	}
+	if m.Subset != nil {
+		want, err := normalizeJSONValue(m.Subset)
+		if err != nil {
+			return []string{fmt.Sprintf("invalid subset matcher: %v", err)}
+		}
+		if !jsonValuesEqual(want, resp.withDefaults, true) {
+			failures = append(failures, "message does not contain expected fields:\n"+jsonDiff(want, projectJSONValue(resp.withDefaults, want)))
+		}
+	}
+	paths := make([]string, 0, len(m.Paths))
+	for p := range m.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		want, err := normalizeJSONValue(m.Paths[p])
+		if err != nil {
+			return []string{fmt.Sprintf("invalid matcher for path %q: %v", p, err)}
+		}
+		got, err := jsonValueAtPath(resp.withDefaults, p)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("path %s: %v", p, err))
+			continue
+		}
+		if !jsonValuesEqual(want, got, true) {
+			failures = append(failures, fmt.Sprintf("path %s: expected %s, got %s", p, compactJSON(want), compactJSON(got)))
+		}
+	}
+	return failures
+}
+
+func decodeJSONValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// normalizeJSONValue converts v, which may be a value decoded from JSON or
+// YAML, to the same form as values returned by decodeJSONValue.
+func normalizeJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(yamlToJSONValue(v))
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONValue(data)
+}
+
+// jsonValuesEqual compares two JSON values. If subset is true, then objects
+// in got may have properties that are not in want. Numbers are compared by
+// value, and a number matches a string with the same value, since 64-bit
+// integers are represented as strings in JSON.
+func jsonValuesEqual(want, got interface{}, subset bool) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || (!subset && len(g) != len(w)) {
+			return false
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok || !jsonValuesEqual(wv, gv, subset) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !jsonValuesEqual(w[i], g[i], subset) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		var gs string
+		switch g := got.(type) {
+		case json.Number:
+			gs = g.String()
+		case string:
+			gs = g
+		default:
+			return false
+		}
+		if w.String() == gs {
+			return true
+		}
+		wf, err1 := w.Float64()
+		gf, err2 := strconv.ParseFloat(gs, 64)
+		return err1 == nil && err2 == nil && wf == gf
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+// projectJSONValue returns the parts of got that correspond to the parts of
+// want, so that a diff of a subset match only shows the relevant fields.
+func projectJSONValue(got, want interface{}) interface{} {
+	w, ok := want.(map[string]interface{})
+	if !ok {
+		if wa, ok := want.([]interface{}); ok {
+			if ga, ok := got.([]interface{}); ok {
+				result := make([]interface{}, len(ga))
+				for i := range ga {
+					if i < len(wa) {
+						result[i] = projectJSONValue(ga[i], wa[i])
+					} else {
+						result[i] = ga[i]
+					}
+				}
+				return result
+			}
+		}
+		return got
+	}
+	g, ok := got.(map[string]interface{})
+	if !ok {
+		return got
+	}
+	result := map[string]interface{}{}
+	for k, wv := range w {
+		if gv, ok := g[k]; ok {
+			result[k] = projectJSONValue(gv, wv)
+		}
+	}
+	return result
+}
+
+// jsonValueAtPath returns the value at the given path in v. A path is a
+// sequence of property names, separated by dots, each optionally followed by
+// one or more array indexes in square brackets.
+func jsonValueAtPath(v interface{}, path string) (interface{}, error) {
+	parts := strings.Split(path, ".")
+	for p, part := range parts {
+		name := part
+		var indexes []string
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("invalid path")
+				}
+				indexes = append(indexes, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+		if name != "" {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%q is not an object", strings.Join(parts[:p], "."))
+			}
+			if v, ok = obj[name]; !ok {
+				return nil, fmt.Errorf("field %q not present", name)
+			}
+		}
+		for _, index := range indexes {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%q is not an array", name)
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index %q", index)
+			}
+			if i >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range; %q has %d element(s)", i, name, len(arr))
+			}
+			v = arr[i]
+		}
+	}
+	return v, nil
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// jsonDiff returns a line-oriented diff of the indented JSON forms of the
+// given values. Lines only in want are prefixed with "-" and lines only in
+// got are prefixed with "+".
+func jsonDiff(want, got interface{}) string {
+	w, _ := json.MarshalIndent(want, "", "  ")
+	g, _ := json.MarshalIndent(got, "", "  ")
+	a := strings.Split(string(w), "\n")
+	b := strings.Split(string(g), "\n")
+
+	// longest common subsequence, computed from the end so that the diff
+	// can be emitted from the start
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var buf strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&buf, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&buf, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&buf, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,186 @@
+package standalone
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGreeterServer starts an in-memory server for the methods returned by
+// loadGreeterMethods. SayHello responds with a greeting for the given name,
+// or fails with NOT_FOUND if the name is "nobody". SayHellos responds with a
+// greeting for all of the names it receives.
+func startGreeterServer(t *testing.T, methods []*desc.MethodDescriptor) *grpc.ClientConn {
+	t.Helper()
+	hello := methods[0].GetInputType()
+	svr := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		var names []string
+		for {
+			req := dynamic.NewMessage(hello)
+			if err := stream.RecvMsg(req); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			names = append(names, req.GetFieldByName("name").(string))
+			if method == "/greet.Greeter/SayHello" {
+				break
+			}
+		}
+		if len(names) == 1 && names[0] == "nobody" {
+			return status.Error(codes.NotFound, "nobody is not here")
+		}
+		_ = stream.SetHeader(metadata.Pairs("greeting-count", "1"))
+		stream.SetTrailer(metadata.Pairs("farewell", "bye"))
+		resp := dynamic.NewMessage(hello)
+		resp.SetFieldByName("name", "hello, "+strings.Join(names, " and "))
+		return stream.SendMsg(resp)
+	}))
+	l := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = svr.Serve(l)
+	}()
+	t.Cleanup(svr.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cc.Close()
+	})
+	return cc
+}
+
+func TestRunExample(t *testing.T) {
+	methods := loadGreeterMethods(t)
+	cc := startGreeterServer(t, methods)
+
+	testCases := []struct {
+		name     string
+		example  string
+		failures []string
+	}{
+		{
+			name:    "no expectations",
+			example: `{"method": "SayHello", "request": {"data": {"name": "bob"}}}`,
+		},
+		{
+			name: "all matchers",
+			example: `{"method": "SayHello", "request": {"data": {"name": "bob"}}, "expect": {
+				"code": "OK",
+				"responses": [{"exact": {"name": "hello, bob"}, "subset": {}, "paths": {"name": "hello, bob"}}],
+				"headers": [{"name": "greeting-count", "value": "1"}],
+				"trailers": [{"name": "Farewell", "value": "bye"}]
+			}}`,
+		},
+		{
+			name: "client stream",
+			example: `{"method": "SayHellos", "request": {"data": [{"name": "a"}, {"name": "b"}]}, "expect": {
+				"responses": [{"subset": {"name": "hello, a and b"}}]
+			}}`,
+		},
+		{
+			name:    "expected error",
+			example: `{"method": "SayHello", "request": {"data": {"name": "nobody"}}, "expect": {"code": "not_found", "message": "not here"}}`,
+		},
+		{
+			name:     "unexpected error",
+			example:  `{"method": "SayHello", "request": {"data": {"name": "nobody"}}}`,
+			failures: []string{"expected status OK, got NOT_FOUND: nobody is not here"},
+		},
+		{
+			name:     "wrong code",
+			example:  `{"method": "SayHello", "request": {"data": {"name": "bob"}}, "expect": {"code": "5"}}`,
+			failures: []string{"expected status NOT_FOUND, got OK"},
+		},
+		{
+			name:     "invalid code",
+			example:  `{"method": "SayHello", "request": {"data": {"name": "bob"}}, "expect": {"code": "SO_SO"}}`,
+			failures: []string{`invalid expected status code "SO_SO"`},
+		},
+		{
+			name: "mismatches",
+			example: `{"method": "SayHello", "request": {"data": {"name": "bob"}}, "expect": {
+				"responses": [{"exact": {"name": "hi, bob"}, "paths": {"name": "hi", "name[0]": 1, "other": 1}}],
+				"headers": [{"name": "greeting-count", "value": "2"}],
+				"trailers": [{"name": "missing", "value": "x"}]
+			}}`,
+			failures: []string{
+				`expected header "greeting-count" to be "2", got "1"`,
+				`expected trailer "missing", but it was not present`,
+				"response #1: message does not match exactly:\n  {\n-   \"name\": \"hi, bob\"\n+   \"name\": \"hello, bob\"\n  }",
+				`response #1: path name: expected "hi", got "hello, bob"`,
+				`response #1: path name[0]: "name" is not an array`,
+				`response #1: path other: field "other" not present`,
+			},
+		},
+		{
+			name:     "wrong number of responses",
+			example:  `{"method": "SayHello", "request": {"data": {}}, "expect": {"responses": [{}, {}]}}`,
+			failures: []string{"expected 2 response message(s), got 1"},
+		},
+		{
+			name:     "unknown method",
+			example:  `{"method": "SayGoodbye", "request": {"data": {}}}`,
+			failures: []string{"method is not exposed by the server"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ex Example
+			if err := json.Unmarshal([]byte(tc.example), &ex); err != nil {
+				t.Fatalf("invalid example: %v", err)
+			}
+			ex.Service = "greet.Greeter"
+			result := RunExample(context.Background(), cc, methods, ex, nil)
+			if result.Passed() != (len(tc.failures) == 0) {
+				t.Errorf("unexpected result: %q", result.Failures)
+			}
+			if strings.Join(result.Failures, "\n") != strings.Join(tc.failures, "\n") {
+				t.Errorf("unexpected failures:\nwant %q\ngot  %q", tc.failures, result.Failures)
+			}
+		})
+	}
+}
+
+func TestJSONValuesEqual(t *testing.T) {
+	testCases := []struct {
+		want, got string
+		subset    bool
+		equal     bool
+	}{
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, true, true},
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, false, false},
+		{`{"a": {"b": [1, {"c": 2}]}}`, `{"a": {"b": [1, {"c": 2, "d": 3}]}}`, true, true},
+		{`{"a": [1]}`, `{"a": [1, 2]}`, true, false},
+		{`{"id": 123}`, `{"id": "123"}`, false, true},
+		{`1.0`, `1`, false, true},
+		{`"1"`, `1`, false, false},
+		{`null`, `null`, false, true},
+	}
+	for _, tc := range testCases {
+		want, _ := decodeJSONValue([]byte(tc.want))
+		got, _ := decodeJSONValue([]byte(tc.got))
+		if jsonValuesEqual(want, got, tc.subset) != tc.equal {
+			t.Errorf("jsonValuesEqual(%s, %s, %v) should be %v", tc.want, tc.got, tc.subset, tc.equal)
+		}
+	}
+}
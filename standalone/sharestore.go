@@ -0,0 +1,209 @@
+package standalone
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// ErrSharedRequestNotFound is returned by ShareStore.SharedRequest when the
+// store has no request with the given ID.
+var ErrSharedRequestNotFound = errors.New("shared request not found")
+
+// SharedRequest is a request from the web form that a user shared, so that
+// others can open the UI with the request pre-filled.
+type SharedRequest struct {
+	// A short ID, assigned by the store, that is used in the link to the
+	// shared request.
+	ID      string         `json:"id"`
+	Service string         `json:"service"`
+	Method  string         `json:"method"`
+	Request ExampleRequest `json:"request"`
+	// The response to the request, in the form returned by the handler that
+	// invokes RPCs, if the user chose to share it.
+	Response json.RawMessage `json:"response,omitempty"`
+	Created  time.Time       `json:"created"`
+}
+
+// ShareStore keeps the requests that users share from the web UI. When a
+// Handler is configured with a store, the web form includes a button that
+// creates a link to the current request.
+type ShareStore interface {
+	// SaveSharedRequest adds the given request to the store and returns it
+	// with its ID assigned.
+	SaveSharedRequest(req SharedRequest) (SharedRequest, error)
+	// SharedRequest returns the request with the given ID. If there is no
+	// such request, it returns ErrSharedRequestNotFound.
+	SharedRequest(id string) (SharedRequest, error)
+}
+
+// NewFileShareStore returns a ShareStore that keeps each shared request in a
+// JSON file, named after its ID, in the given directory. The directory is
+// created when the first request is shared, if it does not already exist.
+// The files, and the directory if it is created, are only accessible to their
+// owner.
+func NewFileShareStore(dir string) (ShareStore, error) {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &fileShareStore{dir: dir}, nil
+}
+
+type fileShareStore struct {
+	dir string
+}
+
+// sharedRequestIDPattern matches the IDs that newSharedRequestID returns.
+var sharedRequestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// newSharedRequestID returns a random ID that is short enough to include in
+// links but long enough that IDs cannot be guessed.
+func newSharedRequestID() (string, error) {
+	var id [9]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:]), nil
+}
+
+func (s *fileShareStore) SaveSharedRequest(req SharedRequest) (SharedRequest, error) {
+	id, err := newSharedRequestID()
+	if err != nil {
+		return req, err
+	}
+	req.ID = id
+	if req.Created.IsZero() {
+		req.Created = time.Now()
+	}
+	data, err := json.MarshalIndent(&req, "", "  ")
+	if err != nil {
+		return req, fmt.Errorf("failed to encode shared request to json: %v", err)
+	}
+	if err := writeFileAtomically(filepath.Join(s.dir, id+".json"), append(data, '\n'), 0600); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+func (s *fileShareStore) SharedRequest(id string) (SharedRequest, error) {
+	var req SharedRequest
+	// the ID comes from users, so make sure it cannot be used to read other
+	// files
+	if !sharedRequestIDPattern.MatchString(id) {
+		return req, ErrSharedRequestNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if os.IsNotExist(err) {
+		return req, ErrSharedRequestNotFound
+	} else if err != nil {
+		return req, err
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, fmt.Errorf("failed to parse shared request %s: %v", id, err)
+	}
+	return req, nil
+}
+
+// isSecretMetadata returns true if the metadata with the given name likely
+// holds credentials, like an "authorization" header or an API key.
+func isSecretMetadata(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"auth", "cookie", "token", "secret", "password", "passwd", "credential", "session", "api-key", "apikey", "api_key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// shareHandler returns a handler for the requests in the given store. It
+// expects to serve both "/share" and "/share/". POST requests to the former
+// share the request in the body, after checking it against the given methods.
+// GET requests to "/share/<id>" return a shared request.
+func shareHandler(store ShareStore, methods []*desc.MethodDescriptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/share"), "/")
+		if id != "" {
+			if r.Method != "GET" {
+				w.Header().Set("Allow", "GET")
+				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			req, err := store.SharedRequest(id)
+			if err != nil {
+				if errors.Is(err, ErrSharedRequestNotFound) {
+					http.NotFound(w, r)
+				} else {
+					http.Error(w, "Failed to load shared request: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(&req)
+			return
+		}
+
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkCSRFToken(w, r) {
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "Request must be JSON", http.StatusUnsupportedMediaType)
+			return
+		}
+		var body struct {
+			SharedRequest
+			// Unless true, metadata that likely holds credentials is removed
+			// before the request is shared.
+			IncludeSecrets bool `json:"includeSecrets"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := body.SharedRequest
+		ex := Example{Name: "shared", Service: req.Service, Method: req.Method, Request: req.Request}
+		if errs := ValidateExamples([]Example{ex}, methods); len(errs) > 0 {
+			http.Error(w, errs[0].Err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if !body.IncludeSecrets {
+			var md []ExampleMetadataPair
+			for _, pair := range req.Request.Metadata {
+				if !isSecretMetadata(pair.Name) {
+					md = append(md, pair)
+				}
+			}
+			req.Request.Metadata = md
+		}
+		req.Created = time.Time{}
+		req, err := store.SaveSharedRequest(req)
+		if err != nil {
+			http.Error(w, "Failed to share request: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(&req)
+	})
+}
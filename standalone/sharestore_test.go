@@ -0,0 +1,147 @@
+package standalone
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileShareStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared")
+	store, err := NewFileShareStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	req, err := store.SaveSharedRequest(SharedRequest{
+		Service: "greet.Greeter",
+		Method:  "SayHello",
+		Request: ExampleRequest{TimeoutSeconds: 2, Data: map[string]interface{}{"name": "bob"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to share request: %v", err)
+	}
+	if len(req.ID) < 8 || req.Created.IsZero() {
+		t.Errorf("shared request is missing ID or created time: %+v", req)
+	}
+	loaded, err := store.SharedRequest(req.ID)
+	if err != nil {
+		t.Fatalf("failed to load shared request: %v", err)
+	}
+	if loaded.Method != "SayHello" || loaded.Request.TimeoutSeconds != 2 {
+		t.Errorf("unexpected shared request: %+v", loaded)
+	}
+
+	for _, id := range []string{"nope", "../shared/" + req.ID, ""} {
+		if _, err := store.SharedRequest(id); !errors.Is(err, ErrSharedRequestNotFound) {
+			t.Errorf("expecting ErrSharedRequestNotFound for ID %q; got %v", id, err)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := NewFileShareStore(file); err == nil {
+		t.Errorf("expecting error for path that is not a directory")
+	}
+}
+
+func TestShareHandler(t *testing.T) {
+	store, err := NewFileShareStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	h := shareHandler(store, loadGreeterMethods(t))
+
+	post := func(body string, csrf bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/share", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if csrf {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "token"})
+			req.Header.Set(csrfHeaderName, "token")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	get := func(id string) (SharedRequest, int) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/share/"+id, nil))
+		var req SharedRequest
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &req); err != nil {
+				t.Fatalf("failed to parse shared request: %v", err)
+			}
+		}
+		return req, w.Code
+	}
+
+	body := `{"service": "greet.Greeter", "method": "SayHello", "request": {"timeout_seconds": 3, "data": {"name": "x"},
+		"metadata": [{"name": "Authorization", "value": "Bearer abc"}, {"name": "x-api-key", "value": "k"}, {"name": "x-tenant", "value": "t"}]},
+		"response": {"responses": [{"message": {"name": "hello, x"}, "isError": false}]}}`
+	if w := post(body, false); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 without CSRF token; got %d", w.Code)
+	}
+	if w := post(`{"service": "greet.Greeter", "method": "SayGoodbye", "request": {"data": {}}}`, true); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expecting status 422 for unknown method; got %d", w.Code)
+	}
+
+	w := post(body, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to share request: %d %s", w.Code, w.Body.String())
+	}
+	var shared SharedRequest
+	if err := json.Unmarshal(w.Body.Bytes(), &shared); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	req, code := get(shared.ID)
+	if code != http.StatusOK {
+		t.Fatalf("failed to get shared request: %d", code)
+	}
+	if req.Request.TimeoutSeconds != 3 || !strings.Contains(string(req.Response), "hello, x") {
+		t.Errorf("unexpected shared request: %+v", req)
+	}
+	if len(req.Request.Metadata) != 1 || req.Request.Metadata[0].Name != "x-tenant" {
+		t.Errorf("expecting secret metadata to be removed; got %+v", req.Request.Metadata)
+	}
+
+	w = post(strings.Replace(body, `"service"`, `"includeSecrets": true, "service"`, 1), true)
+	if err := json.Unmarshal(w.Body.Bytes(), &shared); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if req, _ := get(shared.ID); len(req.Request.Metadata) != 3 {
+		t.Errorf("expecting all metadata to be shared; got %+v", req.Request.Metadata)
+	}
+
+	if _, code := get("missing"); code != http.StatusNotFound {
+		t.Errorf("expecting status 404 for unknown ID; got %d", code)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/share", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expecting status 405 for GET without ID; got %d", w.Code)
+	}
+}
+
+func TestIsSecretMetadata(t *testing.T) {
+	for name, secret := range map[string]bool{
+		"Authorization":  true,
+		"cookie":         true,
+		"x-auth-token":   true,
+		"X-API-Key":      true,
+		"client-secret":  true,
+		"x-request-id":   false,
+		"grpc-timeout":   false,
+		"x-tenant-bin":   false,
+		"accept-charset": false,
+	} {
+		if isSecretMetadata(name) != secret {
+			t.Errorf("isSecretMetadata(%q) should be %v", name, secret)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
@@ -41,6 +42,12 @@ const csrfHeaderName = "x-grpcui-csrf-token"
 // files enumerate all known protobuf (for enumerating all supported message
 // types, to support the use of google.protobuf.Any messages).
 //
+// Besides the web form, the handler serves the exposed schema as descriptors,
+// JSON Schema, and OpenAPI documents, along with client code snippets, sample
+// request data, and conversions from grpcurl commands and to and from Postman
+// collections. History, sharing, workspaces, auditing, authentication, and
+// authorization are enabled via opts.
+//
 // The returned handler expects to serve resources from "/". If it will instead
 // be handling a sub-path (e.g. handling "/rpc-ui/") then use http.StripPrefix.
 func Handler(ch grpcdynamic.Channel, target string, methods []*desc.MethodDescriptor, files []*desc.FileDescriptor, opts ...HandlerOption) http.Handler {
@@ -58,8 +65,8 @@ func Handler(ch grpcdynamic.Channel, target string, methods []*desc.MethodDescri
 
 	// Add embedded resources bundled in standalone package TOC
 	for _, assetName := range standalone.AssetNames() {
-		// the index file will be handled separately
-		if assetName == standalone.IndexTemplateName {
+		// the index and login files will be handled separately
+		if assetName == standalone.IndexTemplateName || assetName == standalone.LoginTemplateName {
 			continue
 		}
 		resourcePath := "/" + assetName
@@ -77,35 +84,96 @@ func Handler(ch grpcdynamic.Channel, target string, methods []*desc.MethodDescri
 
 	// Add the index page (not bundled in standalone)
 	formOpts := grpcui.WebFormOptions{
-		DefaultMetadata: uiOpts.defaultMetadata,
-		Debug:           uiOpts.debug,
-		GRPCurlOptions:  uiOpts.gRPCurlOptions,
-	}
-	webFormHTML := grpcui.WebFormContentsWithOptions("invoke", "metadata", target, methods, formOpts)
-	indexContents := getIndexContents(uiOpts.indexTmpl, target, webFormHTML, uiOpts.tmplResources)
-	indexResource := newResource("/", indexContents, "text/html; charset=utf-8", false)
-	indexResource.MustRevalidate = true
+		DefaultMetadata:  uiOpts.defaultMetadata,
+		Debug:            uiOpts.debug,
+		GRPCurlOptions:   uiOpts.gRPCurlOptions,
+		DescriptorsURI:   "descriptors",
+		SnippetURI:       "snippets",
+		ImportGRPCurlURI: "import-grpcurl",
+		PostmanURI:       "postman",
+		SampleDataURI:    "sample",
+		Redactor:         uiOpts.redactor,
+		InjectedMetadata: metadataNames(uiOpts.extraMetadata),
+	}
+	if uiOpts.tokenSource != nil {
+		formOpts.InjectedMetadata = append(formOpts.InjectedMetadata, "authorization")
+	}
+	formOpts.InjectedMetadata = append(formOpts.InjectedMetadata, uiOpts.injectedMetadata...)
+	if uiOpts.exampleStore != nil {
+		formOpts.SaveExampleURI = "examples"
+	}
+	if uiOpts.historyStore != nil {
+		formOpts.HistoryURI = "history"
+	}
+	if uiOpts.shareStore != nil {
+		formOpts.ShareURI = "share"
+	}
+	if uiOpts.workspaceStore != nil {
+		formOpts.WorkspaceURI = "workspace"
+	}
+	var logoutURI string
+	for _, a := range uiOpts.authenticators {
+		if _, ok := a.(PasswordAuthenticator); ok {
+			logoutURI = "logout"
+		}
+	}
+	newIndexResource := func(methods []*desc.MethodDescriptor) *resource {
+		webFormHTML := grpcui.WebFormContentsWithOptions("invoke", "metadata", target, methods, formOpts)
+		indexContents := getIndexContents(uiOpts.indexTmpl, target, webFormHTML, uiOpts.tmplResources, logoutURI)
+		indexResource := newResource("/", indexContents, "text/html; charset=utf-8", false)
+		indexResource.MustRevalidate = true
+		return indexResource
+	}
+	indexResource := newIndexResource(methods)
+	// with an authorization policy, each user's form only shows the methods
+	// they may invoke; we cache a page for each distinct set of methods
+	var indexMu sync.Mutex
+	indexResources := map[string]*resource{}
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
-			indexResource.ServeHTTP(w, r)
+			if uiOpts.authzPolicy == nil {
+				indexResource.ServeHTTP(w, r)
+				return
+			}
+			allowed := uiOpts.authzPolicy.allowedMethods(uiOpts.user(r), methods)
+			names := make([]string, len(allowed))
+			for i, md := range allowed {
+				names[i] = md.GetFullyQualifiedName()
+			}
+			key := strings.Join(names, ",")
+			indexMu.Lock()
+			res := indexResources[key]
+			if res == nil {
+				res = newIndexResource(allowed)
+				indexResources[key] = res
+			}
+			indexMu.Unlock()
+			res.ServeHTTP(w, r)
 		} else {
 			http.NotFound(w, r)
 		}
 	})
 
 	invokeOpts := grpcui.InvokeOptions{
-		ExtraMetadata:   uiOpts.extraMetadata,
-		PreserveHeaders: uiOpts.preserveHeaders,
-		EmitDefaults:    uiOpts.emitDefaults,
-		Verbosity:       uiOpts.invokeVerbosity,
+		ExtraMetadata:    uiOpts.extraMetadata,
+		PreserveHeaders:  uiOpts.preserveHeaders,
+		EmitDefaults:     uiOpts.emitDefaults,
+		Verbosity:        uiOpts.invokeVerbosity,
+		ConfirmMethod:    uiOpts.confirmMethod,
+		Redactor:         uiOpts.redactor,
+		TokenSource:      uiOpts.tokenSource,
+		InjectedMetadata: uiOpts.injectedMetadata,
+	}
+	invokeHandler := grpcui.RPCInvokeHandlerWithOptions(ch, methods, invokeOpts)
+	if uiOpts.authzPolicy != nil {
+		invokeHandler = authzHandler(invokeHandler, uiOpts.authzPolicy, methods, uiOpts.user)
 	}
-	rpcInvokeHandler := http.StripPrefix("/invoke", grpcui.RPCInvokeHandlerWithOptions(ch, methods, invokeOpts))
+	if uiOpts.auditSink != nil {
+		invokeHandler = auditHandler(invokeHandler, uiOpts.auditSink, uiOpts.auditOptions, methods, uiOpts.redactor, uiOpts.user)
+	}
+	rpcInvokeHandler := http.StripPrefix("/invoke", invokeHandler)
 	mux.HandleFunc("/invoke/", func(w http.ResponseWriter, r *http.Request) {
-		// CSRF protection
-		c, _ := r.Cookie(csrfCookieName)
-		h := r.Header.Get(csrfHeaderName)
-		if c == nil || c.Value == "" || c.Value != h {
-			http.Error(w, "incorrect CSRF token", http.StatusUnauthorized)
+		if !checkCSRFToken(w, r) {
 			return
 		}
 		rpcInvokeHandler.ServeHTTP(w, r)
@@ -114,15 +182,50 @@ func Handler(ch grpcdynamic.Channel, target string, methods []*desc.MethodDescri
 	rpcMetadataHandler := grpcui.RPCMetadataHandler(methods, files)
 	mux.Handle("/metadata", rpcMetadataHandler)
 
-	mux.HandleFunc("/examples", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(200)
-		if len(uiOpts.examples) > 0 {
-			w.Write(uiOpts.examples)
-		} else {
-			w.Write([]byte("[]"))
+	mux.Handle("/descriptors", grpcui.RPCDescriptorsHandler(methods))
+	mux.Handle("/jsonschema", grpcui.RPCJSONSchemaHandler(methods, files))
+	mux.Handle("/openapi.json", grpcui.RPCOpenAPIHandler(methods))
+	mux.Handle("/snippets/", http.StripPrefix("/snippets", grpcui.RPCSnippetHandler(target, methods, uiOpts.snippetOptions)))
+	mux.Handle("/import-grpcurl", grpcui.RPCImportGRPCurlHandler(methods))
+	mux.Handle("/sample/", http.StripPrefix("/sample", grpcui.RPCSampleDataHandler(methods)))
+	mux.Handle("/postman/", http.StripPrefix("/postman", postmanHandler(target, methods)))
+
+	if uiOpts.exampleStore != nil {
+		mux.Handle("/examples", examplesHandler(uiOpts.examples, uiOpts.exampleStore, methods))
+	} else {
+		mux.HandleFunc("/examples", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(200)
+			if len(uiOpts.examples) > 0 {
+				w.Write(uiOpts.examples)
+			} else {
+				w.Write([]byte("[]"))
+			}
+		})
+	}
+
+	if uiOpts.historyStore != nil {
+		var redact func(*HistoryItem)
+		if uiOpts.redactor != nil {
+			redact = historyRedactor(uiOpts.redactor, methods)
 		}
-	})
+		h := historyHandler(uiOpts.historyStore, uiOpts.user, redact)
+		mux.Handle("/history", h)
+		mux.Handle("/history/", h)
+	}
+	if uiOpts.shareStore != nil {
+		h := shareHandler(uiOpts.shareStore, methods)
+		mux.Handle("/share", h)
+		mux.Handle("/share/", h)
+	}
+	if uiOpts.workspaceStore != nil {
+		mux.Handle("/workspace", workspaceHandler(uiOpts.workspaceStore, uiOpts.user))
+	}
+
+	var h http.Handler = &mux
+	if len(uiOpts.authenticators) > 0 {
+		h = authHandler(h, uiOpts.authenticators)
+	}
 
 	// make sure we always have a csrf token cookie
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -133,19 +236,45 @@ func Handler(ch grpcdynamic.Channel, target string, methods []*desc.MethodDescri
 				return
 			}
 			c := &http.Cookie{
-				Name:  csrfCookieName,
-				Value: base64.RawURLEncoding.EncodeToString(tokenBytes),
+				Name:   csrfCookieName,
+				Value:  base64.RawURLEncoding.EncodeToString(tokenBytes),
+				Secure: r.TLS != nil,
 			}
 			http.SetCookie(w, c)
+			// so the login page can include the new token in its form
+			r.AddCookie(c)
 		}
 
-		mux.ServeHTTP(w, r)
+		h.ServeHTTP(w, r)
 	})
 }
 
+// checkCSRFToken verifies that the request includes the CSRF token from the
+// cookie in a header. If not, it writes an error response and returns false.
+func checkCSRFToken(w http.ResponseWriter, r *http.Request) bool {
+	c, _ := r.Cookie(csrfCookieName)
+	h := r.Header.Get(csrfHeaderName)
+	if c == nil || c.Value == "" || c.Value != h {
+		http.Error(w, "incorrect CSRF token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
 var defaultIndexTemplate = template.Must(template.New("index.html").Parse(string(standalone.IndexTemplate())))
 
-func getIndexContents(tmpl *template.Template, target string, webFormHTML []byte, addlResources []*resource) []byte {
+// metadataNames returns the names of the given metadata, each of which has the
+// form "name: value".
+func metadataNames(headers []string) []string {
+	names := make([]string, 0, len(headers))
+	for _, hdr := range headers {
+		name, _, _ := strings.Cut(hdr, ":")
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
+func getIndexContents(tmpl *template.Template, target string, webFormHTML []byte, addlResources []*resource, logoutURI string) []byte {
 	addlHTML := make([]template.HTML, 0, len(addlResources))
 	for _, res := range addlResources {
 		tag := res.AsHTMLTag()
@@ -157,6 +286,7 @@ func getIndexContents(tmpl *template.Template, target string, webFormHTML []byte
 		Target:          target,
 		WebFormContents: template.HTML(webFormHTML),
 		AddlResources:   addlHTML,
+		LogoutURI:       logoutURI,
 	}
 
 	var indexBuf bytes.Buffer
@@ -0,0 +1,91 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb" //lint:ignore SA1019 we have to import this because it appears in grpcurl APIs used herein
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// ExampleError describes a problem with an example, found by ValidateExamples.
+type ExampleError struct {
+	// The example's name.
+	Name string
+	// The fully-qualified name of the method named by the example.
+	Method string
+	// The problem with the example.
+	Err error
+}
+
+func (e *ExampleError) Error() string {
+	return fmt.Sprintf("example %q (%s): %v", e.Name, e.Method, e.Err)
+}
+
+func (e *ExampleError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateExamples checks the given examples against the given methods. An
+// example is invalid if it names a method that is not among the given methods
+// or if its request data cannot be decoded using the method's request type.
+// For client-streaming methods, the request data must be an array, and each
+// element is decoded as a request message. The returned slice has an error for
+// each invalid example and is empty if all examples are valid.
+//
+// The same problems would otherwise only be noticed when a user selects the
+// example in the web UI, and then only as a failure to load or invoke it.
+func ValidateExamples(examples []Example, methods []*desc.MethodDescriptor) []*ExampleError {
+	methodsByName := map[string]*desc.MethodDescriptor{}
+	for _, md := range methods {
+		methodsByName[md.GetFullyQualifiedName()] = md
+	}
+
+	var errs []*ExampleError
+	for _, ex := range examples {
+		name := ex.Service + "." + ex.Method
+		md := methodsByName[name]
+		if md == nil {
+			errs = append(errs, &ExampleError{Name: ex.Name, Method: name, Err: fmt.Errorf("method is not exposed by the server")})
+			continue
+		}
+		if err := validateExampleData(md, ex.Request.Data); err != nil {
+			errs = append(errs, &ExampleError{Name: ex.Name, Method: name, Err: err})
+		}
+	}
+	return errs
+}
+
+func validateExampleData(md *desc.MethodDescriptor, data interface{}) error {
+	js, err := marshalData(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode request data: %v", err)
+	}
+	descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+	if err != nil {
+		return err
+	}
+	unmarshaler := jsonpb.Unmarshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSource(descSource)}
+	inputType := md.GetInputType()
+
+	if !md.IsClientStreaming() {
+		if err := unmarshaler.Unmarshal(bytes.NewReader(js), dynamic.NewMessage(inputType)); err != nil {
+			return fmt.Errorf("request data is not a valid %s: %v", inputType.GetFullyQualifiedName(), err)
+		}
+		return nil
+	}
+
+	var msgs []json.RawMessage
+	if err := json.Unmarshal(js, &msgs); err != nil {
+		return fmt.Errorf("request data for a client-streaming method must be an array")
+	}
+	for i, msg := range msgs {
+		if err := unmarshaler.Unmarshal(bytes.NewReader(msg), dynamic.NewMessage(inputType)); err != nil {
+			return fmt.Errorf("request message #%d is not a valid %s: %v", i+1, inputType.GetFullyQualifiedName(), err)
+		}
+	}
+	return nil
+}
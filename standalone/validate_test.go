@@ -0,0 +1,44 @@
+package standalone
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateExamples(t *testing.T) {
+	methods := loadGreeterMethods(t)
+	examples := []Example{
+		{Name: "ok", Service: "greet.Greeter", Method: "SayHello",
+			Request: ExampleRequest{Data: map[string]interface{}{"name": "bob"}}},
+		{Name: "ok stream", Service: "greet.Greeter", Method: "SayHellos",
+			Request: ExampleRequest{Data: json.RawMessage(`[{"name": "a"}, {}]`)}},
+		{Name: "gone", Service: "greet.Greeter", Method: "SayGoodbye",
+			Request: ExampleRequest{Data: map[string]interface{}{}}},
+		{Name: "renamed field", Service: "greet.Greeter", Method: "SayHello",
+			Request: ExampleRequest{Data: map[string]interface{}{"fullName": "bob"}}},
+		{Name: "wrong type", Service: "greet.Greeter", Method: "SayHello",
+			Request: ExampleRequest{Data: map[string]interface{}{"name": 123}}},
+		{Name: "not a stream", Service: "greet.Greeter", Method: "SayHellos",
+			Request: ExampleRequest{Data: map[string]interface{}{"name": "a"}}},
+		{Name: "bad stream element", Service: "greet.Greeter", Method: "SayHellos",
+			Request: ExampleRequest{Data: json.RawMessage(`[{"name": "a"}, {"nom": "b"}]`)}},
+	}
+
+	errs := ValidateExamples(examples, methods)
+	expected := []string{
+		`example "gone" (greet.Greeter.SayGoodbye): method is not exposed by the server`,
+		`example "renamed field" (greet.Greeter.SayHello): request data is not a valid greet.Hello`,
+		`example "wrong type" (greet.Greeter.SayHello): request data is not a valid greet.Hello`,
+		`example "not a stream" (greet.Greeter.SayHellos): request data for a client-streaming method must be an array`,
+		`example "bad stream element" (greet.Greeter.SayHellos): request message #2 is not a valid greet.Hello`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expecting %d errors; got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), expected[i]) {
+			t.Errorf("expecting error %q; got %q", expected[i], err.Error())
+		}
+	}
+}
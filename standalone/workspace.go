@@ -0,0 +1,254 @@
+package standalone
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WorkspaceFormatVersion is the version of the JSON format of a Workspace.
+const WorkspaceFormatVersion = 1
+
+// ErrWorkspaceConflict is returned by WorkspaceStore.SaveWorkspace when the
+// workspace was changed by someone else since it was read.
+var ErrWorkspaceConflict = errors.New("workspace was changed by someone else; reload it and try again")
+
+// Workspace is a collection of saved requests, organized in folders. Each user
+// of the web UI has their own workspace (see WithUserHeader). A workspace can
+// be exported and imported as JSON; the format is documented in the README.
+type Workspace struct {
+	// The version of the format. Always WorkspaceFormatVersion.
+	Version int `json:"version"`
+	// Incremented every time the workspace is saved. It is used to detect
+	// when two browsers make conflicting changes.
+	Revision int                `json:"revision"`
+	Folders  []*WorkspaceFolder `json:"folders"`
+}
+
+// WorkspaceFolder is a named folder of saved requests, and possibly other
+// folders, in a Workspace.
+type WorkspaceFolder struct {
+	// A unique ID, assigned when the workspace is saved if empty.
+	ID      string             `json:"id"`
+	Name    string             `json:"name"`
+	Folders []*WorkspaceFolder `json:"folders,omitempty"`
+	Items   []*WorkspaceItem   `json:"items,omitempty"`
+}
+
+// WorkspaceItem is a saved request in a Workspace.
+type WorkspaceItem struct {
+	// A unique ID, assigned when the workspace is saved if empty.
+	ID      string         `json:"id"`
+	Name    string         `json:"name"`
+	Notes   string         `json:"notes,omitempty"`
+	Service string         `json:"service"`
+	Method  string         `json:"method"`
+	Request ExampleRequest `json:"request"`
+	// Values for variables referenced in the request's metadata values and
+	// in strings in its data, as "{{name}}". Variables are replaced with
+	// their values when the item is loaded into the web form.
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// WorkspaceStore keeps the workspaces of the users of the web UI. When a
+// Handler is configured with a store, the web form includes a tab for
+// browsing, running, and organizing the requests in the user's workspace.
+type WorkspaceStore interface {
+	// Workspace returns the given user's workspace, which is empty if they
+	// have not saved one.
+	Workspace(user string) (*Workspace, error)
+	// SaveWorkspace replaces the given user's workspace with the given one.
+	// Its revision must be the same as that of the stored workspace, or else
+	// ErrWorkspaceConflict is returned. On success, the given workspace's
+	// revision is incremented.
+	SaveWorkspace(user string, ws *Workspace) error
+}
+
+// NewFileWorkspaceStore returns a WorkspaceStore that keeps each user's
+// workspace in a JSON file in the given directory. The directory is created
+// when the first workspace is saved, if it does not already exist. The files,
+// and the directory if it is created, are only accessible to their owner.
+func NewFileWorkspaceStore(dir string) (WorkspaceStore, error) {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &fileWorkspaceStore{dir: dir}, nil
+}
+
+type fileWorkspaceStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func (s *fileWorkspaceStore) file(user string) string {
+	if user == "" {
+		return filepath.Join(s.dir, "shared.json")
+	}
+	// user names come from request headers, so we encode them to get a
+	// valid (and safe) file name
+	return filepath.Join(s.dir, "user-"+base64.RawURLEncoding.EncodeToString([]byte(user))+".json")
+}
+
+func (s *fileWorkspaceStore) Workspace(user string) (*Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(user)
+}
+
+// load reads the given user's workspace. Must be called with s.mu held.
+func (s *fileWorkspaceStore) load(user string) (*Workspace, error) {
+	ws := &Workspace{Version: WorkspaceFormatVersion, Folders: []*WorkspaceFolder{}}
+	data, err := os.ReadFile(s.file(user))
+	if os.IsNotExist(err) {
+		return ws, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, ws); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace: %v", err)
+	}
+	return ws, nil
+}
+
+func (s *fileWorkspaceStore) SaveWorkspace(user string, ws *Workspace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.load(user)
+	if err != nil {
+		return err
+	}
+	if ws.Revision != existing.Revision {
+		return ErrWorkspaceConflict
+	}
+	ws.Revision++
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		ws.Revision--
+		return fmt.Errorf("failed to encode workspace to json: %v", err)
+	}
+	if err := writeFileAtomically(s.file(user), append(data, '\n'), 0600); err != nil {
+		ws.Revision--
+		return err
+	}
+	return nil
+}
+
+// checkWorkspace verifies that the given workspace is in a format we
+// understand and that all folders and items have names, and assigns IDs to
+// those that have none.
+func checkWorkspace(ws *Workspace) error {
+	if ws.Version != WorkspaceFormatVersion {
+		return fmt.Errorf("unsupported workspace version %d; expecting %d", ws.Version, WorkspaceFormatVersion)
+	}
+	if ws.Folders == nil {
+		ws.Folders = []*WorkspaceFolder{}
+	}
+	ids := map[string]bool{}
+	newID := func(id string) (string, error) {
+		if id != "" && !ids[id] {
+			ids[id] = true
+			return id, nil
+		}
+		// missing or duplicate (e.g. a folder copied in an imported file)
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		id = hex.EncodeToString(b[:])
+		ids[id] = true
+		return id, nil
+	}
+	var checkFolders func(folders []*WorkspaceFolder, path string) error
+	checkFolders = func(folders []*WorkspaceFolder, path string) error {
+		for _, folder := range folders {
+			if folder == nil || strings.TrimSpace(folder.Name) == "" {
+				return fmt.Errorf("folder in %q has no name", path)
+			}
+			var err error
+			if folder.ID, err = newID(folder.ID); err != nil {
+				return err
+			}
+			folderPath := path + folder.Name + "/"
+			for _, item := range folder.Items {
+				if item == nil || strings.TrimSpace(item.Name) == "" {
+					return fmt.Errorf("request in %q has no name", folderPath)
+				}
+				if item.Service == "" || item.Method == "" {
+					return fmt.Errorf("request %q in %q must indicate service and method", item.Name, folderPath)
+				}
+				if item.ID, err = newID(item.ID); err != nil {
+					return err
+				}
+			}
+			if err := checkFolders(folder.Folders, folderPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return checkFolders(ws.Folders, "/")
+}
+
+// workspaceHandler returns a handler for the workspaces in the given store,
+// scoped to the user returned by the given function. GET requests return the
+// user's workspace, and PUT requests replace it with the one in the request
+// body, which is how both edits in the web form and imports are saved.
+func workspaceHandler(store WorkspaceStore, user func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			ws, err := store.Workspace(user(r))
+			if err != nil {
+				http.Error(w, "Failed to load workspace: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(ws)
+		case "PUT":
+			if !checkCSRFToken(w, r) {
+				return
+			}
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				http.Error(w, "Request must be JSON", http.StatusUnsupportedMediaType)
+				return
+			}
+			var ws Workspace
+			if err := json.NewDecoder(r.Body).Decode(&ws); err != nil {
+				http.Error(w, "Failed to parse JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := checkWorkspace(&ws); err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			if err := store.SaveWorkspace(user(r), &ws); err != nil {
+				if errors.Is(err, ErrWorkspaceConflict) {
+					http.Error(w, err.Error(), http.StatusConflict)
+				} else {
+					http.Error(w, "Failed to save workspace: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(&ws)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
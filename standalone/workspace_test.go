@@ -0,0 +1,156 @@
+package standalone
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFileWorkspaceStore(t *testing.T) {
+	store, err := NewFileWorkspaceStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ws, err := store.Workspace("alice")
+	if err != nil {
+		t.Fatalf("failed to load workspace: %v", err)
+	}
+	if ws.Version != WorkspaceFormatVersion || ws.Revision != 0 || len(ws.Folders) != 0 {
+		t.Errorf("unexpected empty workspace: %+v", ws)
+	}
+
+	ws.Folders = append(ws.Folders, &WorkspaceFolder{ID: "f", Name: "Greetings", Items: []*WorkspaceItem{
+		{ID: "i", Name: "hello", Service: "greet.Greeter", Method: "SayHello", Variables: map[string]string{"name": "bob"}},
+	}})
+	if err := store.SaveWorkspace("alice", ws); err != nil {
+		t.Fatalf("failed to save workspace: %v", err)
+	}
+	if ws.Revision != 1 {
+		t.Errorf("expecting revision 1 after save; got %d", ws.Revision)
+	}
+
+	// saving a stale copy fails
+	stale := &Workspace{Version: WorkspaceFormatVersion}
+	if err := store.SaveWorkspace("alice", stale); !errors.Is(err, ErrWorkspaceConflict) {
+		t.Errorf("expecting ErrWorkspaceConflict; got %v", err)
+	}
+	// but other users' workspaces are separate
+	if err := store.SaveWorkspace("bob", stale); err != nil {
+		t.Errorf("failed to save workspace for another user: %v", err)
+	}
+
+	loaded, err := store.Workspace("alice")
+	if err != nil {
+		t.Fatalf("failed to load workspace: %v", err)
+	}
+	if loaded.Revision != 1 || len(loaded.Folders) != 1 || loaded.Folders[0].Items[0].Variables["name"] != "bob" {
+		t.Errorf("unexpected workspace: %+v", loaded)
+	}
+	if shared, _ := store.Workspace(""); len(shared.Folders) != 0 {
+		t.Errorf("expecting empty shared workspace; got %+v", shared)
+	}
+}
+
+func TestCheckWorkspace(t *testing.T) {
+	ws := &Workspace{Version: WorkspaceFormatVersion, Folders: []*WorkspaceFolder{
+		{ID: "a", Name: "one", Items: []*WorkspaceItem{{Name: "x", Service: "s", Method: "m"}}},
+		{ID: "a", Name: "two", Folders: []*WorkspaceFolder{{Name: "three"}}},
+	}}
+	if err := checkWorkspace(ws); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := map[string]bool{}
+	for _, id := range []string{ws.Folders[0].ID, ws.Folders[0].Items[0].ID, ws.Folders[1].ID, ws.Folders[1].Folders[0].ID} {
+		if id == "" || ids[id] {
+			t.Errorf("expecting unique IDs to be assigned; got %q", id)
+		}
+		ids[id] = true
+	}
+	if ws.Folders[0].ID != "a" {
+		t.Errorf("expecting existing ID to be kept; got %q", ws.Folders[0].ID)
+	}
+
+	for _, tc := range []struct {
+		ws  Workspace
+		err string
+	}{
+		{Workspace{Version: 2}, "unsupported workspace version 2"},
+		{Workspace{Version: 1, Folders: []*WorkspaceFolder{{Name: " "}}}, `folder in "/" has no name`},
+		{Workspace{Version: 1, Folders: []*WorkspaceFolder{{Name: "a", Folders: []*WorkspaceFolder{{Name: "b", Items: []*WorkspaceItem{{Name: "c", Service: "s"}}}}}}},
+			`request "c" in "/a/b/" must indicate service and method`},
+	} {
+		if err := checkWorkspace(&tc.ws); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expecting error %q; got %v", tc.err, err)
+		}
+	}
+}
+
+func TestWorkspaceHandler(t *testing.T) {
+	store, err := NewFileWorkspaceStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	h := workspaceHandler(store, func(r *http.Request) string {
+		return r.Header.Get("x-user")
+	})
+
+	serve := func(method, user, body string, csrf bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/workspace", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("x-user", user)
+		}
+		if csrf {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "token"})
+			req.Header.Set(csrfHeaderName, "token")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	get := func(user string) Workspace {
+		w := serve("GET", user, "", false)
+		if w.Code != http.StatusOK {
+			t.Fatalf("failed to get workspace: %d %s", w.Code, w.Body.String())
+		}
+		var ws Workspace
+		if err := json.Unmarshal(w.Body.Bytes(), &ws); err != nil {
+			t.Fatalf("failed to parse workspace: %v", err)
+		}
+		return ws
+	}
+
+	body := `{"version": 1, "revision": 0, "folders": [{"name": "Greetings", "items": [{"name": "hello", "notes": "says hi",
+		"service": "greet.Greeter", "method": "SayHello", "request": {"timeout_seconds": 2, "data": {"name": "{{who}}"}},
+		"variables": {"who": "bob"}}]}]}`
+	if w := serve("PUT", "alice", body, false); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 without CSRF token; got %d", w.Code)
+	}
+	if w := serve("PUT", "alice", `{"version": 7}`, true); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expecting status 422 for unsupported version; got %d", w.Code)
+	}
+	if w := serve("PUT", "alice", body, true); w.Code != http.StatusOK {
+		t.Fatalf("failed to save workspace: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("PUT", "alice", body, true); w.Code != http.StatusConflict {
+		t.Errorf("expecting status 409 for stale revision; got %d", w.Code)
+	}
+
+	ws := get("alice")
+	if ws.Revision != 1 || len(ws.Folders) != 1 || len(ws.Folders[0].Items) != 1 {
+		t.Fatalf("unexpected workspace: %+v", ws)
+	}
+	item := ws.Folders[0].Items[0]
+	if item.ID == "" || item.Notes != "says hi" || item.Request.TimeoutSeconds != 2 || item.Variables["who"] != "bob" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if ws := get(""); len(ws.Folders) != 0 {
+		t.Errorf("expecting empty workspace for other user; got %+v", ws)
+	}
+	if w := serve("POST", "alice", body, true); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expecting status 405 for POST; got %d", w.Code)
+	}
+}
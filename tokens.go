@@ -0,0 +1,81 @@
+package grpcui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// commandTokenTTL is how long a token printed by a credential helper command
+// is used, if the command does not indicate when it expires.
+const commandTokenTTL = 5 * time.Minute
+
+// NewCommandTokenSource returns a token source that gets tokens by running the
+// given command, a credential helper. The command must print the token to
+// stdout, either by itself or as a JSON object with an "access_token" and,
+// optionally, a "token_type" and either "expires_in" (in seconds) or "expiry"
+// (in RFC 3339 format). Tokens are cached and the command is only run again
+// when the current token is about to expire. Tokens printed by themselves, or
+// without an expiry, are used for five minutes.
+func NewCommandTokenSource(name string, args ...string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, commandTokenSource{name: name, args: args})
+}
+
+type commandTokenSource struct {
+	name string
+	args []string
+}
+
+func (s commandTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.name, s.args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("credential helper %q failed: %v: %s", s.name, err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %q failed: %v", s.name, err)
+	}
+	return parseCommandToken(out, time.Now())
+}
+
+// parseCommandToken parses the output of a credential helper command.
+func parseCommandToken(out []byte, now time.Time) (*oauth2.Token, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, errors.New("credential helper printed no token")
+	}
+	if out[0] != '{' {
+		return &oauth2.Token{AccessToken: string(out), Expiry: now.Add(commandTokenTTL)}, nil
+	}
+	var resp struct {
+		AccessToken string    `json:"access_token"`
+		TokenType   string    `json:"token_type"`
+		ExpiresIn   int64     `json:"expires_in"`
+		Expiry      time.Time `json:"expiry"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse output of credential helper: %v", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("credential helper printed no access_token")
+	}
+	tok := &oauth2.Token{AccessToken: resp.AccessToken, TokenType: resp.TokenType, Expiry: resp.Expiry}
+	if resp.ExpiresIn > 0 {
+		tok.Expiry = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	if tok.Expiry.IsZero() {
+		tok.Expiry = now.Add(commandTokenTTL)
+	}
+	return tok, nil
+}
@@ -0,0 +1,153 @@
+package grpcui
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestParseCommandToken(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCases := []struct {
+		out      string
+		token    string
+		typ      string
+		expiry   time.Time
+		errorMsg string
+	}{
+		{out: "abc\n", token: "abc", expiry: now.Add(commandTokenTTL)},
+		{out: `{"access_token": "abc", "token_type": "MAC", "expires_in": 60}`, token: "abc", typ: "MAC", expiry: now.Add(time.Minute)},
+		{out: `{"access_token": "abc", "expiry": "2024-01-02T04:00:00Z"}`, token: "abc", expiry: time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC)},
+		{out: `{"access_token": "abc"}`, token: "abc", expiry: now.Add(commandTokenTTL)},
+		{out: " \n", errorMsg: "printed no token"},
+		{out: `{"token_type": "Bearer"}`, errorMsg: "printed no access_token"},
+		{out: `{"access_token": `, errorMsg: "failed to parse"},
+	}
+	for _, tc := range testCases {
+		tok, err := parseCommandToken([]byte(tc.out), now)
+		if tc.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.errorMsg) {
+				t.Errorf("%q: expecting error containing %q; got %v", tc.out, tc.errorMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.out, err)
+			continue
+		}
+		if tok.AccessToken != tc.token || tok.TokenType != tc.typ || !tok.Expiry.Equal(tc.expiry) {
+			t.Errorf("%q: unexpected token: %+v", tc.out, tok)
+		}
+	}
+}
+
+func TestCommandTokenSource(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("test requires /bin/sh")
+	}
+	count := filepath.Join(t.TempDir(), "count")
+	ts := NewCommandTokenSource("/bin/sh", "-c", `echo x >> "$0"; echo tok-$(wc -l < "$0" | tr -d ' ')`, count)
+	for i := 0; i < 3; i++ {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatalf("failed to get token: %v", err)
+		}
+		// the token is cached, so the command only runs once
+		if tok.AccessToken != "tok-1" || tok.Type() != "Bearer" {
+			t.Errorf("unexpected token: %+v", tok)
+		}
+	}
+
+	ts = NewCommandTokenSource("/bin/sh", "-c", "echo oops >&2; exit 1")
+	if _, err := ts.Token(); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expecting error with output of failed command; got %v", err)
+	}
+}
+
+// startMetadataServer starts a server whose methods take and return
+// google.protobuf.Empty, and which stores the request metadata of each RPC in
+// md. It returns a connection to the server.
+func startMetadataServer(t *testing.T, md *metadata.MD) *grpc.ClientConn {
+	t.Helper()
+	svr := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		*md, _ = metadata.FromIncomingContext(stream.Context())
+		if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+			return err
+		}
+		return stream.SendMsg(&emptypb.Empty{})
+	}))
+	l := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = svr.Serve(l)
+	}()
+	t.Cleanup(svr.Stop)
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cc.Close()
+	})
+	return cc
+}
+
+func TestRPCInvokeHandler_TokenSource(t *testing.T) {
+	var md metadata.MD
+	cc := startMetadataServer(t, &md)
+
+	h := RPCInvokeHandlerWithOptions(cc, loadTestMethods(t), InvokeOptions{
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "abc"}),
+	})
+	body := `{"metadata":[{"name":"Authorization","value":"Bearer mine"}],"data":[{}]}`
+	req := httptest.NewRequest("POST", "/test.KitchenSink.Ping", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expecting status 200; got %d: %s", rec.Code, rec.Body.String())
+	}
+	// the token replaces authorization metadata from the form
+	if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer abc" {
+		t.Errorf("unexpected authorization metadata: %v", auth)
+	}
+}
+
+func TestRPCInvokeHandler_InjectedMetadata(t *testing.T) {
+	var md metadata.MD
+	cc := startMetadataServer(t, &md)
+
+	h := RPCInvokeHandlerWithOptions(cc, loadTestMethods(t), InvokeOptions{
+		InjectedMetadata: []string{"Authorization"},
+	})
+	body := `{"metadata":[{"name":"authorization","value":"Bearer mine"},{"name":"x-trace","value":"1"}],"data":[{}]}`
+	req := httptest.NewRequest("POST", "/test.KitchenSink.Ping", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expecting status 200; got %d: %s", rec.Code, rec.Body.String())
+	}
+	if auth := md.Get("authorization"); len(auth) != 0 {
+		t.Errorf("expecting injected metadata from the form to be dropped; got %v", auth)
+	}
+	if trace := md.Get("x-trace"); len(trace) != 1 || trace[0] != "1" {
+		t.Errorf("expecting other metadata to be sent; got %v", trace)
+	}
+}
@@ -64,7 +64,9 @@ func WebFormContents(invokeURI, metadataURI string, target string, descs []*desc
 // WebFormOptions contains optional arguments when creating a gRPCui web form.
 type WebFormOptions struct {
 	// The set of metadata to show in the web form by default. Each value in
-	// the slice should be in the form "name: value"
+	// the slice should be in the form "name: value". Values of metadata that
+	// the Redactor masks are not sent to the browser: the form lists such
+	// metadata by name only, for the user to fill in.
 	DefaultMetadata []string
 	// If non-nil and true, the web form JS code will log debug information
 	// to the JS console. If nil, whether debug is enabled or not depends on
@@ -73,6 +75,95 @@ type WebFormOptions struct {
 	Debug *bool
 	// Any options that will be rendered before grpcurl in the grpccurl/raw request box
 	GRPCurlOptions []string
+	// If non-empty, the web form will include links for downloading the
+	// descriptors of the exposed services. The URI should be where a handler
+	// returned by RPCDescriptorsHandler is registered.
+	DescriptorsURI string
+	// If non-empty, the raw request tab will include client code, in several
+	// languages, for issuing the current request. The URI should be where a
+	// handler returned by RPCSnippetHandler is registered.
+	SnippetURI string
+	// If non-empty, the raw request tab will allow the user to paste a
+	// grpcurl command line, to load its request into the form. The URI should
+	// be where a handler returned by RPCImportGRPCurlHandler is registered.
+	ImportGRPCurlURI string
+	// If non-empty, the history tab will include buttons for exporting the
+	// history as a Postman collection and for importing a Postman collection
+	// as examples. The URI should be where a handler that performs the
+	// conversion is registered. The standalone package provides such a
+	// handler, which accepts POST requests to "<uri>/export" and
+	// "<uri>/import".
+	PostmanURI string
+	// If non-empty, the request form will include a button for filling the
+	// form with sample data. The URI should be where a handler returned by
+	// RPCSampleDataHandler is registered.
+	SampleDataURI string
+	// If non-empty, the request form will include a button for saving the
+	// current request as a named example. The URI should be where a handler
+	// that accepts POST requests with a JSON example in the body is
+	// registered. The standalone package provides such a handler when it is
+	// configured with an example store.
+	SaveExampleURI string
+	// If non-empty, the history of invocations is kept on the server, instead
+	// of in the browser's local storage, and the history tab allows searching
+	// it. The URI should be where a handler that lists history in response
+	// to GET requests (with an optional "q" query parameter for searching),
+	// adds an item in response to POST requests, and clears the history in
+	// response to DELETE requests is registered. DELETE requests to
+	// "<uri>/<id>" should remove a single item. The standalone package
+	// provides such a handler when it is configured with a history store.
+	HistoryURI string
+	// If non-empty, the request form will include a button for sharing the
+	// current request, which creates a link that opens the web form with the
+	// request pre-filled. The URI should be where a handler that stores the
+	// JSON request in the body of a POST request, and returns it with an "id"
+	// property, is registered. GET requests to "<uri>/<id>" should return the
+	// stored request. When the web form is loaded with a "share" query
+	// parameter, it loads the request with that ID. The standalone package
+	// provides such a handler when it is configured with a share store.
+	ShareURI string
+	// If non-empty, the web form will include a tab for browsing, running,
+	// and organizing the requests saved in the user's workspace, and for
+	// exporting and importing it. The URI should be where a handler that
+	// returns the workspace as JSON in response to GET requests, and replaces
+	// it in response to PUT requests, is registered. The standalone package
+	// provides such a handler when it is configured with a workspace store.
+	WorkspaceURI string
+	// If non-nil, sensitive fields and metadata are masked in the grpcurl
+	// command shown for the current request. This should be the same
+	// redactor given to the invocation handler, via InvokeOptions.
+	Redactor *Redactor
+	// The names of metadata that the server adds to every RPC, such as the
+	// ExtraMetadata of InvokeOptions. Their values are never sent to the
+	// browser: the web form lists them with an "(injected)" placeholder, and
+	// the grpcurl command shown for the current request refers to them via
+	// shell variables, like "${AUTHORIZATION}". Entries in DefaultMetadata
+	// with these names are left out of the form, since the server would
+	// override them anyway.
+	InjectedMetadata []string
+}
+
+// webFormFeatures describes optional capabilities of the web form, which are
+// enabled in the JS code when the corresponding server handler is available.
+type webFormFeatures struct {
+	DescriptorsURI   string            `json:"descriptorsURI,omitempty"`
+	SnippetURI       string            `json:"snippetURI,omitempty"`
+	ImportGRPCurlURI string            `json:"importGRPCurlURI,omitempty"`
+	PostmanURI       string            `json:"postmanURI,omitempty"`
+	SaveExampleURI   string            `json:"saveExampleURI,omitempty"`
+	SampleDataURI    string            `json:"sampleDataURI,omitempty"`
+	HistoryURI       string            `json:"historyURI,omitempty"`
+	ShareURI         string            `json:"shareURI,omitempty"`
+	WorkspaceURI     string            `json:"workspaceURI,omitempty"`
+	Redact           *webFormRedaction `json:"redact,omitempty"`
+	InjectedMetadata []string          `json:"injectedMetadata,omitempty"`
+}
+
+// webFormRedaction tells the web form which fields and metadata to mask in
+// the grpcurl command, in addition to fields with the debug_redact option.
+type webFormRedaction struct {
+	Fields   []string `json:"fields"`
+	Metadata []string `json:"metadata"`
 }
 
 // WebFormContentsWithOptions is the same as WebFormContents except that it
@@ -94,6 +185,7 @@ func WebFormContentsWithOptions(invokeURI, metadataURI string, target string, de
 		Debug           bool
 		Target          string
 		GRPCurlOptions  template.JS
+		Features        template.JS
 	}{
 		InvokeURI:   invokeURI,
 		MetadataURI: metadataURI,
@@ -109,6 +201,36 @@ func WebFormContentsWithOptions(invokeURI, metadataURI string, target string, de
 		panic(fmt.Errorf("Error marshaling to JSON: %w", err))
 	}
 	params.GRPCurlOptions = template.JS(gRPCOptionsJSONStr)
+	features := webFormFeatures{
+		DescriptorsURI:   opts.DescriptorsURI,
+		SnippetURI:       opts.SnippetURI,
+		ImportGRPCurlURI: opts.ImportGRPCurlURI,
+		PostmanURI:       opts.PostmanURI,
+		SaveExampleURI:   opts.SaveExampleURI,
+		SampleDataURI:    opts.SampleDataURI,
+		HistoryURI:       opts.HistoryURI,
+		ShareURI:         opts.ShareURI,
+		WorkspaceURI:     opts.WorkspaceURI,
+	}
+	injected := map[string]bool{}
+	for _, name := range opts.InjectedMetadata {
+		name = strings.ToLower(name)
+		if !injected[name] {
+			injected[name] = true
+			features.InjectedMetadata = append(features.InjectedMetadata, name)
+		}
+	}
+	if opts.Redactor != nil {
+		features.Redact = &webFormRedaction{
+			Fields:   opts.Redactor.fields,
+			Metadata: opts.Redactor.metadata,
+		}
+	}
+	featuresJSON, err := json.Marshal(features)
+	if err != nil {
+		panic(fmt.Errorf("Error marshaling to JSON: %w", err))
+	}
+	params.Features = template.JS(featuresJSON)
 	if opts.Debug != nil {
 		params.Debug = *opts.Debug
 	}
@@ -119,6 +241,12 @@ func WebFormContentsWithOptions(invokeURI, metadataURI string, target string, de
 		if len(parts) > 1 {
 			val = strings.TrimLeftFunc(parts[1], unicode.IsSpace)
 		}
+		if injected[strings.ToLower(key)] {
+			continue
+		}
+		if opts.Redactor != nil && opts.Redactor.IsSensitiveMetadata(key) {
+			val = ""
+		}
 		params.DefaultMetadata = append(params.DefaultMetadata, metadataEntry{Name: key, Value: val})
 	}
 
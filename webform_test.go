@@ -0,0 +1,38 @@
+package grpcui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebFormContents_InjectedMetadata(t *testing.T) {
+	html := string(WebFormContentsWithOptions("invoke", "metadata", "target", loadTestMethods(t), WebFormOptions{
+		DefaultMetadata:  []string{"Authorization: Bearer s3cret", "x-trace: 1"},
+		InjectedMetadata: []string{"authorization", "X-Api-Key"},
+	}))
+	if strings.Contains(html, "s3cret") {
+		t.Errorf("expecting default metadata overridden by injected metadata to be left out")
+	}
+	if !strings.Contains(html, "x-trace") {
+		t.Errorf("expecting other default metadata in form")
+	}
+	if !strings.Contains(html, `"injectedMetadata":["authorization","x-api-key"]`) {
+		t.Errorf("expecting names of injected metadata in form features")
+	}
+}
+
+func TestWebFormContents_RedactedDefaultMetadata(t *testing.T) {
+	html := string(WebFormContentsWithOptions("invoke", "metadata", "target", loadTestMethods(t), WebFormOptions{
+		DefaultMetadata: []string{"X-Api-Key: s3cret", "x-trace: 1"},
+		Redactor:        NewRedactor(nil, []string{"x-api-key"}),
+	}))
+	if strings.Contains(html, "s3cret") {
+		t.Errorf("expecting value of masked default metadata to be left out")
+	}
+	if !strings.Contains(html, `{"name": "X-Api-Key", "value": ""}`) {
+		t.Errorf("expecting masked default metadata to be listed by name")
+	}
+	if !strings.Contains(html, `{"name": "x-trace", "value": "1"}`) {
+		t.Errorf("expecting other default metadata in form")
+	}
+}